@@ -1,9 +1,11 @@
 resource "aws_dynamodb_table" "score_table" {
   name           = "scores"
   billing_mode   = "PROVISIONED"
-  # R/W capacity for free tier must be <= 25
-  read_capacity  = 15
-  write_capacity = 15
+  # R/W capacity for free tier must be <= 25 across the table and its indexes
+  # a score costs 5 WCU on the table, its all time item, its daily, weekly and monthly copies and the update of the player's best score
+  # which is charged even when the score is not the best. Nonces, idempotency keys, rate limits and submission counters add more when they are used
+  read_capacity  = 9
+  write_capacity = 12
   hash_key       = "pk"
   range_key      = "sk"
 
@@ -22,6 +24,16 @@ resource "aws_dynamodb_table" "score_table" {
     type = "S"
   }
 
+  attribute {
+    name = "bgame"
+    type = "S"
+  }
+
+  attribute {
+    name = "bsk"
+    type = "N"
+  }
+
   ttl {
     attribute_name = "ttl"
     enabled = true
@@ -31,9 +43,22 @@ resource "aws_dynamodb_table" "score_table" {
     name = "GameScoresIndex"
     hash_key = "game"
     range_key = "sk"
-    write_capacity = 10
-    read_capacity = 10
+    # a score costs 4 WCU, its all time item and its three period copies
+    write_capacity = 8
+    read_capacity = 8
+    projection_type    = "INCLUDE"
+    non_key_attributes = ["pname", "ts", "rts", "meta"]
+  }
+
+  # holds one item per player and game with the player's all time best score
+  global_secondary_index {
+    name = "GameBestScoresIndex"
+    hash_key = "bgame"
+    range_key = "bsk"
+    # a score costs nothing unless it is the player's best, then 1 WCU for their first score or 2 to replace their previous best
+    write_capacity = 5
+    read_capacity = 8
     projection_type    = "INCLUDE"
-    non_key_attributes = ["pname", "ts"]
+    non_key_attributes = ["pname", "ts", "rts", "meta"]
   }
 }
@@ -2,6 +2,18 @@ variable "lambda_function_name" {
   default = "cheerleader_proxy_api"
 }
 
+variable "admin_token" {
+  description = "Bearer token for the admin api, which is disabled when empty"
+  default     = ""
+  sensitive   = true
+}
+
+variable "session_secret" {
+  description = "Secret which signs session tokens, games cannot require sessions when empty"
+  default     = ""
+  sensitive   = true
+}
+
 resource "aws_cloudwatch_log_group" "lambda_logs" {
   name              = "/aws/lambda/${var.lambda_function_name}"
   retention_in_days = 7
@@ -18,7 +30,9 @@ resource "aws_lambda_function" "lambda_func" {
 
   environment {
     variables = {
-      DDB_TABLE = aws_dynamodb_table.score_table.name
+      DDB_TABLE      = aws_dynamodb_table.score_table.name
+      ADMIN_TOKEN    = var.admin_token
+      SESSION_SECRET = var.session_secret
     }
   }
 
@@ -37,7 +51,10 @@ resource "aws_iam_policy" "lambda_policy" {
         Action = [
           "dynamodb:GetItem",
           "dynamodb:BatchGetItem",
+          "dynamodb:BatchWriteItem",
           "dynamodb:PutItem",
+          "dynamodb:UpdateItem",
+          "dynamodb:DeleteItem",
           "dynamodb:Query",
         ]
         Resource = [
@@ -7,54 +7,157 @@ import (
 )
 
 type ApiRoutes struct {
-	ScoresByPlayer string
-	RanksByPlayer  string
-	Scores         string
-	Ranks          string
+	ScoresByPlayer        string
+	RanksByPlayer         string
+	SessionsByPlayer      string
+	ProfileByPlayer       string
+	Scores                string
+	ScoresBatch           string
+	Ranks                 string
+	GroupRanks            string
+	Groups                string
+	Group                 string
+	AdminGames            string
+	AdminGame             string
+	AdminQuarantine       string
+	AdminQuarantinedScore string
+	AdminApproveScore     string
+	AdminPlayer           string
+	AdminPlayerScore      string
+	AdminBans             string
+	AdminBan              string
+	AdminKeys             string
+	AdminKey              string
 }
 
 func NewApiRoutes() ApiRoutes {
 	return ApiRoutes{
-		ScoresByPlayer: "/{game}/{player_id}/scores",
-		RanksByPlayer:  "/{game}/{player_id}/ranks",
-		Ranks:          "/{game}/ranks",
+		ScoresByPlayer:        "/{game}/{player_id}/scores",
+		RanksByPlayer:         "/{game}/{player_id}/ranks",
+		SessionsByPlayer:      "/{game}/{player_id}/sessions",
+		ProfileByPlayer:       "/{game}/{player_id}/profile",
+		ScoresBatch:           "/{game}/scores:batch",
+		Ranks:                 "/{game}/ranks",
+		GroupRanks:            "/{game}/ranks:friends",
+		Groups:                "/{game}/groups",
+		Group:                 "/{game}/groups/{group_id}",
+		AdminGames:            "/admin/games",
+		AdminGame:             "/admin/games/{game}",
+		AdminQuarantine:       "/admin/games/{game}/quarantine",
+		AdminQuarantinedScore: "/admin/games/{game}/quarantine/{score_id}",
+		AdminApproveScore:     "/admin/games/{game}/quarantine/{score_id}/approve",
+		AdminPlayer:           "/admin/games/{game}/players/{player_id}",
+		AdminPlayerScore:      "/admin/games/{game}/players/{player_id}/scores/{score}",
+		AdminBans:             "/admin/games/{game}/bans",
+		AdminBan:              "/admin/games/{game}/bans/{player_id}",
+		AdminKeys:             "/admin/keys",
+		AdminKey:              "/admin/keys/{key_id}",
 	}
 }
 
+// reservedGame is the first segment of the admin paths, so it cannot be the name of a game
+const reservedGame = "admin"
+
+// boardPath splits a path within a board into the path of its game and the board, the routes of a board are those of its game under /{game}/boards/{board}
+var boardPath = regexp.MustCompile(`^(/admin/games)?(/[\w\d]+)/boards/([\w\d]+)(/.*)?$`)
+
 type ApiDefinition struct {
 	Route    string
 	PlayerId string
 	Game     string
+	// Board is the board of the game the path is within, empty for the game's own leaderboard
+	Board string
+	KeyId string
+	// GroupId is the id of a group of players within the game
+	GroupId string
+	// ScoreId is the id of a quarantined score, or the value of a player's score which identifies it among the player's scores
+	ScoreId string
+}
+
+type apiDescription struct {
+	route string
+	regex regexp.Regexp
+	// gamePart, playerIdPart, keyIdPart, groupIdPart and scoreIdPart are the indexes of the path segments holding them, zero when the route has none
+	gamePart     int
+	playerIdPart int
+	keyIdPart    int
+	groupIdPart  int
+	scoreIdPart  int
+	// boards is whether the route can be within a board, as can those of scores and ranks but not those of players, groups or api keys
+	boards bool
 }
 
 func EventPathToApiDefinition(path string) (ApiDefinition, error) {
-	type apiDescription struct {
-		regex           regexp.Regexp
-		hasGamePath     bool
-		hasPlayerIdPath bool
-	}
 	routes := NewApiRoutes()
-	apiPaths := map[string]apiDescription{
-		routes.ScoresByPlayer: {regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/scores/?$`), hasGamePath: true, hasPlayerIdPath: true},
-		routes.RanksByPlayer:  {regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/ranks/?$`), hasGamePath: true, hasPlayerIdPath: true},
-		routes.Ranks:          {regex: *regexp.MustCompile(`^/[\w\d]+/ranks/?$`), hasGamePath: true},
+	// the admin paths come first, since /admin/games/{game} would otherwise be mistaken for a game named admin
+	apiPaths := []apiDescription{
+		{route: routes.AdminGames, regex: *regexp.MustCompile(`^/admin/games/?$`)},
+		{route: routes.AdminGame, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/?$`), gamePart: 3, boards: true},
+		{route: routes.AdminQuarantine, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/quarantine/?$`), gamePart: 3, boards: true},
+		{route: routes.AdminQuarantinedScore, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/quarantine/[\w\d]+/?$`), gamePart: 3, scoreIdPart: 5, boards: true},
+		{route: routes.AdminApproveScore, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/quarantine/[\w\d]+/approve/?$`), gamePart: 3, scoreIdPart: 5, boards: true},
+		{route: routes.AdminPlayer, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/players/[\w\d]+/?$`), gamePart: 3, playerIdPart: 5, boards: true},
+		{route: routes.AdminPlayerScore, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/players/[\w\d]+/scores/-?\d+/?$`), gamePart: 3, playerIdPart: 5, scoreIdPart: 7, boards: true},
+		{route: routes.AdminBans, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/bans/?$`), gamePart: 3},
+		{route: routes.AdminBan, regex: *regexp.MustCompile(`^/admin/games/[\w\d]+/bans/[\w\d]+/?$`), gamePart: 3, playerIdPart: 5},
+		{route: routes.AdminKeys, regex: *regexp.MustCompile(`^/admin/keys/?$`)},
+		{route: routes.AdminKey, regex: *regexp.MustCompile(`^/admin/keys/[\w\d]+/?$`), keyIdPart: 3},
+		{route: routes.ScoresByPlayer, regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/scores/?$`), gamePart: 1, playerIdPart: 2, boards: true},
+		{route: routes.RanksByPlayer, regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/ranks/?$`), gamePart: 1, playerIdPart: 2, boards: true},
+		{route: routes.SessionsByPlayer, regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/sessions/?$`), gamePart: 1, playerIdPart: 2},
+		{route: routes.ProfileByPlayer, regex: *regexp.MustCompile(`^/[\w\d]+/[\w\d]+/profile/?$`), gamePart: 1, playerIdPart: 2},
+		{route: routes.ScoresBatch, regex: *regexp.MustCompile(`^/[\w\d]+/scores:batch/?$`), gamePart: 1, boards: true},
+		{route: routes.Ranks, regex: *regexp.MustCompile(`^/[\w\d]+/ranks/?$`), gamePart: 1, boards: true},
+		{route: routes.GroupRanks, regex: *regexp.MustCompile(`^/[\w\d]+/ranks:friends/?$`), gamePart: 1, boards: true},
+		// the routes of a group come after those of players, so a player named groups keeps their routes
+		{route: routes.Groups, regex: *regexp.MustCompile(`^/[\w\d]+/groups/?$`), gamePart: 1},
+		{route: routes.Group, regex: *regexp.MustCompile(`^/[\w\d]+/groups/[\w\d]+/?$`), gamePart: 1, groupIdPart: 3},
 	}
 
-	for k, v := range apiPaths {
-		definition := ApiDefinition{}
+	definition, ok := matchApiPath(apiPaths, path, false)
+	if ok {
+		return definition, nil
+	}
+	// a path within a board is matched as the path of its game with the board left out
+	if parts := boardPath.FindStringSubmatch(path); parts != nil {
+		definition, ok := matchApiPath(apiPaths, parts[1]+parts[2]+parts[4], true)
+		if ok {
+			definition.Board = parts[3]
+			return definition, nil
+		}
+	}
+	return ApiDefinition{}, errors.New("No matching api found")
+}
+
+// func matchApiPath finds the first route matching the path, inBoard limits it to the routes which can be within a board
+func matchApiPath(apiPaths []apiDescription, path string, inBoard bool) (ApiDefinition, bool) {
+	for _, v := range apiPaths {
+		if inBoard && !v.boards {
+			continue
+		}
 		if v.regex.Match([]byte(path)) {
-			definition.Route = k
+			definition := ApiDefinition{Route: v.route}
 			parts := strings.Split(path, "/")
-			if v.hasGamePath {
-				definition.Game = parts[1]
+			if v.gamePart > 0 {
+				definition.Game = parts[v.gamePart]
 			}
-			if v.hasPlayerIdPath {
-				definition.PlayerId = parts[2]
+			if v.playerIdPart > 0 {
+				definition.PlayerId = parts[v.playerIdPart]
 			}
-
-			return definition, nil
+			if v.keyIdPart > 0 {
+				definition.KeyId = parts[v.keyIdPart]
+			}
+			if v.groupIdPart > 0 {
+				definition.GroupId = parts[v.groupIdPart]
+			}
+			if v.scoreIdPart > 0 {
+				definition.ScoreId = parts[v.scoreIdPart]
+			}
+			if v.gamePart == 1 && definition.Game == reservedGame {
+				return ApiDefinition{}, false
+			}
+			return definition, true
 		}
 	}
-
-	return ApiDefinition{}, errors.New("No matching api found")
+	return ApiDefinition{}, false
 }
@@ -13,8 +13,37 @@ func TestValidApiPaths(t *testing.T) {
 		{input: "/pp1/abc/scores/", want: ApiDefinition{Route: "/{game}/{player_id}/scores", Game: "pp1", PlayerId: "abc"}},
 		{input: "/1/sasa/scores", want: ApiDefinition{Route: "/{game}/{player_id}/scores", Game: "1", PlayerId: "sasa"}},
 		{input: "/duck/goose/ranks", want: ApiDefinition{Route: "/{game}/{player_id}/ranks", Game: "duck", PlayerId: "goose"}},
+		{input: "/duck/goose/sessions", want: ApiDefinition{Route: "/{game}/{player_id}/sessions", Game: "duck", PlayerId: "goose"}},
 		{input: "/duck/ranks", want: ApiDefinition{Route: "/{game}/ranks", Game: "duck", PlayerId: ""}},
 		{input: "/duck/ranks/", want: ApiDefinition{Route: "/{game}/ranks", Game: "duck", PlayerId: ""}},
+		{input: "/duck/scores:batch", want: ApiDefinition{Route: "/{game}/scores:batch", Game: "duck"}},
+		{input: "/duck/ranks:friends", want: ApiDefinition{Route: "/{game}/ranks:friends", Game: "duck"}},
+		{input: "/duck/groups", want: ApiDefinition{Route: "/{game}/groups", Game: "duck"}},
+		{input: "/duck/groups/9c1e/", want: ApiDefinition{Route: "/{game}/groups/{group_id}", Game: "duck", GroupId: "9c1e"}},
+		{input: "/duck/groups/ranks", want: ApiDefinition{Route: "/{game}/{player_id}/ranks", Game: "duck", PlayerId: "groups"}},
+		{input: "/admin/games", want: ApiDefinition{Route: "/admin/games"}},
+		{input: "/admin/games/", want: ApiDefinition{Route: "/admin/games"}},
+		{input: "/admin/games/duck", want: ApiDefinition{Route: "/admin/games/{game}", Game: "duck"}},
+		{input: "/admin/games/scores", want: ApiDefinition{Route: "/admin/games/{game}", Game: "scores"}},
+		{input: "/admin/games/duck/quarantine", want: ApiDefinition{Route: "/admin/games/{game}/quarantine", Game: "duck"}},
+		{input: "/admin/games/duck/quarantine/9c1e", want: ApiDefinition{Route: "/admin/games/{game}/quarantine/{score_id}", Game: "duck", ScoreId: "9c1e"}},
+		{input: "/admin/games/duck/quarantine/9c1e/approve/", want: ApiDefinition{Route: "/admin/games/{game}/quarantine/{score_id}/approve", Game: "duck", ScoreId: "9c1e"}},
+		{input: "/duck/456/profile", want: ApiDefinition{Route: "/{game}/{player_id}/profile", Game: "duck", PlayerId: "456"}},
+		{input: "/admin/games/duck/players/456", want: ApiDefinition{Route: "/admin/games/{game}/players/{player_id}", Game: "duck", PlayerId: "456"}},
+		{input: "/admin/games/duck/players/456/scores/-20", want: ApiDefinition{Route: "/admin/games/{game}/players/{player_id}/scores/{score}", Game: "duck", PlayerId: "456", ScoreId: "-20"}},
+		{input: "/admin/games/duck/bans", want: ApiDefinition{Route: "/admin/games/{game}/bans", Game: "duck"}},
+		{input: "/admin/games/duck/bans/456/", want: ApiDefinition{Route: "/admin/games/{game}/bans/{player_id}", Game: "duck", PlayerId: "456"}},
+		{input: "/admin/keys", want: ApiDefinition{Route: "/admin/keys"}},
+		{input: "/admin/keys/3f9a0c", want: ApiDefinition{Route: "/admin/keys/{key_id}", KeyId: "3f9a0c"}},
+		{input: "/duck/boards/level1/ranks", want: ApiDefinition{Route: "/{game}/ranks", Game: "duck", Board: "level1"}},
+		{input: "/duck/boards/level1/123/scores/", want: ApiDefinition{Route: "/{game}/{player_id}/scores", Game: "duck", Board: "level1", PlayerId: "123"}},
+		{input: "/duck/boards/level1/123/ranks", want: ApiDefinition{Route: "/{game}/{player_id}/ranks", Game: "duck", Board: "level1", PlayerId: "123"}},
+		{input: "/duck/boards/level1/scores:batch", want: ApiDefinition{Route: "/{game}/scores:batch", Game: "duck", Board: "level1"}},
+		{input: "/duck/boards/level1/ranks:friends", want: ApiDefinition{Route: "/{game}/ranks:friends", Game: "duck", Board: "level1"}},
+		{input: "/duck/boards/scores", want: ApiDefinition{Route: "/{game}/{player_id}/scores", Game: "duck", PlayerId: "boards"}},
+		{input: "/admin/games/duck/boards/level1", want: ApiDefinition{Route: "/admin/games/{game}", Game: "duck", Board: "level1"}},
+		{input: "/admin/games/duck/boards/level1/quarantine/9c1e/approve", want: ApiDefinition{Route: "/admin/games/{game}/quarantine/{score_id}/approve", Game: "duck", Board: "level1", ScoreId: "9c1e"}},
+		{input: "/admin/games/duck/boards/level1/players/456", want: ApiDefinition{Route: "/admin/games/{game}/players/{player_id}", Game: "duck", Board: "level1", PlayerId: "456"}},
 	}
 
 	for _, tc := range testCases {
@@ -25,9 +54,21 @@ func TestValidApiPaths(t *testing.T) {
 		if tc.want.Game != got.Game {
 			t.Errorf("want %v, got %v, input %v", tc.want.Game, got.Game, tc.input)
 		}
+		if tc.want.Board != got.Board {
+			t.Errorf("want %v, got %v, input %v", tc.want.Board, got.Board, tc.input)
+		}
 		if tc.want.PlayerId != got.PlayerId {
 			t.Errorf("want %v, got %v, input %v", tc.want.PlayerId, got.PlayerId, tc.input)
 		}
+		if tc.want.KeyId != got.KeyId {
+			t.Errorf("want %v, got %v, input %v", tc.want.KeyId, got.KeyId, tc.input)
+		}
+		if tc.want.GroupId != got.GroupId {
+			t.Errorf("want %v, got %v, input %v", tc.want.GroupId, got.GroupId, tc.input)
+		}
+		if tc.want.ScoreId != got.ScoreId {
+			t.Errorf("want %v, got %v, input %v", tc.want.ScoreId, got.ScoreId, tc.input)
+		}
 	}
 }
 
@@ -44,6 +85,27 @@ func TestInvalidApiPaths(t *testing.T) {
 		{input: "/duck/"},
 		{input: "/duck/123/scores/rabbits"},
 		{input: "/duck/score"},
+		{input: "/admin/ranks"},
+		{input: "/admin/123/ranks"},
+		{input: "/admin/123/scores"},
+		{input: "/admin/123/sessions"},
+		{input: "/admin/123/profile"},
+		{input: "/admin/scores:batch"},
+		{input: "/duck/scores:batches"},
+		{input: "/admin/groups"},
+		{input: "/admin/ranks:friends"},
+		{input: "/duck/groups/9c1e/ranks"},
+		{input: "/duck/boards/level1/groups/9c1e"},
+		{input: "/admin/games/duck/goose"},
+		{input: "/admin/keys/3f9a0c/revoke"},
+		{input: "/admin/games/duck/quarantine/9c1e/reject"},
+		{input: "/admin/games/duck/players/456/scores/abc"},
+		{input: "/duck/boards/level1"},
+		{input: "/duck/boards/level1/123/sessions"},
+		{input: "/duck/boards/level1/123/profile"},
+		{input: "/admin/boards/level1/ranks"},
+		{input: "/admin/games/duck/boards/level1/bans"},
+		{input: "/duck/boards/level1/boards/level2/ranks"},
 	}
 
 	for _, tc := range testCases {
@@ -1,11 +1,18 @@
 package ddb
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -14,12 +21,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/indimeco/cheerleader/internal/models"
+	"golang.org/x/sync/errgroup"
 )
 
 type DynamoScoreDatabase struct {
 	tableName string
 	client    *dynamodb.Client
 	rankLimit int
+	// rankReadLimit bounds the items read for top ranks which dynamodb cannot find by key, filtered or of unique players in a window
+	rankReadLimit int
 }
 
 var ddbClient *dynamodb.Client
@@ -50,15 +60,23 @@ func New(ctx context.Context) (DynamoScoreDatabase, error) {
 
 	/**
 	 * DdbMaxRanksLimit is set in order to simplify the possible load placed on any given instance of the service
-	 * the constraint prevents expensive query operations and ensures fast response time by limiting the rank requests to a single 'page' of data
+	 * the constraint prevents expensive query operations and ensures fast response time by limiting rank requests without a limit to a single 'page' of data
+	 * a request with a limit is bounded by its game's maxRanksLimit instead, which defaults to the same
 	 * 1000 is very appoximately the maximum theoretical size that DDB can return in one response given the 1MB limitation and the maximum data size of a single rank
 	 */
 	const ddbMaxRanksLimit = 1000
 
+	/**
+	 * DdbMaxRankRead bounds the cost of top ranks which are found by reading through a leaderboard's scores, as those matching a filter or the best of each player in a window are
+	 * 5000 is about five pages, after which the ranks found so far are returned even when there are fewer than were asked for
+	 */
+	const ddbMaxRankRead = 5000
+
 	return DynamoScoreDatabase{
-		tableName: tableName,
-		client:    ddbClient,
-		rankLimit: ddbMaxRanksLimit,
+		tableName:     tableName,
+		client:        ddbClient,
+		rankLimit:     ddbMaxRanksLimit,
+		rankReadLimit: ddbMaxRankRead,
 	}, nil
 }
 
@@ -66,94 +84,1658 @@ func (d DynamoScoreDatabase) getDdbPk(playerId string, game string) string {
 	return fmt.Sprintf("%v|%v", playerId, game)
 }
 
-func (d DynamoScoreDatabase) getDdbCompositeKey(s models.Score) map[string]types.AttributeValue {
-	pk := d.getDdbPk(s.PlayerId, s.Game)
-	sk := s.Score
-	pkAttr, err := attributevalue.Marshal(pk)
-	if err != nil {
-		panic(err)
+func (d DynamoScoreDatabase) getDdbBestKey(playerId string, leaderboard string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("best#%v|%v", playerId, leaderboard)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
 	}
-	skAttr, err := attributevalue.Marshal(sk)
-	if err != nil {
-		panic(err)
+}
+
+// gamesPartition groups the registry items in GameScoresIndex, no game can be named it as game names are alphanumeric
+const gamesPartition = "#games"
+
+// func getDdbGameKey is the key of a game's item in the registry, beginning with # so that it cannot collide with a player's scores
+func (d DynamoScoreDatabase) getDdbGameKey(game string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#game|%v", game)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// apiKeysPartition groups the api key items in GameScoresIndex, in the same way as gamesPartition
+const apiKeysPartition = "#keys"
+
+func (d DynamoScoreDatabase) getDdbApiKeyKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#key|%v", id)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+func (d DynamoScoreDatabase) getDdbCounterKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#counter|%v", key)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+func (d DynamoScoreDatabase) getDdbBucketKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#bucket|%v", key)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+func (d DynamoScoreDatabase) getDdbIdempotencyKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#idempotency|%v", key)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func getQuarantinePartition groups a game's quarantined scores in GameScoresIndex
+func getQuarantinePartition(game string) string {
+	return fmt.Sprintf("#quarantine|%v", game)
+}
+
+func (d DynamoScoreDatabase) getDdbQuarantineKey(game string, id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#quarantine|%v|%v", game, id)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func getBansPartition groups a game's bans in GameScoresIndex
+func getBansPartition(game string) string {
+	return fmt.Sprintf("#bans|%v", game)
+}
+
+func (d DynamoScoreDatabase) getDdbBanKey(game string, playerId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#ban|%v|%v", game, playerId)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func getDdbProfileKey is the key of a player's profile, which has no game attribute as profiles are only read by key
+func (d DynamoScoreDatabase) getDdbProfileKey(game string, playerId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#profile|%v|%v", game, playerId)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func getDdbGroupKey is the key of a group of players, which has no game attribute as groups are only read by key
+func (d DynamoScoreDatabase) getDdbGroupKey(game string, id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#group|%v|%v", game, id)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func getDdbPlayerBoardsKey is the key of the set of the game's boards on which the player has scores, as a player's scores are partitioned by board
+func (d DynamoScoreDatabase) getDdbPlayerBoardsKey(game string, playerId string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#boards|%v|%v", game, playerId)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
 	}
-	return map[string]types.AttributeValue{"pk": pkAttr, "sk": skAttr}
 }
 
+// func getDdbNonceKey is the key of a used nonce, which has no game attribute so it stays out of the indexes
+func (d DynamoScoreDatabase) getDdbNonceKey(game string, nonce string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("#nonce|%v|%v", game, nonce)},
+		"sk": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+// func PutScore writes the score to the all time leaderboard and to a copy for each of its periods, so that a window is its own partition of the ranking indexes
+// only the all time leaderboard keeps a best score item, the best scores of a period are found from its copies when they are read, as its leaderboard is short lived and writes are the table's scarcest capacity
 func (d DynamoScoreDatabase) PutScore(ctx context.Context, score models.Score) error {
 	item, err := attributevalue.MarshalMap(&score)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal score: %w", err)
+	}
 
-	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return d.putScoreItem(gctx, d.getAllTimeItem(item, score))
+	})
+	g.Go(func() error {
+		return d.putBestScore(gctx, score)
+	})
+	g.Go(func() error {
+		return d.addPlayerBoard(gctx, score.Game, score.PlayerId)
+	})
+	for _, period := range score.Periods {
+		g.Go(func() error {
+			return d.putScoreItem(gctx, d.getPeriodItem(item, score, period))
+		})
+	}
+	return g.Wait()
+}
+
+// func PutScores writes the items of every score in batches, then updates the best scores they beat
+// dynamodb has no transaction this large, so a failure may leave some of the scores stored
+func (d DynamoScoreDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	// a batch may not write the same key twice, the last submission of a key is kept as it would be by putting the scores in turn
+	requests := make(map[string]types.WriteRequest)
+	keys := make([]string, 0)
+	for _, score := range scores {
+		item, err := attributevalue.MarshalMap(&score)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal score: %w", err)
+		}
+		items := []map[string]types.AttributeValue{d.getAllTimeItem(item, score)}
+		for _, period := range score.Periods {
+			items = append(items, d.getPeriodItem(item, score, period))
+		}
+		for _, item := range items {
+			key := fmt.Sprintf("%v|%v", item["pk"].(*types.AttributeValueMemberS).Value, item["sk"].(*types.AttributeValueMemberN).Value)
+			if _, ok := requests[key]; !ok {
+				keys = append(keys, key)
+			}
+			requests[key] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+		}
+	}
+	writes := make([]types.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		writes = append(writes, requests[key])
+	}
+	err := d.batchWrite(ctx, writes)
+	if err != nil {
+		return fmt.Errorf("Failed to put scores: %w", err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	// each score updates the player's best score, which is limited so that a batch does not exhaust the table's capacity at once
+	g.SetLimit(25)
+	boards := make(map[[2]string]bool)
+	for _, score := range scores {
+		g.Go(func() error {
+			return d.putBestScore(gctx, score)
+		})
+		board := [2]string{score.Game, score.PlayerId}
+		if !boards[board] {
+			boards[board] = true
+			g.Go(func() error {
+				return d.addPlayerBoard(gctx, score.Game, score.PlayerId)
+			})
+		}
+	}
+	return g.Wait()
+}
+
+// func addPlayerBoard records that the player has scores on the board of partition, so that they can be found without reading every board
+// a score on the game's own leaderboard is found by its key, so only boards are recorded and the scores of a game without boards cost nothing more
+func (d DynamoScoreDatabase) addPlayerBoard(ctx context.Context, partition string, playerId string) error {
+	game := models.PartitionGame(partition)
+	if game == partition {
+		return nil
+	}
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbPlayerBoardsKey(game, playerId),
+		// the expression builder cannot add to a string set, so the update is written out
+		UpdateExpression:          aws.String("ADD boards :board"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":board": &types.AttributeValueMemberSS{Value: []string{partition}}},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record player board: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) putScoreItem(ctx context.Context, item map[string]types.AttributeValue) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
 		Item:      item,
 	})
+	if err != nil {
+		return fmt.Errorf("Failed to put score: %w", err)
+	}
+	return nil
+}
+
+// func getAllTimeItem records the leaderboards of the score's periods on its all time item, so that deleting the score can find its copies
+func (d DynamoScoreDatabase) getAllTimeItem(item map[string]types.AttributeValue, score models.Score) map[string]types.AttributeValue {
+	if len(score.Periods) == 0 {
+		return item
+	}
+	leaderboards := make([]string, 0, len(score.Periods))
+	for _, period := range score.Periods {
+		leaderboards = append(leaderboards, period.Leaderboard(score.Game))
+	}
+	allTimeItem := maps.Clone(item)
+	allTimeItem["periods"] = &types.AttributeValueMemberSS{Value: leaderboards}
+	return allTimeItem
+}
+
+// func getPeriodItem copies a marshalled score into the period's leaderboard, where it expires shortly after the period ends unless the game keeps scores for less time
+func (d DynamoScoreDatabase) getPeriodItem(item map[string]types.AttributeValue, score models.Score, period models.Period) map[string]types.AttributeValue {
+	leaderboard := period.Leaderboard(score.Game)
+	periodItem := maps.Clone(item)
+	periodItem["pk"] = &types.AttributeValueMemberS{Value: d.getDdbPk(score.PlayerId, leaderboard)}
+	periodItem["game"] = &types.AttributeValueMemberS{Value: leaderboard}
+	periodItem["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(min(period.Expiry(), score.Expiry()))}
+	return periodItem
+}
+
+// func putBestScore keeps one item per player and game holding the player's all time best score, these items are ranked by GameBestScoresIndex
+// the item is only replaced by a better score, which the condition checks atomically so concurrent submissions cannot regress it
+// the sort key of the best score holds the time it was received as the score's own item does, so of equal scores the earliest received is kept
+func (d DynamoScoreDatabase) putBestScore(ctx context.Context, score models.Score) error {
+	leaderboard := score.Game
+	ttl := score.Expiry()
+	sortKey := attributevalue.Number(score.SortKey())
+	update := expression.
+		Set(expression.Name("bgame"), expression.Value(leaderboard)).
+		Set(expression.Name("bsk"), expression.Value(sortKey)).
+		Set(expression.Name("pname"), expression.Value(score.PlayerName)).
+		Set(expression.Name("ts"), expression.Value(score.Timestamp)).
+		Set(expression.Name("rts"), expression.Value(score.ReceivedAt)).
+		Set(expression.Name("ttl"), expression.Value(ttl))
+	// the metadata of a previous best score must not be left on a better one without any
+	if len(score.Metadata) > 0 {
+		update = update.Set(expression.Name("meta"), expression.Value(score.Metadata))
+	} else {
+		update = update.Remove(expression.Name("meta"))
+	}
+	worse := expression.Name("bsk").LessThan(expression.Value(sortKey))
+	if score.Order == models.LowestFirst {
+		worse = expression.Name("bsk").GreaterThan(expression.Value(sortKey))
+	}
+	condition := expression.Or(
+		expression.AttributeNotExists(expression.Name("bsk")),
+		worse,
+	)
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return fmt.Errorf("Failed to build best score expression: %w", err)
+	}
 
-	return err
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.tableName),
+		Key:                       d.getDdbBestKey(score.PlayerId, leaderboard),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		// the player already has a better score
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to update best score: %w", err)
+	}
+	return nil
 }
 
+// func GetTopPlayerScores reads the player's scores best first, following pagination only when a filter leaves a page short of the limit
 func (d DynamoScoreDatabase) GetTopPlayerScores(ctx context.Context, scoreRequest models.PlayerScoreRequest) ([]models.Score, error) {
-	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(scoreRequest.PlayerId, scoreRequest.Game)))
-	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(scoreRequest.PlayerId, scoreRequest.Period.Leaderboard(scoreRequest.Game))))
+	expr, err := buildQuery(keyEx, scoreRequest.Filter)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to build key expression: %w", err)
+		return nil, err
 	}
-	items, err := d.client.Query(ctx, &dynamodb.QueryInput{
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
 		TableName:                 &d.tableName,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
 		Limit:                     aws.Int32(int32(scoreRequest.Limit)),
-		ScanIndexForward:          aws.Bool(false), // reverse the sort order to get the highest scores
+		ScanIndexForward:          aws.Bool(scanIndexForward(true, scoreRequest.Order)),
 	})
+
+	scores := make([]models.Score, 0, scoreRequest.Limit)
+	for paginator.HasMorePages() && len(scores) < scoreRequest.Limit {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query player scores: %w", err)
+		}
+		for _, marshalledScore := range page.Items {
+			var score models.Score
+			err := attributevalue.UnmarshalMap(marshalledScore, &score)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to unmarshall a score: %w", err)
+			}
+			scores = append(scores, score)
+		}
+	}
+	if len(scores) > scoreRequest.Limit {
+		scores = scores[:scoreRequest.Limit]
+	}
+	return scores, nil
+}
+
+// func DeleteScore removes each submission of the score from the all time leaderboard and from the leaderboard of each period it was entered into
+// the submissions are every sort key from the score up to the next, and the player's best score is then found again from the scores that remain
+func (d DynamoScoreDatabase) DeleteScore(ctx context.Context, score models.Score) error {
+	first, last := models.SortKeyBounds(score.Score)
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(score.PlayerId, score.Game))).
+		And(expression.Key("sk").Between(expression.Value(attributevalue.Number(first)), expression.Value(attributevalue.Number(last))))
+	keys, err := d.getScoreKeys(ctx, keyEx, score.PlayerId)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to query player scores: %w", err)
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	err = d.batchWrite(ctx, deleteRequests(keys))
+	if err != nil {
+		return fmt.Errorf("Failed to delete score: %w", err)
+	}
+	return d.refreshBestScore(ctx, score.PlayerId, score.Game, score.Order)
+}
+
+// func DeletePlayerScores removes every score of the player in the game, along with their copies in period leaderboards and the player's best score
+func (d DynamoScoreDatabase) DeletePlayerScores(ctx context.Context, game string, playerId string) error {
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(playerId, game)))
+	keys, err := d.getScoreKeys(ctx, keyEx, playerId)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, d.getDdbBestKey(playerId, game))
+
+	err = d.batchWrite(ctx, deleteRequests(keys))
+	if err != nil {
+		return fmt.Errorf("Failed to delete player scores: %w", err)
 	}
 
-	scores := make([]models.Score, 0, items.Count)
-	for _, marshalledScore := range items.Items {
-		var score models.Score
-		err := attributevalue.UnmarshalMap(marshalledScore, &score)
+	if owner := models.PartitionGame(game); owner != game {
+		_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(d.tableName),
+			Key:                       d.getDdbPlayerBoardsKey(owner, playerId),
+			UpdateExpression:          aws.String("DELETE boards :board"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":board": &types.AttributeValueMemberSS{Value: []string{game}}},
+		})
 		if err != nil {
-			return nil, fmt.Errorf("Failed to unmarshall a score: %w", err)
+			return fmt.Errorf("Failed to forget player board: %w", err)
 		}
-		scores = append(scores, score)
 	}
-	return scores, nil
+	return nil
 }
 
-func (d DynamoScoreDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRequest) (models.Ranks, error) {
-	keyEx := expression.Key("game").Equal(expression.Value(ranksRequest.Game))
+// func GetPlayerPartitions finds the player's scores on the game's own leaderboard by their key, and the boards with scores of the player from the set recorded as they were put
+// a board stays in the set once its scores have expired or been deleted one by one, so it may hold none
+func (d DynamoScoreDatabase) GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbPlayerBoardsKey(game, playerId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get player boards: %w", err)
+	}
+	var boards struct {
+		Boards []string `dynamodbav:"boards,stringset"`
+	}
+	err = attributevalue.UnmarshalMap(out.Item, &boards)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshall player boards: %w", err)
+	}
+
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(playerId, game)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build key expression: %w", err)
+	}
+	page, err := d.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 &d.tableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		Limit:                     aws.Int32(1),
+		Select:                    types.SelectCount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player scores: %w", err)
+	}
+	if page.Count > 0 {
+		return append([]string{game}, boards.Boards...), nil
+	}
+	if boards.Boards == nil {
+		return []string{}, nil
+	}
+	return boards.Boards, nil
+}
+
+// func RenamePlayer sets the name shown on every score of the player in the game, along with their copies in period leaderboards and the player's best score
+// each item is updated in turn, which is slow for a player with many scores, but a rename is rare beside the reads of names it saves
+func (d DynamoScoreDatabase) RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error {
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(playerId, game)))
+	keys, err := d.getScoreKeys(ctx, keyEx, playerId)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, d.getDdbBestKey(playerId, game))
+	// an update creates a missing item, so only those which exist are renamed
+	expr, err := expression.NewBuilder().
+		WithUpdate(expression.Set(expression.Name("pname"), expression.Value(playerName))).
+		WithCondition(expression.AttributeExists(expression.Name("pk"))).
+		Build()
+	if err != nil {
+		return fmt.Errorf("Failed to build rename expression: %w", err)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(25)
+	for _, key := range keys {
+		g.Go(func() error {
+			_, err := d.client.UpdateItem(gctx, &dynamodb.UpdateItemInput{
+				TableName:                 aws.String(d.tableName),
+				Key:                       key,
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+				UpdateExpression:          expr.Update(),
+				ConditionExpression:       expr.Condition(),
+			})
+			var conditionErr *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionErr) {
+				// the score was deleted or expired while the player was renamed
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to rename player: %w", err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// func getScoreKeys returns the keys of the player's all time items matching keyEx and of their copies in period leaderboards
+func (d DynamoScoreDatabase) getScoreKeys(ctx context.Context, keyEx expression.KeyConditionBuilder, playerId string) ([]map[string]types.AttributeValue, error) {
 	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to build key expression: %w", err)
 	}
 
-	limit := d.rankLimit
-	if ranksRequest.Limit > 0 {
-		limit = min(d.rankLimit, ranksRequest.Limit)
+	keys := make([]map[string]types.AttributeValue, 0)
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:                 &d.tableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query player scores: %w", err)
+		}
+		for _, item := range page.Items {
+			keys = append(keys, map[string]types.AttributeValue{"pk": item["pk"], "sk": item["sk"]})
+			for _, leaderboard := range getPeriodLeaderboards(item) {
+				keys = append(keys, map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: d.getDdbPk(playerId, leaderboard)},
+					"sk": item["sk"],
+				})
+			}
+		}
+	}
+	return keys, nil
+}
+
+func deleteRequests(keys []map[string]types.AttributeValue) []types.WriteRequest {
+	requests := make([]types.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+	}
+	return requests
+}
+
+// func batchWrite makes the writes in batches, retrying any that a batch leaves unprocessed
+func (d DynamoScoreDatabase) batchWrite(ctx context.Context, writes []types.WriteRequest) error {
+	// BatchWriteItem writes at most 25 items per request
+	for start := 0; start < len(writes); start += 25 {
+		batch := writes[start:min(start+25, len(writes))]
+		request := map[string][]types.WriteRequest{d.tableName: batch}
+		for len(request) > 0 {
+			out, err := d.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+			request = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// func getPeriodLeaderboards reads the period leaderboards recorded on an all time item by getAllTimeItem
+// scores submitted before they were recorded have none, their period copies are left to expire with the period
+func getPeriodLeaderboards(item map[string]types.AttributeValue) []string {
+	periods, ok := item["periods"].(*types.AttributeValueMemberSS)
+	if !ok {
+		return nil
 	}
-	items, err := d.client.Query(ctx, &dynamodb.QueryInput{
+	return periods.Value
+}
+
+// func refreshBestScore replaces the player's best score item in the leaderboard with their best remaining score, or removes it when none remain
+// unlike putBestScore the replacement is unconditional, so a score submitted at the same moment may need submitting again to be counted as the best
+func (d DynamoScoreDatabase) refreshBestScore(ctx context.Context, playerId string, leaderboard string, order models.Order) error {
+	keyEx := expression.Key("pk").Equal(expression.Value(d.getDdbPk(playerId, leaderboard)))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return fmt.Errorf("Failed to build key expression: %w", err)
+	}
+	out, err := d.client.Query(ctx, &dynamodb.QueryInput{
 		TableName:                 &d.tableName,
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
-		Limit:                     aws.Int32(int32(limit)),
-		ScanIndexForward:          aws.Bool(false), // reverse the sort order to get the highest scores
-		IndexName:                 aws.String("GameScoresIndex"),
+		Limit:                     aws.Int32(1),
+		ScanIndexForward:          aws.Bool(scanIndexForward(true, order)),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to query player ranks: %w", err)
+		return fmt.Errorf("Failed to query best score: %w", err)
 	}
 
-	ranks := make(models.Ranks, 0, items.Count)
-	for i, marshalledRank := range items.Items {
-		var rank models.Rank
-		err := attributevalue.UnmarshalMap(marshalledRank, &rank)
+	if len(out.Items) == 0 {
+		_, err = d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(d.tableName),
+			Key:       d.getDdbBestKey(playerId, leaderboard),
+		})
 		if err != nil {
-			return nil, fmt.Errorf("Failed to unmarshall a rank: %w", err)
+			return fmt.Errorf("Failed to delete best score: %w", err)
 		}
-		rank.Position = i + 1
-		ranks = append(ranks, rank)
+		return nil
+	}
+
+	best := out.Items[0]
+	item := d.getDdbBestKey(playerId, leaderboard)
+	item["bgame"] = &types.AttributeValueMemberS{Value: leaderboard}
+	item["bsk"] = best["sk"]
+	item["pname"] = best["pname"]
+	item["ts"] = best["ts"]
+	item["ttl"] = best["ttl"]
+	// the best score is replaced whole, so whatever it was submitted with is carried over from the score's own item
+	for _, name := range []string{"rts", "meta"} {
+		if value, ok := best[name]; ok {
+			item[name] = value
+		}
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put best score: %w", err)
+	}
+	return nil
+}
+
+// banItem is a ban's item, held as json in the same way as a game's config
+type banItem struct {
+	Ban string `dynamodbav:"ban"`
+}
+
+func (d DynamoScoreDatabase) PutBan(ctx context.Context, ban models.Ban) error {
+	out, err := json.Marshal(ban)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal ban: %w", err)
+	}
+	item := d.getDdbBanKey(ban.Game, ban.PlayerId)
+	item["game"] = &types.AttributeValueMemberS{Value: getBansPartition(ban.Game)}
+	item["ban"] = &types.AttributeValueMemberS{Value: string(out)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put ban: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbBanKey(game, playerId),
+	})
+	if err != nil {
+		return models.Ban{}, false, fmt.Errorf("Failed to get ban: %w", err)
+	}
+	if out.Item == nil {
+		return models.Ban{}, false, nil
+	}
+	ban, err := unmarshalBanItem(out.Item)
+	if err != nil {
+		return models.Ban{}, false, err
+	}
+	return ban, true, nil
+}
+
+func (d DynamoScoreDatabase) GetBans(ctx context.Context, game string) ([]models.Ban, error) {
+	items, err := d.getPartitionItems(ctx, getBansPartition(game))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get bans: %w", err)
+	}
+	bans := make([]models.Ban, 0, len(items))
+	for _, item := range items {
+		ban, err := unmarshalBanItem(item)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, ban)
+	}
+
+	slices.SortFunc(bans, func(a models.Ban, b models.Ban) int {
+		return strings.Compare(a.PlayerId, b.PlayerId)
+	})
+	return bans, nil
+}
+
+func (d DynamoScoreDatabase) DeleteBan(ctx context.Context, game string, playerId string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbBanKey(game, playerId),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete ban: %w", err)
+	}
+	return nil
+}
+
+// profileItem is a player's profile item, held as json in the same way as a game's config
+type profileItem struct {
+	Profile string `dynamodbav:"profile"`
+}
+
+func (d DynamoScoreDatabase) PutProfile(ctx context.Context, profile models.Profile) error {
+	out, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal profile: %w", err)
+	}
+	item := d.getDdbProfileKey(profile.Game, profile.PlayerId)
+	item["profile"] = &types.AttributeValueMemberS{Value: string(out)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put profile: %w", err)
+	}
+	return nil
+}
+
+// func GetProfiles returns the profiles of those players in the game who have one, in no particular order
+func (d DynamoScoreDatabase) GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error) {
+	profiles := make([]models.Profile, 0, len(playerIds))
+	// BatchGetItem reads at most 100 keys per request
+	for start := 0; start < len(playerIds); start += 100 {
+		keys := make([]map[string]types.AttributeValue, 0, 100)
+		for _, playerId := range playerIds[start:min(start+100, len(playerIds))] {
+			keys = append(keys, d.getDdbProfileKey(game, playerId))
+		}
+		request := map[string]types.KeysAndAttributes{d.tableName: {Keys: keys}}
+		for len(request) > 0 {
+			out, err := d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get profiles: %w", err)
+			}
+			for _, item := range out.Responses[d.tableName] {
+				var pItem profileItem
+				err := attributevalue.UnmarshalMap(item, &pItem)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to unmarshall a profile: %w", err)
+				}
+				var profile models.Profile
+				err = json.Unmarshal([]byte(pItem.Profile), &profile)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to unmarshall a profile: %w", err)
+				}
+				profiles = append(profiles, profile)
+			}
+			request = out.UnprocessedKeys
+		}
+	}
+	return profiles, nil
+}
+
+// groupItem is a group's item, held as json in the same way as a game's config
+type groupItem struct {
+	Group string `dynamodbav:"group"`
+}
+
+func (d DynamoScoreDatabase) PutGroup(ctx context.Context, group models.Group) error {
+	out, err := json.Marshal(group)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal group: %w", err)
+	}
+	item := d.getDdbGroupKey(group.Game, group.Id)
+	item["group"] = &types.AttributeValueMemberS{Value: string(out)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put group: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbGroupKey(game, id),
+	})
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to get group: %w", err)
+	}
+	if out.Item == nil {
+		return models.Group{}, false, nil
+	}
+	var gItem groupItem
+	err = attributevalue.UnmarshalMap(out.Item, &gItem)
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to unmarshall a group: %w", err)
+	}
+	var group models.Group
+	err = json.Unmarshal([]byte(gItem.Group), &group)
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to unmarshall a group: %w", err)
+	}
+	return group, true, nil
+}
+
+func (d DynamoScoreDatabase) DeleteGroup(ctx context.Context, game string, id string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbGroupKey(game, id),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete group: %w", err)
+	}
+	return nil
+}
+
+func unmarshalBanItem(item map[string]types.AttributeValue) (models.Ban, error) {
+	var bItem banItem
+	err := attributevalue.UnmarshalMap(item, &bItem)
+	if err != nil {
+		return models.Ban{}, fmt.Errorf("Failed to unmarshall a ban: %w", err)
+	}
+	var ban models.Ban
+	err = json.Unmarshal([]byte(bItem.Ban), &ban)
+	if err != nil {
+		return models.Ban{}, fmt.Errorf("Failed to unmarshall a ban: %w", err)
+	}
+	return ban, nil
+}
+
+// gameItem is a game's registry item, the config is held as json so that new settings need no change to the table
+type gameItem struct {
+	Config string `dynamodbav:"config"`
+}
+
+func (d DynamoScoreDatabase) GetGame(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbGameKey(game),
+	})
+	if err != nil {
+		return models.GameConfig{}, false, fmt.Errorf("Failed to get game: %w", err)
+	}
+	if out.Item == nil {
+		return models.GameConfig{}, false, nil
+	}
+
+	config, err := unmarshalGameItem(out.Item)
+	if err != nil {
+		return models.GameConfig{}, false, err
+	}
+	return config, true, nil
+}
+
+// func GetGames finds every registered game through its item in GameScoresIndex, then reads their configs from the table
+func (d DynamoScoreDatabase) GetGames(ctx context.Context) ([]models.GameConfig, error) {
+	items, err := d.getPartitionItems(ctx, gamesPartition)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get games: %w", err)
+	}
+	configs := make([]models.GameConfig, 0, len(items))
+	for _, item := range items {
+		config, err := unmarshalGameItem(item)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	slices.SortFunc(configs, func(a models.GameConfig, b models.GameConfig) int {
+		return strings.Compare(a.Game, b.Game)
+	})
+	return configs, nil
+}
+
+// func getPartitionItems reads every item whose game attribute is partition
+// GameScoresIndex holds only keys for these items, so the items themselves are read from the table afterwards
+func (d DynamoScoreDatabase) getPartitionItems(ctx context.Context, partition string) ([]map[string]types.AttributeValue, error) {
+	keyEx := expression.Key("game").Equal(expression.Value(partition))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build key expression: %w", err)
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0)
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:                 &d.tableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		IndexName:                 aws.String("GameScoresIndex"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to query keys: %w", err)
+		}
+		for _, item := range page.Items {
+			keys = append(keys, map[string]types.AttributeValue{"pk": item["pk"], "sk": item["sk"]})
+		}
+	}
+
+	items := make([]map[string]types.AttributeValue, 0, len(keys))
+	// BatchGetItem reads at most 100 keys per request
+	for start := 0; start < len(keys); start += 100 {
+		batch := keys[start:min(start+100, len(keys))]
+		request := map[string]types.KeysAndAttributes{d.tableName: {Keys: batch}}
+		for len(request) > 0 {
+			out, err := d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get items: %w", err)
+			}
+			items = append(items, out.Responses[d.tableName]...)
+			request = out.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
+
+func (d DynamoScoreDatabase) PutGame(ctx context.Context, config models.GameConfig) error {
+	out, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal game config: %w", err)
+	}
+	item := d.getDdbGameKey(config.Game)
+	item["game"] = &types.AttributeValueMemberS{Value: gamesPartition}
+	item["config"] = &types.AttributeValueMemberS{Value: string(out)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put game: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) DeleteGame(ctx context.Context, game string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbGameKey(game),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete game: %w", err)
+	}
+	return nil
+}
+
+// func ClaimNonce records the nonce as used for the game until expiry, reporting false if it is already in use
+// dynamodb deletes expired items some time after their ttl, so the condition also lets an expired nonce be claimed again
+func (d DynamoScoreDatabase) ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error) {
+	condition := expression.Or(
+		expression.AttributeNotExists(expression.Name("pk")),
+		expression.Name("ttl").LessThanEqual(expression.Value(time.Now().Unix())),
+	)
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return false, fmt.Errorf("Failed to build nonce expression: %w", err)
+	}
+	item := d.getDdbNonceKey(game, nonce)
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(expiry)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(d.tableName),
+		Item:                      item,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConditionExpression:       expr.Condition(),
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Failed to put nonce: %w", err)
+	}
+	return true, nil
+}
+
+func (d DynamoScoreDatabase) ReleaseNonce(ctx context.Context, game string, nonce string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbNonceKey(game, nonce),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete nonce: %w", err)
+	}
+	return nil
+}
+
+type idempotencyItem struct {
+	RequestHash string `dynamodbav:"request_hash"`
+	StatusCode  int    `dynamodbav:"status"`
+	Body        string `dynamodbav:"body"`
+	Ttl         int    `dynamodbav:"ttl"`
+}
+
+func (d DynamoScoreDatabase) marshalIdempotencyRecord(record models.IdempotencyRecord) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(idempotencyItem{
+		RequestHash: record.RequestHash,
+		StatusCode:  record.StatusCode,
+		Body:        record.Body,
+		Ttl:         record.Expires,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal idempotency record: %w", err)
+	}
+	maps.Copy(item, d.getDdbIdempotencyKey(record.Key))
+	return item, nil
+}
+
+// func ClaimIdempotencyKey stores the pending record unless its key is already in use, in which case the record using it is returned with false
+// the failed condition returns the item in use, so the claim takes a single write whether or not it succeeds
+func (d DynamoScoreDatabase) ClaimIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	condition := expression.Or(
+		expression.AttributeNotExists(expression.Name("pk")),
+		expression.Name("ttl").LessThanEqual(expression.Value(time.Now().Unix())),
+	)
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to build idempotency expression: %w", err)
+	}
+	item, err := d.marshalIdempotencyRecord(record)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                           aws.String(d.tableName),
+		Item:                                item,
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ConditionExpression:                 expr.Condition(),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		var existing idempotencyItem
+		err = attributevalue.UnmarshalMap(conditionErr.Item, &existing)
+		if err != nil {
+			return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to unmarshal idempotency record: %w", err)
+		}
+		return models.IdempotencyRecord{
+			Key:         record.Key,
+			RequestHash: existing.RequestHash,
+			StatusCode:  existing.StatusCode,
+			Body:        existing.Body,
+			Expires:     existing.Ttl,
+		}, false, nil
+	}
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to put idempotency record: %w", err)
+	}
+	return record, true, nil
+}
+
+func (d DynamoScoreDatabase) PutIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord) error {
+	item, err := d.marshalIdempotencyRecord(record)
+	if err != nil {
+		return err
+	}
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbIdempotencyKey(key),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) GetApiKey(ctx context.Context, id string) (models.ApiKey, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbApiKeyKey(id),
+	})
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to get api key: %w", err)
+	}
+	if out.Item == nil {
+		return models.ApiKey{}, false, nil
+	}
+
+	var key models.ApiKey
+	err = attributevalue.UnmarshalMap(out.Item, &key)
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to unmarshall an api key: %w", err)
+	}
+	return key, true, nil
+}
+
+func (d DynamoScoreDatabase) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	items, err := d.getPartitionItems(ctx, apiKeysPartition)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get api keys: %w", err)
+	}
+	keys := make([]models.ApiKey, 0, len(items))
+	err = attributevalue.UnmarshalListOfMaps(items, &keys)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshall api keys: %w", err)
+	}
+
+	slices.SortFunc(keys, func(a models.ApiKey, b models.ApiKey) int {
+		return cmp.Or(strings.Compare(a.Game, b.Game), strings.Compare(a.Id, b.Id))
+	})
+	return keys, nil
+}
+
+func (d DynamoScoreDatabase) PutApiKey(ctx context.Context, key models.ApiKey) error {
+	item, err := attributevalue.MarshalMap(&key)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal api key: %w", err)
+	}
+	maps.Copy(item, d.getDdbApiKeyKey(key.Id))
+	item["game"] = &types.AttributeValueMemberS{Value: apiKeysPartition}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put api key: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) DeleteApiKey(ctx context.Context, id string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbApiKeyKey(id),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete api key: %w", err)
+	}
+	return nil
+}
+
+// maxIncrementAttempts is how many times an expired counter is updated again after another request restarted it first
+const maxIncrementAttempts = 3
+
+// func Increment adds one to the counter and returns its new count, a counter past its expiry starts again from zero
+// dynamodb deletes expired items some time after their ttl, so an expired counter is replaced rather than added to
+// the replacement is conditional on the counter still being expired, so that of concurrent requests only one restarts it and the others add to it
+func (d DynamoScoreDatabase) Increment(ctx context.Context, key string, expiry int) (int, error) {
+	now := time.Now().Unix()
+	update := expression.
+		Add(expression.Name("count"), expression.Value(1)).
+		Set(expression.Name("ttl"), expression.Value(expiry))
+	live := expression.Or(
+		expression.AttributeNotExists(expression.Name("pk")),
+		expression.Name("ttl").GreaterThan(expression.Value(now)),
+	)
+	updateExpr, err := expression.NewBuilder().WithUpdate(update).WithCondition(live).Build()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to build counter expression: %w", err)
+	}
+	expired := expression.Or(
+		expression.AttributeNotExists(expression.Name("pk")),
+		expression.Name("ttl").LessThanEqual(expression.Value(now)),
+	)
+	restartExpr, err := expression.NewBuilder().WithCondition(expired).Build()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to build counter expression: %w", err)
+	}
+
+	for attempt := 0; attempt < maxIncrementAttempts; attempt++ {
+		out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String(d.tableName),
+			Key:                       d.getDdbCounterKey(key),
+			ExpressionAttributeNames:  updateExpr.Names(),
+			ExpressionAttributeValues: updateExpr.Values(),
+			UpdateExpression:          updateExpr.Update(),
+			ConditionExpression:       updateExpr.Condition(),
+			ReturnValues:              types.ReturnValueUpdatedNew,
+		})
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			item := d.getDdbCounterKey(key)
+			item["count"] = &types.AttributeValueMemberN{Value: "1"}
+			item["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(expiry)}
+			_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:                 aws.String(d.tableName),
+				Item:                      item,
+				ExpressionAttributeNames:  restartExpr.Names(),
+				ExpressionAttributeValues: restartExpr.Values(),
+				ConditionExpression:       restartExpr.Condition(),
+			})
+			if errors.As(err, &conditionErr) {
+				// another request restarted the counter first, so this one is added to it
+				continue
+			}
+			if err != nil {
+				return 0, fmt.Errorf("Failed to restart counter: %w", err)
+			}
+			return 1, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Failed to increment counter: %w", err)
+		}
+
+		var counter struct {
+			Count int `dynamodbav:"count"`
+		}
+		err = attributevalue.UnmarshalMap(out.Attributes, &counter)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to unmarshall counter: %w", err)
+		}
+		return counter.Count, nil
+	}
+	return 0, fmt.Errorf("Failed to increment counter %q, it was restarted repeatedly", key)
+}
+
+type bucketItem struct {
+	Tokens  float64 `dynamodbav:"tokens"`
+	Updated int64   `dynamodbav:"updated"`
+	Ttl     int     `dynamodbav:"ttl"`
+}
+
+// maxTakeTokenAttempts is how many times a bucket is read again after another request changed it, before the request is refused
+const maxTakeTokenAttempts = 3
+
+// func TakeToken replaces the bucket only if no other request has changed it since it was read, so concurrent requests cannot take the same token
+func (d DynamoScoreDatabase) TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error) {
+	for attempt := 0; attempt < maxTakeTokenAttempts; attempt++ {
+		out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(d.tableName),
+			Key:            d.getDdbBucketKey(key),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return models.TokenBucket{}, false, fmt.Errorf("Failed to get token bucket: %w", err)
+		}
+		var previous bucketItem
+		if out.Item != nil {
+			err = attributevalue.UnmarshalMap(out.Item, &previous)
+			if err != nil {
+				return models.TokenBucket{}, false, fmt.Errorf("Failed to unmarshal token bucket: %w", err)
+			}
+		}
+		taken, ok := limit.Take(models.TokenBucket{Tokens: previous.Tokens, Updated: previous.Updated}, now)
+		if !ok {
+			return taken, false, nil
+		}
+
+		item, err := attributevalue.MarshalMap(bucketItem{Tokens: taken.Tokens, Updated: taken.Updated, Ttl: limit.Expiry(taken)})
+		if err != nil {
+			return models.TokenBucket{}, false, fmt.Errorf("Failed to marshal token bucket: %w", err)
+		}
+		maps.Copy(item, d.getDdbBucketKey(key))
+		condition := expression.AttributeNotExists(expression.Name("pk"))
+		if out.Item != nil {
+			condition = expression.Name("updated").Equal(expression.Value(previous.Updated))
+		}
+		expr, err := expression.NewBuilder().WithCondition(condition).Build()
+		if err != nil {
+			return models.TokenBucket{}, false, fmt.Errorf("Failed to build token bucket expression: %w", err)
+		}
+		_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:                 aws.String(d.tableName),
+			Item:                      item,
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ConditionExpression:       expr.Condition(),
+		})
+		var conditionErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionErr) {
+			continue
+		}
+		if err != nil {
+			return models.TokenBucket{}, false, fmt.Errorf("Failed to put token bucket: %w", err)
+		}
+		return taken, true, nil
+	}
+	// the bucket is changing faster than it can be read, which only happens while the key is flooded with requests
+	return models.TokenBucket{Updated: now.UnixMilli()}, false, nil
+}
+
+// quarantineItem is a quarantined score's item, held as json in the same way as a game's config
+type quarantineItem struct {
+	Score string `dynamodbav:"quarantined"`
+}
+
+func (d DynamoScoreDatabase) PutQuarantinedScore(ctx context.Context, score models.QuarantinedScore) error {
+	out, err := json.Marshal(score)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal quarantined score: %w", err)
+	}
+	item := d.getDdbQuarantineKey(score.Game, score.Id)
+	item["game"] = &types.AttributeValueMemberS{Value: getQuarantinePartition(score.Game)}
+	item["quarantined"] = &types.AttributeValueMemberS{Value: string(out)}
+	item["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(score.Expires)}
+
+	_, err = d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to put quarantined score: %w", err)
+	}
+	return nil
+}
+
+func (d DynamoScoreDatabase) GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbQuarantineKey(game, id),
+	})
+	if err != nil {
+		return models.QuarantinedScore{}, false, fmt.Errorf("Failed to get quarantined score: %w", err)
+	}
+	if out.Item == nil {
+		return models.QuarantinedScore{}, false, nil
+	}
+	score, err := unmarshalQuarantineItem(out.Item)
+	if err != nil {
+		return models.QuarantinedScore{}, false, err
+	}
+	if score.Expires <= int(time.Now().Unix()) {
+		return models.QuarantinedScore{}, false, nil
+	}
+	return score, true, nil
+}
+
+// func GetQuarantinedScores returns the game's quarantined scores in the order they were submitted
+func (d DynamoScoreDatabase) GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error) {
+	items, err := d.getPartitionItems(ctx, getQuarantinePartition(game))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get quarantined scores: %w", err)
+	}
+	now := int(time.Now().Unix())
+	scores := make([]models.QuarantinedScore, 0, len(items))
+	for _, item := range items {
+		score, err := unmarshalQuarantineItem(item)
+		if err != nil {
+			return nil, err
+		}
+		if score.Expires > now {
+			scores = append(scores, score)
+		}
+	}
+
+	slices.SortFunc(scores, func(a models.QuarantinedScore, b models.QuarantinedScore) int {
+		return cmp.Or(cmp.Compare(a.Timestamp, b.Timestamp), strings.Compare(a.Id, b.Id))
+	})
+	return scores, nil
+}
+
+func (d DynamoScoreDatabase) DeleteQuarantinedScore(ctx context.Context, game string, id string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tableName),
+		Key:       d.getDdbQuarantineKey(game, id),
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to delete quarantined score: %w", err)
+	}
+	return nil
+}
+
+func unmarshalQuarantineItem(item map[string]types.AttributeValue) (models.QuarantinedScore, error) {
+	var qItem quarantineItem
+	err := attributevalue.UnmarshalMap(item, &qItem)
+	if err != nil {
+		return models.QuarantinedScore{}, fmt.Errorf("Failed to unmarshall a quarantined score: %w", err)
+	}
+	var score models.QuarantinedScore
+	err = json.Unmarshal([]byte(qItem.Score), &score)
+	if err != nil {
+		return models.QuarantinedScore{}, fmt.Errorf("Failed to unmarshall a quarantined score: %w", err)
+	}
+	return score, nil
+}
+
+func unmarshalGameItem(item map[string]types.AttributeValue) (models.GameConfig, error) {
+	var gItem gameItem
+	err := attributevalue.UnmarshalMap(item, &gItem)
+	if err != nil {
+		return models.GameConfig{}, fmt.Errorf("Failed to unmarshall a game: %w", err)
+	}
+	var config models.GameConfig
+	err = json.Unmarshal([]byte(gItem.Config), &config)
+	if err != nil {
+		return models.GameConfig{}, fmt.Errorf("Failed to unmarshall a game config: %w", err)
+	}
+	return config, nil
+}
+
+// func getPlayerId reads the player from the pk of a score or best score item, which begins with the player id and a bar
+func getPlayerId(item map[string]types.AttributeValue) string {
+	pk, ok := item["pk"].(*types.AttributeValueMemberS)
+	if !ok {
+		return ""
+	}
+	playerId, _, _ := strings.Cut(strings.TrimPrefix(pk.Value, "best#"), "|")
+	return playerId
+}
+
+// rankItem is a rank read from either ranking index, its score is parsed from the index's sort key
+type rankItem struct {
+	PlayerName string          `dynamodbav:"pname"`
+	Timestamp  int             `dynamodbav:"ts"`
+	ReceivedAt int             `dynamodbav:"rts"`
+	Metadata   models.Metadata `dynamodbav:"meta"`
+}
+
+// rankIndex is a secondary index ordering the scores of a game
+type rankIndex struct {
+	name         string
+	partitionKey string
+	scoreKey     string
+}
+
+// func getRankIndex returns the index ranking a leaderboard, GameBestScoresIndex only holds the best scores of all time leaderboards
+func (d DynamoScoreDatabase) getRankIndex(uniquePlayers bool) rankIndex {
+	if uniquePlayers {
+		return rankIndex{name: "GameBestScoresIndex", partitionKey: "bgame", scoreKey: "bsk"}
+	}
+	return rankIndex{name: "GameScoresIndex", partitionKey: "game", scoreKey: "sk"}
+}
+
+// func scanIndexForward is whether to read an index in ascending score order to get either the best or the worst scores first
+func scanIndexForward(bestFirst bool, order models.Order) bool {
+	return bestFirst == (order == models.LowestFirst)
+}
+
+// func buildQuery builds the expression of a query, with a filter on the metadata of each item when there is one
+func buildQuery(keyEx expression.KeyConditionBuilder, filter models.Metadata) (expression.Expression, error) {
+	builder := expression.NewBuilder().WithKeyCondition(keyEx)
+	if len(filter) > 0 {
+		keys := make([]string, 0, len(filter))
+		for key := range filter {
+			keys = append(keys, key)
+		}
+		// sorted so that the same filter always builds the same expression
+		slices.Sort(keys)
+		conditions := make([]expression.ConditionBuilder, 0, len(keys))
+		for _, key := range keys {
+			// metadata keys are word characters, so the key is a single step of the path
+			conditions = append(conditions, expression.Name("meta."+key).Equal(expression.Value(filter[key])))
+		}
+		condition := conditions[0]
+		if len(conditions) > 1 {
+			condition = expression.And(conditions[0], conditions[1], conditions[2:]...)
+		}
+		builder = builder.WithFilter(condition)
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return expression.Expression{}, fmt.Errorf("Failed to build query expression: %w", err)
+	}
+	return expr, nil
+}
+
+// func queryRanks reads ranks from index until it has limit of them, leaving positions for the caller to assign, a limit of zero reads every rank
+// dynamodb filters each page after reading it, so a filtered query may read many pages to find a few ranks
+// when seen is not nil only the first rank of each player not already in it is kept, which reading best first is the player's best
+func (d DynamoScoreDatabase) queryRanks(ctx context.Context, index rankIndex, keyEx expression.KeyConditionBuilder, filter models.Metadata, seen map[string]bool, limit int, bestFirst bool, order models.Order) (models.Ranks, error) {
+	ranks, _, _, err := d.readRanks(ctx, index, keyEx, filter, seen, limit, 0, bestFirst, order)
+	return ranks, err
+}
+
+// func readRanks is queryRanks reading at most maxRead items of the index whatever the filter keeps, a maxRead of zero reads as many as it needs
+// it also returns how many items it read and whether any were left unread, so that a caller can tell whether it saw every rank
+func (d DynamoScoreDatabase) readRanks(ctx context.Context, index rankIndex, keyEx expression.KeyConditionBuilder, filter models.Metadata, seen map[string]bool, limit int, maxRead int, bestFirst bool, order models.Order) (models.Ranks, int, bool, error) {
+	expr, err := buildQuery(keyEx, filter)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 &d.tableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ScanIndexForward:          aws.Bool(scanIndexForward(bestFirst, order)),
+		IndexName:                 aws.String(index.name),
+	}
+
+	ranks := make(models.Ranks, 0, limit)
+	read := 0
+	for {
+		pageLimit := limit
+		if maxRead > 0 && (pageLimit == 0 || pageLimit > maxRead-read) {
+			pageLimit = maxRead - read
+		}
+		if pageLimit > 0 {
+			input.Limit = aws.Int32(int32(pageLimit))
+		}
+		page, err := d.client.Query(ctx, input)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("Failed to query player ranks: %w", err)
+		}
+		read += int(page.ScannedCount)
+		for _, marshalledRank := range page.Items {
+			rank, err := unmarshalRank(index, marshalledRank)
+			if err != nil {
+				return nil, 0, false, err
+			}
+			if seen != nil {
+				if seen[rank.PlayerId] {
+					continue
+				}
+				seen[rank.PlayerId] = true
+			}
+			ranks = append(ranks, rank)
+		}
+		unread := page.LastEvaluatedKey != nil
+		if !unread || (limit > 0 && len(ranks) >= limit) || (maxRead > 0 && read >= maxRead) {
+			if limit > 0 && len(ranks) > limit {
+				ranks = ranks[:limit]
+			}
+			return ranks, read, unread, nil
+		}
+		input.ExclusiveStartKey = page.LastEvaluatedKey
+	}
+}
+
+func unmarshalRank(index rankIndex, marshalledRank map[string]types.AttributeValue) (models.Rank, error) {
+	var item rankItem
+	err := attributevalue.UnmarshalMap(marshalledRank, &item)
+	if err != nil {
+		return models.Rank{}, fmt.Errorf("Failed to unmarshall a rank: %w", err)
+	}
+	sortKey, ok := marshalledRank[index.scoreKey].(*types.AttributeValueMemberN)
+	if !ok {
+		return models.Rank{}, fmt.Errorf("Wrong type stored at %v", index.scoreKey)
+	}
+	score, err := models.ParseSortKey(sortKey.Value)
+	if err != nil {
+		return models.Rank{}, err
+	}
+	return models.Rank{
+		Score:      score,
+		PlayerName: item.PlayerName,
+		Timestamp:  item.Timestamp,
+		Metadata:   item.Metadata,
+		ReceivedAt: item.ReceivedAt,
+		PlayerId:   getPlayerId(marshalledRank),
+	}, nil
+}
+
+// func GetTopRanks reads the best scores of the leaderboard from the index of its request
+// a filtered or windowed request for unique players reads GameScoresIndex and keeps the first score of each player, as GameBestScoresIndex holds each player's all time best score whatever its metadata
+// a filtered request, or one for unique players in a window, reads at most rankReadLimit items, so it may be cut short of its limit
+func (d DynamoScoreDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRequest) (models.Ranks, error) {
+	bestIndexed := len(ranksRequest.Filter) == 0 && ranksRequest.Period.IsAllTime()
+	index := d.getRankIndex(ranksRequest.UniquePlayers && bestIndexed)
+	keyEx := expression.Key(index.partitionKey).Equal(expression.Value(ranksRequest.Period.Leaderboard(ranksRequest.Game)))
+	var seen map[string]bool
+	if ranksRequest.UniquePlayers && !bestIndexed {
+		seen = make(map[string]bool)
+	}
+
+	limit := d.rankLimit
+	if ranksRequest.Limit > 0 {
+		limit = ranksRequest.Limit
+	}
+	maxRead := 0
+	if len(ranksRequest.Filter) > 0 || seen != nil {
+		// the ranks kept may be few of the items read, so the items read are bounded rather than only the ranks
+		maxRead = max(limit, d.rankReadLimit)
+	}
+	ranks, _, _, err := d.readRanks(ctx, index, keyEx, ranksRequest.Filter, seen, limit, maxRead, true, ranksRequest.Order)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ranks {
+		ranks[i].Position = i + 1
+	}
+	return ranks, nil
+}
+
+// func rankBounds splits the leaderboard of the request in index at the requested submission, into the key conditions of the better scores and of the rest
+// earlier submissions of an equal score sort as better, so the sort key of the submission divides the index
+func rankBounds(index rankIndex, request models.RanksAroundRequest) (expression.KeyConditionBuilder, expression.KeyConditionBuilder) {
+	sortKey := expression.Value(attributevalue.Number(models.Score{Score: request.Score, Timestamp: request.Timestamp, ReceivedAt: request.ReceivedAt, Order: request.Order}.SortKey()))
+	inGame := expression.Key(index.partitionKey).Equal(expression.Value(request.Period.Leaderboard(request.Game)))
+	if request.Order == models.LowestFirst {
+		return inGame.And(expression.Key(index.scoreKey).LessThan(sortKey)), inGame.And(expression.Key(index.scoreKey).GreaterThanEqual(sortKey))
+	}
+	return inGame.And(expression.Key(index.scoreKey).GreaterThan(sortKey)), inGame.And(expression.Key(index.scoreKey).LessThanEqual(sortKey))
+}
+
+// func GetRanksAround finds the position of a score by counting every better score in the game, so that it is exact however far down the score is
+// the count reads the better scores without returning them, so its cost grows with the score's position
+func (d DynamoScoreDatabase) GetRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	if request.UniquePlayers && (len(request.Filter) > 0 || !request.Period.IsAllTime()) {
+		return d.getUniqueRanksAround(ctx, request)
+	}
+	index := d.getRankIndex(request.UniquePlayers)
+	better, notBetter := rankBounds(index, request)
+
+	var betterCount int
+	var above, atAndBelow models.Ranks
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		count, err := d.countRanks(gctx, index, better, request.Filter)
+		betterCount = count
+		return err
+	})
+	if request.Around > 0 {
+		g.Go(func() error {
+			// the closest better scores are the worst of them
+			ranks, err := d.queryRanks(gctx, index, better, request.Filter, nil, request.Around, false, request.Order)
+			if err != nil {
+				return err
+			}
+			slices.Reverse(ranks)
+			above = ranks
+			return nil
+		})
+	}
+	g.Go(func() error {
+		ranks, err := d.queryRanks(gctx, index, notBetter, request.Filter, nil, request.Around+1, true, request.Order)
+		if err != nil {
+			return err
+		}
+		atAndBelow = ranks
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return positionRanks(max(betterCount, len(above)), above, atAndBelow), nil
+}
+
+// func getUniqueRanksAround reads the scores better than the requested one rather than counting them, as a count cannot keep only the best score of each player
+// filtered and windowed ranks of unique players are read in this way, as only the unfiltered alltime leaderboard has best score items
+// the better scores are read best first so that the players they include are left out of the scores at and below
+func (d DynamoScoreDatabase) getUniqueRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	index := d.getRankIndex(false)
+	better, notBetter := rankBounds(index, request)
+	seen := make(map[string]bool)
+
+	best, err := d.queryRanks(ctx, index, better, request.Filter, seen, 0, true, request.Order)
+	if err != nil {
+		return nil, err
+	}
+	atAndBelow, err := d.queryRanks(ctx, index, notBetter, request.Filter, seen, request.Around+1, true, request.Order)
+	if err != nil {
+		return nil, err
+	}
+	return positionRanks(len(best), best[max(0, len(best)-request.Around):], atAndBelow), nil
+}
+
+// func positionRanks numbers the ranks either side of a score, above being the closest of the betterCount ranks better than it
+func positionRanks(betterCount int, above models.Ranks, atAndBelow models.Ranks) models.Ranks {
+	ranks := make(models.Ranks, 0, len(above)+len(atAndBelow))
+	for i, rank := range above {
+		rank.Position = betterCount - len(above) + i + 1
+		ranks = append(ranks, rank)
+	}
+	for i, rank := range atAndBelow {
+		rank.Position = betterCount + i + 1
+		ranks = append(ranks, rank)
+	}
+	return ranks
+}
+
+// func countRanks counts the items matching keyEx and filter in index, reading every page of them
+func (d DynamoScoreDatabase) countRanks(ctx context.Context, index rankIndex, keyEx expression.KeyConditionBuilder, filter models.Metadata) (int, error) {
+	expr, err := buildQuery(keyEx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 &d.tableName,
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		IndexName:                 aws.String(index.name),
+		Select:                    types.SelectCount,
+	}
+	count := 0
+	for {
+		page, err := d.client.Query(ctx, input)
+		if err != nil {
+			return 0, fmt.Errorf("Failed to count ranks: %w", err)
+		}
+		count += int(page.Count)
+		if page.LastEvaluatedKey == nil {
+			return count, nil
+		}
+		input.ExclusiveStartKey = page.LastEvaluatedKey
 	}
-	return ranks, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"sync/atomic"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,6 +15,7 @@ import (
 	smithyendpoints "github.com/aws/smithy-go/endpoints"
 	"github.com/google/go-cmp/cmp"
 	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/indimeco/cheerleader/internal/storagetest"
 	"github.com/testcontainers/testcontainers-go"
 	tcdynamodb "github.com/testcontainers/testcontainers-go/modules/dynamodb"
 )
@@ -22,6 +24,8 @@ var globalTestClient *dynamodb.Client
 
 const testTableName = "test_table"
 
+var testTableCount atomic.Int32
+
 type DynamoDBLocalResolver struct {
 	hostAndPort string
 }
@@ -62,8 +66,18 @@ func createTestDdbClient(ctx context.Context) (*dynamodb.Client, func(), error)
 
 	client := dynamodb.NewFromConfig(cfg, dynamodb.WithEndpointResolverV2(&DynamoDBLocalResolver{hostAndPort: hostPort}))
 
-	_, err = client.CreateTable(ctx, &dynamodb.CreateTableInput{
-		TableName: aws.String(testTableName),
+	err = createTestTable(ctx, client, testTableName)
+	if err != nil {
+		return nil, close, err
+	}
+
+	return client, close, nil
+}
+
+// func createTestTable creates a table with the same keys and indexes as the one deployed by terraform
+func createTestTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(tableName),
 		ProvisionedThroughput: &types.ProvisionedThroughput{
 			ReadCapacityUnits:  aws.Int64(1),
 			WriteCapacityUnits: aws.Int64(1),
@@ -91,6 +105,14 @@ func createTestDdbClient(ctx context.Context) (*dynamodb.Client, func(), error)
 				AttributeName: aws.String("game"),
 				AttributeType: "S",
 			},
+			{
+				AttributeName: aws.String("bgame"),
+				AttributeType: "S",
+			},
+			{
+				AttributeName: aws.String("bsk"),
+				AttributeType: "N",
+			},
 		},
 		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
 			{
@@ -107,7 +129,28 @@ func createTestDdbClient(ctx context.Context) (*dynamodb.Client, func(), error)
 				},
 				Projection: &types.Projection{
 					ProjectionType:   "INCLUDE",
-					NonKeyAttributes: []string{"pname", "ts"},
+					NonKeyAttributes: []string{"pname", "ts", "rts", "meta"},
+				},
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(1),
+					WriteCapacityUnits: aws.Int64(1),
+				},
+			},
+			{
+				IndexName: aws.String("GameBestScoresIndex"),
+				KeySchema: []types.KeySchemaElement{
+					{
+						AttributeName: aws.String("bgame"),
+						KeyType:       "HASH",
+					},
+					{
+						AttributeName: aws.String("bsk"),
+						KeyType:       "SORT",
+					},
+				},
+				Projection: &types.Projection{
+					ProjectionType:   "INCLUDE",
+					NonKeyAttributes: []string{"pname", "ts", "rts", "meta"},
 				},
 				ProvisionedThroughput: &types.ProvisionedThroughput{
 					ReadCapacityUnits:  aws.Int64(1),
@@ -117,17 +160,32 @@ func createTestDdbClient(ctx context.Context) (*dynamodb.Client, func(), error)
 		},
 	})
 	if err != nil {
-		return nil, close, fmt.Errorf("Failed to create table: %w", err)
+		return fmt.Errorf("Failed to create table: %w", err)
 	}
-
-	return client, close, nil
+	return nil
 }
 
 func createTestDynamoScoreDatabase() DynamoScoreDatabase {
 	return DynamoScoreDatabase{
-		client:    globalTestClient,
-		rankLimit: 1000,
-		tableName: testTableName,
+		client:        globalTestClient,
+		rankLimit:     1000,
+		rankReadLimit: 5000,
+		tableName:     testTableName,
+	}
+}
+
+// func createIsolatedTestDynamoScoreDatabase creates a new table, so that the test starts without any scores
+func createIsolatedTestDynamoScoreDatabase(t *testing.T) DynamoScoreDatabase {
+	tableName := fmt.Sprintf("test_table_%d", testTableCount.Add(1))
+	err := createTestTable(context.Background(), globalTestClient, tableName)
+	if err != nil {
+		t.Fatalf("Failed to create isolated table: %v", err)
+	}
+	return DynamoScoreDatabase{
+		client:        globalTestClient,
+		rankLimit:     1000,
+		rankReadLimit: 5000,
+		tableName:     tableName,
 	}
 }
 
@@ -143,6 +201,12 @@ func TestMain(m *testing.M) {
 	return
 }
 
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Database {
+		return createIsolatedTestDynamoScoreDatabase(t)
+	})
+}
+
 func TestPutScore(t *testing.T) {
 	ctx := context.Background()
 	score := models.Score{
@@ -377,3 +441,37 @@ func TestGetTopRanks(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestGetTopRanksReadsAtMostRankReadLimit(t *testing.T) {
+	d := createIsolatedTestDynamoScoreDatabase(t)
+	d.rankReadLimit = 4
+	ctx := context.Background()
+	scores := []models.Score{}
+	for i := range 10 {
+		score := models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Bananalord", Game: "Comedy", Score: 100 - i, Timestamp: 1}
+		// only the two worst scores match the filter, beyond the items a request may read
+		if i >= 8 {
+			score.Metadata = models.Metadata{"platform": "pc"}
+		}
+		scores = append(scores, score)
+	}
+	err := d.PutScores(ctx, scores)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy", Limit: 2, Filter: models.Metadata{"platform": "pc"}})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 0 {
+		t.Errorf("want the ranks cut short, got %v", ranks)
+	}
+	ranks, err = d.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy", Limit: 10, Filter: models.Metadata{"platform": "pc"}})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 2 {
+		t.Errorf("want a limit above the read limit to read as far as the limit, got %v", ranks)
+	}
+}
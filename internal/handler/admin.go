@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func IsAdmin reports whether the headers carry the admin token, no request carries it when the token is not configured
+func (h Handler) IsAdmin(headers map[string]string) bool {
+	if h.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(header(headers, "Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) == 1
+}
+
+// func header finds a header by name regardless of case, as api gateway passes header names as the client sent them
+func header(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+func (h Handler) GetGames(ctx context.Context) events.APIGatewayProxyResponse {
+	configs, err := h.Database.GetGames(ctx)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get games: %w", err))
+	}
+	out, err := json.Marshal(&configs)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal games: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+func (h Handler) GetGame(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	config, ok, err := h.Database.GetGame(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board))
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	out, err := json.Marshal(&config)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal game: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func PutGame registers a game or replaces its config, settings missing from the body take their defaults rather than their previous values
+// the defaults of a board are its game's settings, so that a board registered to change one setting keeps the others of its game
+func (h Handler) PutGame(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
+	defaults := h.defaultGameConfig(apiDefinition.Game)
+	if apiDefinition.Board != "" {
+		gameConfig, ok, err := h.gameConfig(ctx, apiDefinition.Game)
+		if err != nil {
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+		}
+		if !ok {
+			return h.ResponseNotFound()
+		}
+		defaults = gameConfig
+		defaults.Game = models.BoardPartition(apiDefinition.Game, apiDefinition.Board)
+	}
+	config, err := models.ParseGameConfig(defaults, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	if config.RequireSessions && h.SessionSecret == "" {
+		return h.ResponseBadRequest(errors.New("requireSessions needs SESSION_SECRET to be configured"))
+	}
+	err = h.Database.PutGame(ctx, config)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put game: %w", err))
+	}
+	out, err := json.Marshal(&config)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal game: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func DeleteGame removes a game from the registry, its scores are kept and it is played with the default config unless unknown games are rejected
+// a board removed from the registry is played with its game's config again
+func (h Handler) DeleteGame(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	err := h.Database.DeleteGame(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board))
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete game: %w", err))
+	}
+	return h.ResponseNoContent()
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/memory"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func createTestAdminHandler() Handler {
+	return Handler{
+		Logger:     slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database:   memory.New(),
+		AdminToken: "secret",
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	type test struct {
+		token   string
+		headers map[string]string
+		want    bool
+	}
+	testCases := []test{
+		{token: "secret", headers: map[string]string{"Authorization": "Bearer secret"}, want: true},
+		{token: "secret", headers: map[string]string{"authorization": "Bearer secret"}, want: true},
+		{token: "secret", headers: map[string]string{"Authorization": "Bearer wrong"}, want: false},
+		{token: "secret", headers: map[string]string{"Authorization": "secret"}, want: false},
+		{token: "secret", headers: map[string]string{}, want: false},
+		{token: "", headers: map[string]string{"Authorization": "Bearer "}, want: false},
+	}
+
+	for _, tc := range testCases {
+		handler := Handler{AdminToken: tc.token}
+		got := handler.IsAdmin(tc.headers)
+		if got != tc.want {
+			t.Errorf("want %v, got %v, headers %v", tc.want, got, tc.headers)
+		}
+	}
+}
+
+func TestPutGameThenGetGame(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Golf"}, `{"order": "lowestFirst", "maxScore": 200}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	response = handler.GetGame(ctx, api.ApiDefinition{Game: "Golf"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var config models.GameConfig
+	if err := json.Unmarshal([]byte(response.Body), &config); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	maxScore := 200
+	want := models.NewGameConfig("Golf")
+	want.Order = models.LowestFirst
+	want.MaxScore = &maxScore
+	if diff := cmp.Diff(want, config); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", PlayerId: "1"}, nil, `{"score": 201, "playerName": "goose"}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+
+	response = handler.DeleteGame(ctx, api.ApiDefinition{Game: "Golf"})
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	response = handler.GetGame(ctx, api.ApiDefinition{Game: "Golf"})
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+}
+
+func TestPutGameBadRequest(t *testing.T) {
+	handler := createTestAdminHandler()
+
+	response := handler.PutGame(context.Background(), api.ApiDefinition{Game: "Golf"}, `{"retentionDays": 0}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestRejectUnknownGames(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.RejectUnknownGames = true
+	ctx := context.Background()
+
+	response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 10, "playerName": "goose"}`)
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+	response = handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10"})
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+
+	response = handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 10, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("want %v, got %v", 201, response.StatusCode)
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// adminTokenKey is who a request made with the admin token acts as
+var adminTokenKey = models.ApiKey{Id: "admin_token", Game: models.AllGames, Role: models.RoleAdmin, Name: "ADMIN_TOKEN"}
+
+// func Authenticate finds the api key sent as the bearer token, the bool is false when there is no token or it matches no key
+func (h Handler) Authenticate(ctx context.Context, headers map[string]string) (models.ApiKey, bool, error) {
+	if h.IsAdmin(headers) {
+		return adminTokenKey, true, nil
+	}
+	token, ok := strings.CutPrefix(header(headers, "Authorization"), "Bearer ")
+	if !ok {
+		return models.ApiKey{}, false, nil
+	}
+	id, ok := models.ApiKeyId(token)
+	if !ok {
+		return models.ApiKey{}, false, nil
+	}
+	key, ok, err := h.Database.GetApiKey(ctx, id)
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to get api key: %w", err)
+	}
+	if !ok || !key.Matches(token) {
+		return models.ApiKey{}, false, nil
+	}
+	return key, true, nil
+}
+
+// func Authorize checks a request against the role its route needs before the request is routed, returning the api key it was made with
+// when the bool is false the request must be refused with the response
+func (h Handler) Authorize(ctx context.Context, apiDefinition api.ApiDefinition, method string, headers map[string]string) (models.ApiKey, events.APIGatewayProxyResponse, bool) {
+	routes := api.NewApiRoutes()
+	role, game := models.RoleReader, apiDefinition.Game
+	switch apiDefinition.Route {
+	case routes.ScoresByPlayer:
+		if method == "PUT" {
+			role = models.RoleSubmitter
+		}
+	case routes.SessionsByPlayer, routes.ProfileByPlayer, routes.ScoresBatch:
+		role = models.RoleSubmitter
+	case routes.Groups, routes.Group:
+		if method != "GET" {
+			role = models.RoleSubmitter
+		}
+	case routes.AdminGames:
+		role, game = models.RoleAdmin, models.AllGames
+	case routes.AdminGame, routes.AdminQuarantine, routes.AdminQuarantinedScore, routes.AdminApproveScore,
+		routes.AdminPlayer, routes.AdminPlayerScore, routes.AdminBans, routes.AdminBan, routes.AdminKeys, routes.AdminKey:
+		role = models.RoleAdmin
+	}
+	if role != models.RoleAdmin && !h.RequireApiKeys {
+		return models.ApiKey{}, events.APIGatewayProxyResponse{}, true
+	}
+
+	key, ok, err := h.Authenticate(ctx, headers)
+	if err != nil {
+		return models.ApiKey{}, h.ResponseInternalServerError(fmt.Errorf("Failed to authenticate: %w", err)), false
+	}
+	if !ok {
+		return models.ApiKey{}, h.ResponseUnauthorized(), false
+	}
+	allowed := key.Allows(game, role)
+	// the game of an api key is in the body or the stored key rather than the path, so the key endpoints check it themselves
+	if apiDefinition.Route == routes.AdminKeys || apiDefinition.Route == routes.AdminKey {
+		allowed = key.Role.Includes(role)
+	}
+	if !allowed {
+		return models.ApiKey{}, h.ResponseForbidden(), false
+	}
+	return key, events.APIGatewayProxyResponse{}, true
+}
+
+// func GetApiKeys lists the keys of every game the caller administers
+func (h Handler) GetApiKeys(ctx context.Context, caller models.ApiKey) events.APIGatewayProxyResponse {
+	keys, err := h.Database.GetApiKeys(ctx)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get api keys: %w", err))
+	}
+	visible := make([]models.ApiKey, 0, len(keys))
+	for _, key := range keys {
+		if caller.Allows(key.Game, models.RoleAdmin) {
+			visible = append(visible, key)
+		}
+	}
+	out, err := json.Marshal(&visible)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal api keys: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func CreateApiKey responds with the new key's token, which cannot be read again as only its hash is stored
+func (h Handler) CreateApiKey(ctx context.Context, caller models.ApiKey, body string) events.APIGatewayProxyResponse {
+	key, token, err := models.NewApiKey(body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	if !caller.Allows(key.Game, models.RoleAdmin) {
+		return h.ResponseForbidden()
+	}
+	err = h.Database.PutApiKey(ctx, key)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put api key: %w", err))
+	}
+
+	type createdApiKey struct {
+		models.ApiKey
+		Key string `json:"key"`
+	}
+	out, err := json.Marshal(createdApiKey{ApiKey: key, Key: token})
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal api key: %w", err))
+	}
+	return h.ResponseCreatedWith(string(out))
+}
+
+// func RevokeApiKey deletes a key, a key of a game the caller does not administer is reported as not found
+func (h Handler) RevokeApiKey(ctx context.Context, caller models.ApiKey, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	key, ok, err := h.Database.GetApiKey(ctx, apiDefinition.KeyId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get api key: %w", err))
+	}
+	if !ok || !caller.Allows(key.Game, models.RoleAdmin) {
+		return h.ResponseNotFound()
+	}
+	err = h.Database.DeleteApiKey(ctx, key.Id)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete api key: %w", err))
+	}
+	return h.ResponseNoContent()
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func createTestApiKey creates a key through the admin token and returns the headers that authenticate with it
+func createTestApiKey(t *testing.T, handler Handler, body string) (models.ApiKey, map[string]string) {
+	t.Helper()
+	response := handler.CreateApiKey(context.Background(), adminTokenKey, body)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+	var created struct {
+		models.ApiKey
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &created); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return created.ApiKey, map[string]string{"Authorization": "Bearer " + created.Key}
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.RequireApiKeys = true
+	routes := api.NewApiRoutes()
+	_, reader := createTestApiKey(t, handler, `{"game": "Tetris", "role": "reader"}`)
+	_, submitter := createTestApiKey(t, handler, `{"game": "Tetris", "role": "submitter"}`)
+	_, gameAdmin := createTestApiKey(t, handler, `{"game": "Tetris", "role": "admin"}`)
+	_, admin := createTestApiKey(t, handler, `{"game": "*", "role": "admin"}`)
+	adminToken := map[string]string{"Authorization": "Bearer secret"}
+
+	type test struct {
+		name          string
+		apiDefinition api.ApiDefinition
+		method        string
+		headers       map[string]string
+		want          int
+	}
+	testCases := []test{
+		{name: "no key", apiDefinition: api.ApiDefinition{Route: routes.Ranks, Game: "Tetris"}, method: "GET", headers: map[string]string{}, want: 401},
+		{name: "unknown key", apiDefinition: api.ApiDefinition{Route: routes.Ranks, Game: "Tetris"}, method: "GET", headers: map[string]string{"Authorization": "Bearer abc.def"}, want: 401},
+		{name: "reader reads", apiDefinition: api.ApiDefinition{Route: routes.Ranks, Game: "Tetris"}, method: "GET", headers: reader, want: 200},
+		{name: "reader reads another game", apiDefinition: api.ApiDefinition{Route: routes.Ranks, Game: "Golf"}, method: "GET", headers: reader, want: 403},
+		{name: "reader submits", apiDefinition: api.ApiDefinition{Route: routes.ScoresByPlayer, Game: "Tetris", PlayerId: "1"}, method: "PUT", headers: reader, want: 403},
+		{name: "submitter submits", apiDefinition: api.ApiDefinition{Route: routes.ScoresByPlayer, Game: "Tetris", PlayerId: "1"}, method: "PUT", headers: submitter, want: 200},
+		{name: "reader reads group", apiDefinition: api.ApiDefinition{Route: routes.Group, Game: "Tetris", GroupId: "9c1e"}, method: "GET", headers: reader, want: 200},
+		{name: "reader changes group", apiDefinition: api.ApiDefinition{Route: routes.Group, Game: "Tetris", GroupId: "9c1e"}, method: "PUT", headers: reader, want: 403},
+		{name: "submitter creates group", apiDefinition: api.ApiDefinition{Route: routes.Groups, Game: "Tetris"}, method: "POST", headers: submitter, want: 200},
+		{name: "submitter configures game", apiDefinition: api.ApiDefinition{Route: routes.AdminGame, Game: "Tetris"}, method: "PUT", headers: submitter, want: 403},
+		{name: "game admin configures game", apiDefinition: api.ApiDefinition{Route: routes.AdminGame, Game: "Tetris"}, method: "PUT", headers: gameAdmin, want: 200},
+		{name: "game admin lists games", apiDefinition: api.ApiDefinition{Route: routes.AdminGames}, method: "GET", headers: gameAdmin, want: 403},
+		{name: "game admin lists keys", apiDefinition: api.ApiDefinition{Route: routes.AdminKeys}, method: "GET", headers: gameAdmin, want: 200},
+		{name: "admin lists games", apiDefinition: api.ApiDefinition{Route: routes.AdminGames}, method: "GET", headers: admin, want: 200},
+		{name: "admin token lists games", apiDefinition: api.ApiDefinition{Route: routes.AdminGames}, method: "GET", headers: adminToken, want: 200},
+	}
+
+	for _, tc := range testCases {
+		_, response, ok := handler.Authorize(context.Background(), tc.apiDefinition, tc.method, tc.headers)
+		got := response.StatusCode
+		if ok {
+			got = 200
+		}
+		if got != tc.want {
+			t.Errorf("%v: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestAuthorizeOptionalApiKeys(t *testing.T) {
+	handler := createTestAdminHandler()
+	routes := api.NewApiRoutes()
+
+	_, _, ok := handler.Authorize(context.Background(), api.ApiDefinition{Route: routes.ScoresByPlayer, Game: "Tetris", PlayerId: "1"}, "PUT", map[string]string{})
+	if !ok {
+		t.Errorf("want scores open without api keys")
+	}
+	_, response, ok := handler.Authorize(context.Background(), api.ApiDefinition{Route: routes.AdminKeys}, "GET", map[string]string{})
+	if ok || response.StatusCode != 401 {
+		t.Errorf("want %v, got %v", 401, response.StatusCode)
+	}
+}
+
+func TestApiKeyScopes(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	tetrisAdmin, _ := createTestApiKey(t, handler, `{"game": "Tetris", "role": "admin"}`)
+	tetrisReader, _ := createTestApiKey(t, handler, `{"game": "Tetris", "role": "reader"}`)
+	golfReader, _ := createTestApiKey(t, handler, `{"game": "Golf", "role": "reader"}`)
+
+	response := handler.CreateApiKey(ctx, tetrisAdmin, `{"game": "Golf", "role": "reader"}`)
+	if response.StatusCode != 403 {
+		t.Errorf("want %v, got %v", 403, response.StatusCode)
+	}
+	response = handler.CreateApiKey(ctx, tetrisAdmin, `{"game": "*", "role": "reader"}`)
+	if response.StatusCode != 403 {
+		t.Errorf("want %v, got %v", 403, response.StatusCode)
+	}
+
+	response = handler.GetApiKeys(ctx, tetrisAdmin)
+	var keys []models.ApiKey
+	if err := json.Unmarshal([]byte(response.Body), &keys); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("want %v, got %v", 2, len(keys))
+	}
+
+	response = handler.RevokeApiKey(ctx, tetrisAdmin, api.ApiDefinition{KeyId: golfReader.Id})
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+	response = handler.RevokeApiKey(ctx, tetrisAdmin, api.ApiDefinition{KeyId: tetrisReader.Id})
+	if response.StatusCode != 204 {
+		t.Errorf("want %v, got %v", 204, response.StatusCode)
+	}
+	_, ok, _ := handler.Database.GetApiKey(ctx, tetrisReader.Id)
+	if ok {
+		t.Errorf("want the api key to be revoked")
+	}
+}
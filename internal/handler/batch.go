@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func PostScoreBatch submits several scores of the game at once, for one player or many, such as the runs an offline game queued
+// a batch sent with an Idempotency-Key is submitted once however many times it is retried
+func (h Handler) PostScoreBatch(ctx context.Context, caller models.ApiKey, apiDefinition api.ApiDefinition, headers map[string]string, body string) events.APIGatewayProxyResponse {
+	key := header(headers, "Idempotency-Key")
+	if key == "" {
+		return h.postScoreBatch(ctx, caller, apiDefinition, headers, body)
+	}
+	return h.idempotent(ctx, apiDefinition, key, body, func() events.APIGatewayProxyResponse {
+		return h.postScoreBatch(ctx, caller, apiDefinition, headers, body)
+	})
+}
+
+// func postScoreBatch checks each score as if it were submitted alone and stores those that pass together
+// the batch is responded to with the result of every score, so that a client can resubmit only those which failed
+func (h Handler) postScoreBatch(ctx context.Context, caller models.ApiKey, apiDefinition api.ApiDefinition, headers map[string]string, body string) events.APIGatewayProxyResponse {
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	// the whole batch is signed at once, a session proves each score of it
+	if config.SigningSecret != "" {
+		ok, err := h.verifyScoreSignature(ctx, config, "POST", BatchPath(apiDefinition), headers, body)
+		if err != nil {
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to verify score signature: %w", err))
+		}
+		if !ok {
+			return h.ResponseUnauthorized()
+		}
+	}
+	batch, err := models.NewBatch(config, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+
+	results := make([]models.BatchResult, len(batch))
+	scores := make([]models.Score, 0, len(batch))
+	stored := make([]int, 0, len(batch))
+	for i, item := range batch {
+		score, response, ok := h.checkBatchScore(ctx, caller, config, item)
+		results[i] = batchResult(i, item.PlayerId, response)
+		if ok {
+			scores = append(scores, score)
+			stored = append(stored, i)
+		}
+	}
+	if len(scores) > 0 {
+		err := h.Database.PutScores(ctx, scores)
+		if err != nil {
+			// the whole batch fails rather than its stored scores, so that an idempotent batch is not completed and can be retried
+			sessionTokens := make([]string, 0, len(stored))
+			for _, i := range stored {
+				sessionTokens = append(sessionTokens, batch[i].SessionToken)
+			}
+			h.releaseNonces(ctx, config, headers, sessionTokens)
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to put batch scores: %w", err))
+		}
+		for _, i := range stored {
+			results[i] = batchResult(i, batch[i].PlayerId, h.ResponseCreated())
+		}
+	}
+
+	out, err := json.Marshal(&results)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal batch results: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func checkBatchScore checks a score of a batch as PutScore would, with the player's rate limit taken here as the player is not in the path
+func (h Handler) checkBatchScore(ctx context.Context, caller models.ApiKey, config models.GameConfig, item models.BatchScore) (models.Score, events.APIGatewayProxyResponse, bool) {
+	if err := item.Validate(); err != nil {
+		return models.Score{}, h.ResponseBadRequest(err), false
+	}
+	// the config may be a board's, whose players are limited and banned by its game
+	game := models.PartitionGame(config.Game)
+	if response, ok := h.takePlayerToken(ctx, caller, game, item.PlayerId); !ok {
+		return models.Score{}, response, false
+	}
+	if response, ok := h.checkBan(ctx, game, item.PlayerId); !ok {
+		return models.Score{}, response, false
+	}
+	return h.checkScore(ctx, config, item.PlayerId, map[string]string{SessionTokenHeader: item.SessionToken}, item.Body)
+}
+
+// func batchResult reports the response a score of a batch would have had alone, with the body of a refusal as its error
+func batchResult(index int, playerId string, response events.APIGatewayProxyResponse) models.BatchResult {
+	result := models.BatchResult{Index: index, PlayerId: playerId, Status: response.StatusCode}
+	if response.StatusCode >= http.StatusBadRequest {
+		result.Error = response.Body
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func postTestBatch(t *testing.T, handler Handler, headers map[string]string, body string) []models.BatchResult {
+	t.Helper()
+	response := handler.PostScoreBatch(context.Background(), models.ApiKey{}, api.ApiDefinition{Game: "Tetris"}, headers, body)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var results []models.BatchResult
+	if err := json.Unmarshal([]byte(response.Body), &results); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return results
+}
+
+func TestPostScoreBatch(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.BanPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "3"}, "")
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	results := postTestBatch(t, handler, nil, `[
+		{"playerId": "1", "score": 500, "playerName": "goose"},
+		{"playerId": "2", "score": 300, "playerName": "duck"},
+		{"playerId": "2", "score": "many", "playerName": "duck"},
+		{"playerId": "a/b", "score": 100, "playerName": "swan"},
+		{"playerId": "3", "score": 900, "playerName": "crow"}
+	]`)
+	want := []models.BatchResult{
+		{Index: 0, PlayerId: "1", Status: 201},
+		{Index: 1, PlayerId: "2", Status: 201},
+		{Index: 2, PlayerId: "2", Status: 400},
+		{Index: 3, PlayerId: "a/b", Status: 400},
+		{Index: 4, PlayerId: "3", Status: 403},
+	}
+	if diff := cmp.Diff(want, results, cmpopts.IgnoreFields(models.BatchResult{}, "Error")); diff != "" {
+		t.Errorf("PostScoreBatch() mismatch (-want +got):\n%s", diff)
+	}
+	for _, result := range results {
+		if (result.Status >= 400) != (result.Error != "") {
+			t.Errorf("want an error only for refused scores, got %+v", result)
+		}
+	}
+
+	ranks := getTestRanks(t, handler, "Tetris")
+	if len(ranks) != 2 || ranks[0].PlayerName != "goose" || ranks[1].PlayerName != "duck" {
+		t.Errorf("want only the accepted scores ranked, got %v", ranks)
+	}
+}
+
+func TestPostScoreBatchInvalid(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxBatchScores": 2}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	testCases := []string{
+		`[]`,
+		`{"playerId": "1", "score": 500}`,
+		`[{"playerId": "1", "score": 1}, {"playerId": "1", "score": 2}, {"playerId": "1", "score": 3}]`,
+	}
+	for _, body := range testCases {
+		response := handler.PostScoreBatch(ctx, models.ApiKey{}, api.ApiDefinition{Game: "Tetris"}, nil, body)
+		if response.StatusCode != 400 {
+			t.Errorf("want %v, got %v, body %v", 400, response.StatusCode, body)
+		}
+	}
+}
+
+func TestPostScoreBatchRateLimitsEachPlayer(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.PlayerRateLimit = models.RateLimit{Burst: 1, Rate: 0.1}
+
+	results := postTestBatch(t, handler, nil, `[
+		{"playerId": "1", "score": 500, "playerName": "goose"},
+		{"playerId": "1", "score": 600, "playerName": "goose"},
+		{"playerId": "2", "score": 300, "playerName": "duck"}
+	]`)
+	for i, want := range []int{201, 429, 201} {
+		if results[i].Status != want {
+			t.Errorf("score %v: want %v, got %v", i, want, results[i].Status)
+		}
+	}
+}
+
+func TestPostScoreBatchSigned(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"signingSecret": "`+testSigningSecret+`"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	apiDefinition := api.ApiDefinition{Game: "Tetris"}
+	body := `[{"playerId": "1", "score": 10, "playerName": "goose"}]`
+	now := time.Now().Unix()
+	signed := map[string]string{
+		"x-signature":           models.Sign(testSigningSecret, "POST", BatchPath(apiDefinition), now, "n1", body),
+		"x-signature-timestamp": strconv.FormatInt(now, 10),
+		"x-signature-nonce":     "n1",
+	}
+
+	response = handler.PostScoreBatch(ctx, models.ApiKey{}, apiDefinition, map[string]string{}, body)
+	if response.StatusCode != 401 {
+		t.Errorf("unsigned: want %v, got %v", 401, response.StatusCode)
+	}
+	results := postTestBatch(t, handler, signed, body)
+	if len(results) != 1 || results[0].Status != 201 {
+		t.Errorf("signed: want %v, got %v", 201, results)
+	}
+}
+
+func TestPostScoreBatchReplaysIdempotentResponse(t *testing.T) {
+	handler := createTestAdminHandler()
+	headers := map[string]string{"Idempotency-Key": "batch-1"}
+	body := `[{"playerId": "1", "score": 500, "playerName": "goose"}]`
+
+	first := postTestBatch(t, handler, headers, body)
+	second := postTestBatch(t, handler, headers, body)
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("PostScoreBatch() mismatch (-want +got):\n%s", diff)
+	}
+	if ranks := getTestRanks(t, handler, "Tetris"); len(ranks) != 1 {
+		t.Errorf("want the batch stored once, got %v", ranks)
+	}
+}
+
+// failingPutScoresDatabase fails to store the first batches put to it
+type failingPutScoresDatabase struct {
+	HandlerDatabase
+	failures *int
+}
+
+func (d failingPutScoresDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	if *d.failures > 0 {
+		*d.failures--
+		return errors.New("table unavailable")
+	}
+	return d.HandlerDatabase.PutScores(ctx, scores)
+}
+
+func TestPostScoreBatchRetriedAfterFailedWrite(t *testing.T) {
+	handler := createTestAdminHandler()
+	failures := 1
+	handler.Database = failingPutScoresDatabase{HandlerDatabase: handler.Database, failures: &failures}
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"signingSecret": "`+testSigningSecret+`", "requireSessions": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	apiDefinition := api.ApiDefinition{Game: "Tetris"}
+	session := postTestSession(t, handler, api.ApiDefinition{Game: "Tetris", PlayerId: "1"})
+	body := `[{"playerId": "1", "score": 10, "playerName": "goose", "sessionToken": "` + session["x-session-token"] + `"}]`
+	now := time.Now().Unix()
+	headers := map[string]string{
+		"Idempotency-Key":       "batch-1",
+		"x-signature":           models.Sign(testSigningSecret, "POST", BatchPath(apiDefinition), now, "n1", body),
+		"x-signature-timestamp": strconv.FormatInt(now, 10),
+		"x-signature-nonce":     "n1",
+	}
+
+	response = handler.PostScoreBatch(ctx, models.ApiKey{}, apiDefinition, headers, body)
+	if response.StatusCode != 500 {
+		t.Fatalf("failed write: want %v, got %v", 500, response.StatusCode)
+	}
+	results := postTestBatch(t, handler, headers, body)
+	if len(results) != 1 || results[0].Status != 201 {
+		t.Errorf("retry: want %v, got %v", 201, results)
+	}
+	if ranks := getTestRanks(t, handler, "Tetris"); len(ranks) != 1 {
+		t.Errorf("want the retried batch stored, got %v", ranks)
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func boardConfig returns the config of the board the request is within, or of the game when it is within none
+// a board missing from the registry is played with its game's config, so the bool is false only when the game cannot be played
+func (h Handler) boardConfig(ctx context.Context, apiDefinition api.ApiDefinition) (models.GameConfig, bool, error) {
+	if apiDefinition.Board == "" {
+		return h.gameConfig(ctx, apiDefinition.Game)
+	}
+	config, ok, err := h.gameConfig(ctx, apiDefinition.Game)
+	if err != nil || !ok {
+		return models.GameConfig{}, false, err
+	}
+	partition := models.BoardPartition(apiDefinition.Game, apiDefinition.Board)
+	boardConfig, ok, err := h.Database.GetGame(ctx, partition)
+	if err != nil {
+		return models.GameConfig{}, false, err
+	}
+	if ok {
+		return boardConfig, true, nil
+	}
+	config.Game = partition
+	return config, true, nil
+}
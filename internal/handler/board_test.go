@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func getTestBoardRanks(t *testing.T, handler Handler, board string) models.Ranks {
+	t.Helper()
+	response := handler.GetTopRanks(context.Background(), api.ApiDefinition{Game: "Tetris", Board: board}, map[string]string{"limit": "10"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return ranks
+}
+
+func TestBoardScoresAreSeparate(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	puts := []struct {
+		board string
+		score int
+	}{
+		{board: "", score: 10},
+		{board: "level1", score: 20},
+		{board: "level1", score: 30},
+		{board: "level2", score: 40},
+	}
+	for _, put := range puts {
+		body := fmt.Sprintf(`{"score": %v, "playerName": "goose"}`, put.score)
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: put.board, PlayerId: "1"}, nil, body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	for board, want := range map[string][]int{"": {10}, "level1": {30, 20}, "level2": {40}, "level3": {}} {
+		ranks := getTestBoardRanks(t, handler, board)
+		got := make([]int, 0, len(ranks))
+		for _, rank := range ranks {
+			got = append(got, rank.Score)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("board %q: want %v, got %v", board, want, got)
+		}
+	}
+
+	response := handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Tetris", Board: "level1", PlayerId: "1"}, map[string]string{"ranks_around": "1"})
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(ranks) != 2 || ranks[0].Score != 30 {
+		t.Errorf("want the player's best on the board first, got %v", ranks)
+	}
+}
+
+func TestBoardConfig(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Golf"}, `{"order": "lowestFirst", "maxScore": 100}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	board := api.ApiDefinition{Game: "Golf", Board: "long"}
+	response = handler.PutGame(ctx, board, `{"maxScore": 200}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var config models.GameConfig
+	if err := json.Unmarshal([]byte(response.Body), &config); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	// settings left out of a board's config are its game's rather than the defaults
+	if config.Game != "Golf/long" || config.Order != models.LowestFirst {
+		t.Errorf("want the game's order on board Golf/long, got %v on %v", config.Order, config.Game)
+	}
+
+	body := `{"score": 150, "playerName": "goose"}`
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", PlayerId: "1"}, nil, body)
+	if response.StatusCode != 400 {
+		t.Errorf("game: want %v, got %v", 400, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", Board: "long", PlayerId: "1"}, nil, body)
+	if response.StatusCode != 201 {
+		t.Errorf("board: want %v, got %v", 201, response.StatusCode)
+	}
+
+	response = handler.DeleteGame(ctx, board)
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	response = handler.GetGame(ctx, board)
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", Board: "long", PlayerId: "1"}, nil, body)
+	if response.StatusCode != 400 {
+		t.Errorf("board without a config: want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestBoardOfUnknownGame(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.RejectUnknownGames = true
+	ctx := context.Background()
+	board := api.ApiDefinition{Game: "Golf", Board: "long"}
+
+	response := handler.PutGame(ctx, board, `{}`)
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+	response = handler.GetTopRanks(ctx, board, map[string]string{"limit": "10"})
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+}
+
+func TestBoardSessionIsUsedOnce(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"requireSessions": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	body := `{"score": 10, "playerName": "goose"}`
+
+	session := postTestSession(t, handler, api.ApiDefinition{Game: "Tetris", PlayerId: "1"})
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: "level1", PlayerId: "1"}, session, body)
+	if response.StatusCode != 201 {
+		t.Errorf("a game's session on its board: want %v, got %v", 201, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: "level2", PlayerId: "1"}, session, body)
+	if response.StatusCode != 401 {
+		t.Errorf("reused session on another board: want %v, got %v", 401, response.StatusCode)
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentPlayerReads limits the best scores of a group read at once, so that ranking a large group does not exhaust the table's capacity
+const maxConcurrentPlayerReads = 25
+
+// func CreateGroup stores a new group of the game's players, responding with the group and the id it was given
+func (h Handler) CreateGroup(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
+	_, ok, err := h.gameConfig(ctx, apiDefinition.Game)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	group, err := models.NewGroup(apiDefinition.Game, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	err = h.Database.PutGroup(ctx, group)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put group: %w", err))
+	}
+	out, err := json.Marshal(&group)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal group: %w", err))
+	}
+	return h.ResponseCreatedWith(string(out))
+}
+
+func (h Handler) GetGroup(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	group, ok, err := h.Database.GetGroup(ctx, apiDefinition.Game, apiDefinition.GroupId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get group: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	out, err := json.Marshal(&group)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal group: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func PutGroup replaces the name and players of an existing group, groups are only created with CreateGroup so that their ids cannot be chosen
+func (h Handler) PutGroup(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
+	_, ok, err := h.Database.GetGroup(ctx, apiDefinition.Game, apiDefinition.GroupId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get group: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	group, err := models.ParseGroup(apiDefinition.Game, apiDefinition.GroupId, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	err = h.Database.PutGroup(ctx, group)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put group: %w", err))
+	}
+	out, err := json.Marshal(&group)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal group: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+func (h Handler) DeleteGroup(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	err := h.Database.DeleteGroup(ctx, apiDefinition.Game, apiDefinition.GroupId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete group: %w", err))
+	}
+	return h.ResponseNoContent()
+}
+
+// func GetGroupRanks ranks the best score of each of the players listed in the request, or of those in a stored group, among themselves
+// groups belong to the game, so a group can be ranked on any of its boards
+func (h Handler) GetGroupRanks(ctx context.Context, apiDefinition api.ApiDefinition, params map[string]string) events.APIGatewayProxyResponse {
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	groupRanksRequest, err := models.NewGroupRanksRequest(params, config)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	if groupRanksRequest.GroupId != "" {
+		group, ok, err := h.Database.GetGroup(ctx, apiDefinition.Game, groupRanksRequest.GroupId)
+		if err != nil {
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to get group: %w", err))
+		}
+		if !ok {
+			return h.ResponseNotFound()
+		}
+		groupRanksRequest.PlayerIds = group.PlayerIds
+	}
+
+	bestScores, err := h.bestScores(ctx, groupRanksRequest)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get top player scores: %w", err))
+	}
+	ranks := models.NewGroupRanks(bestScores, groupRanksRequest.Order)
+	out, err := json.Marshal(&ranks)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal group ranks: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func bestScores reads the best score of each player of the request in parallel, players who have not scored are left out
+func (h Handler) bestScores(ctx context.Context, groupRanksRequest models.GroupRanksRequest) ([]models.Score, error) {
+	playerScores := make([][]models.Score, len(groupRanksRequest.PlayerIds))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentPlayerReads)
+	for i, playerId := range groupRanksRequest.PlayerIds {
+		g.Go(func() error {
+			scores, err := h.Database.GetTopPlayerScores(gctx, models.PlayerScoreRequest{
+				PlayerId: playerId,
+				ScoreRequest: models.ScoreRequest{
+					Game:   groupRanksRequest.Game,
+					Limit:  1,
+					Period: groupRanksRequest.Period,
+					Order:  groupRanksRequest.Order,
+					Filter: groupRanksRequest.Filter,
+				},
+			})
+			playerScores[i] = scores
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return slices.Concat(playerScores...), nil
+}
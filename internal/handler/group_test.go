@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func createTestGroup creates a group of the players in Tetris and returns it
+func createTestGroup(t *testing.T, handler Handler, body string) models.Group {
+	t.Helper()
+	response := handler.CreateGroup(context.Background(), api.ApiDefinition{Game: "Tetris"}, body)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+	var group models.Group
+	if err := json.Unmarshal([]byte(response.Body), &group); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return group
+}
+
+func getTestGroupRanks(t *testing.T, handler Handler, apiDefinition api.ApiDefinition, params map[string]string) string {
+	t.Helper()
+	response := handler.GetGroupRanks(context.Background(), apiDefinition, params)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	got := []string{}
+	for _, rank := range ranks {
+		got = append(got, fmt.Sprintf("%v %v %v", rank.Position, rank.PlayerName, rank.Score))
+	}
+	return strings.Join(got, ", ")
+}
+
+func TestGroupCrud(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	group := createTestGroup(t, handler, `{"name": "Pond", "playerIds": ["1", "2"]}`)
+	apiDefinition := api.ApiDefinition{Game: "Tetris", GroupId: group.Id}
+
+	response := handler.PutGroup(ctx, apiDefinition, `{"name": "Lake", "playerIds": ["2", "3"]}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.GetGroup(ctx, apiDefinition)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var got models.Group
+	if err := json.Unmarshal([]byte(response.Body), &got); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if got.Id != group.Id || got.Name != "Lake" || fmt.Sprint(got.PlayerIds) != "[2 3]" {
+		t.Errorf("unexpected group %+v", got)
+	}
+
+	response = handler.DeleteGroup(ctx, apiDefinition)
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	for _, response := range []int{
+		handler.GetGroup(ctx, apiDefinition).StatusCode,
+		handler.PutGroup(ctx, apiDefinition, `{"playerIds": ["1"]}`).StatusCode,
+	} {
+		if response != 404 {
+			t.Errorf("want %v, got %v", 404, response)
+		}
+	}
+}
+
+func TestCreateGroupInvalid(t *testing.T) {
+	handler := createTestAdminHandler()
+	response := handler.CreateGroup(context.Background(), api.ApiDefinition{Game: "Tetris"}, `{"playerIds": []}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+
+	handler.RejectUnknownGames = true
+	response = handler.CreateGroup(context.Background(), api.ApiDefinition{Game: "Pong"}, `{"playerIds": ["1"]}`)
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+}
+
+func TestGetGroupRanks(t *testing.T) {
+	handler := createTestAdminHandler()
+	putTestScores(t, handler, "1", `{"score": 500, "playerName": "goose"}`, `{"score": 100, "playerName": "goose"}`)
+	putTestScores(t, handler, "2", `{"score": 300, "playerName": "duck"}`)
+	putTestScores(t, handler, "3", `{"score": 900, "playerName": "swan"}`)
+	putTestScores(t, handler, "4", `{"score": 700, "playerName": "heron"}`)
+	response := handler.PatchProfile(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, `{"playerName": "mallard"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	tetris := api.ApiDefinition{Game: "Tetris"}
+
+	got := getTestGroupRanks(t, handler, tetris, map[string]string{"players": "2,1,5"})
+	if want := "1 goose 500, 2 mallard 300"; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	group := createTestGroup(t, handler, `{"playerIds": ["4", "3", "2"]}`)
+	got = getTestGroupRanks(t, handler, tetris, map[string]string{"group": group.Id})
+	if want := "1 swan 900, 2 heron 700, 3 mallard 300"; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	got = getTestGroupRanks(t, handler, api.ApiDefinition{Game: "Tetris", Board: "level1"}, map[string]string{"group": group.Id})
+	if got != "" {
+		t.Errorf("want no ranks on an empty board, got %v", got)
+	}
+}
+
+func TestGetGroupRanksInvalid(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.GetGroupRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{})
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+	response = handler.GetGroupRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"group": "9c1e"})
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+}
@@ -3,55 +3,327 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/indimeco/cheerleader/internal/api"
 	"github.com/indimeco/cheerleader/internal/ddb"
+	"github.com/indimeco/cheerleader/internal/memory"
 	"github.com/indimeco/cheerleader/internal/models"
-	"golang.org/x/sync/errgroup"
+	"github.com/indimeco/cheerleader/internal/postgres"
+	"github.com/indimeco/cheerleader/internal/sqlite"
 )
 
 type Handler struct {
 	Database HandlerDatabase
 	Logger   *slog.Logger
+	// UniquePlayersGames, AscendingGames and Location configure the games missing from the registry
+	// UniquePlayersGames are ranked with only the best score of each player unless a request asks otherwise
+	UniquePlayersGames map[string]bool
+	// AscendingGames rank their lowest scores first, for games such as speedruns where less is better
+	AscendingGames map[string]bool
+	// Location is the timezone in which daily, weekly and monthly leaderboards begin at midnight
+	Location *time.Location
+	// RejectUnknownGames refuses requests for games missing from the registry
+	RejectUnknownGames bool
+	// AdminToken is a bearer token with the admin role over every game, for creating the first api keys
+	AdminToken string
+	// SessionSecret signs the session tokens issued to players, games cannot require sessions without it
+	SessionSecret string
+	// RequireApiKeys refuses score and rank requests without an api key, admin requests always need one
+	RequireApiKeys bool
+	// PlayerRateLimit limits the writes for each player of each game, IpRateLimit the writes from each source ip and IpReadRateLimit the reads from each source ip
+	PlayerRateLimit models.RateLimit
+	IpRateLimit     models.RateLimit
+	IpReadRateLimit models.RateLimit
 }
 
 type HandlerDatabase interface {
 	PutScore(context.Context, models.Score) error
+	// PutScores stores the scores of a batch together, where the backend cannot store them atomically a failure may leave some of them stored
+	PutScores(context.Context, []models.Score) error
 	GetTopPlayerScores(context.Context, models.PlayerScoreRequest) ([]models.Score, error)
 	GetTopRanks(context.Context, models.RanksRequest) (models.Ranks, error)
+	// GetRanksAround returns exact positions for a window of ranks holding the player's score and at least Around ranks either side, where they exist
+	GetRanksAround(context.Context, models.RanksAroundRequest) (models.Ranks, error)
+	// GetGame reports whether the game is in the registry along with its config
+	GetGame(context.Context, string) (models.GameConfig, bool, error)
+	GetGames(context.Context) ([]models.GameConfig, error)
+	PutGame(context.Context, models.GameConfig) error
+	DeleteGame(context.Context, string) error
+	GetApiKey(context.Context, string) (models.ApiKey, bool, error)
+	GetApiKeys(context.Context) ([]models.ApiKey, error)
+	PutApiKey(context.Context, models.ApiKey) error
+	DeleteApiKey(context.Context, string) error
+	// Increment adds one to a counter and returns its new count, a counter past its expiry starts again from zero
+	Increment(ctx context.Context, key string, expiry int) (int, error)
+	// TakeToken takes a token from the key's bucket under the limit, reporting false when it is empty along with the refilled bucket
+	TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error)
+	PutQuarantinedScore(context.Context, models.QuarantinedScore) error
+	GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error)
+	GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error)
+	DeleteQuarantinedScore(ctx context.Context, game string, id string) error
+	// DeleteScore removes every submission of the player's score with the value of score, the order is the game's so that storage can find the player's next best score
+	DeleteScore(ctx context.Context, score models.Score) error
+	DeletePlayerScores(ctx context.Context, game string, playerId string) error
+	// GetPlayerPartitions returns the partitions of the game's own leaderboard and of its boards which hold scores of the player, registered or not, in no particular order
+	GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error)
+	PutBan(context.Context, models.Ban) error
+	GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error)
+	GetBans(ctx context.Context, game string) ([]models.Ban, error)
+	DeleteBan(ctx context.Context, game string, playerId string) error
+	PutProfile(context.Context, models.Profile) error
+	// GetProfiles returns the profiles of those players in the game who have one, in no particular order
+	GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error)
+	PutGroup(context.Context, models.Group) error
+	GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error)
+	DeleteGroup(ctx context.Context, game string, id string) error
+	// RenamePlayer sets the name shown on every score of the player in the game, as a profile's name is stored with the scores rather than read with each rank
+	RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error
+	// ClaimIdempotencyKey stores the pending record unless its key is already in use, in which case the record using it is returned with false
+	ClaimIdempotencyKey(context.Context, models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+	PutIdempotencyRecord(context.Context, models.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, key string) error
+	// ClaimNonce records a signature's nonce as used for the game until expiry, reporting false if it is already in use
+	ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error)
+	// ReleaseNonce forgets a claimed nonce, so that a request which failed to store what it was claimed for can be retried
+	ReleaseNonce(ctx context.Context, game string, nonce string) error
 }
 
 func New(ctx context.Context) (Handler, error) {
-	ddbClient, err := ddb.New(ctx)
+	database, err := newDatabase(ctx)
 	if err != nil {
 		return Handler{}, fmt.Errorf("Failed to get database: %w", err)
 	}
+	location, err := time.LoadLocation(os.Getenv("LEADERBOARD_TIMEZONE"))
+	if err != nil {
+		return Handler{}, fmt.Errorf("Failed to load leaderboard timezone: %w", err)
+	}
+	rejectUnknownGames, err := boolFromEnv("REJECT_UNKNOWN_GAMES")
+	if err != nil {
+		return Handler{}, err
+	}
+	requireApiKeys, err := boolFromEnv("REQUIRE_API_KEYS")
+	if err != nil {
+		return Handler{}, err
+	}
+	playerRateLimit, err := rateLimitFromEnv("PLAYER_RATE_LIMIT", defaultPlayerRateLimit)
+	if err != nil {
+		return Handler{}, err
+	}
+	ipRateLimit, err := rateLimitFromEnv("IP_RATE_LIMIT", defaultIpRateLimit)
+	if err != nil {
+		return Handler{}, err
+	}
+	ipReadRateLimit, err := rateLimitFromEnv("IP_READ_RATE_LIMIT", defaultIpReadRateLimit)
+	if err != nil {
+		return Handler{}, err
+	}
 
 	return Handler{
-		Database: ddbClient,
-		Logger:   slog.Default(),
+		Database:           database,
+		Logger:             slog.Default(),
+		UniquePlayersGames: gamesFromEnv("UNIQUE_PLAYERS_GAMES"),
+		AscendingGames:     gamesFromEnv("ASCENDING_GAMES"),
+		Location:           location,
+		RejectUnknownGames: rejectUnknownGames,
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		SessionSecret:      os.Getenv("SESSION_SECRET"),
+		RequireApiKeys:     requireApiKeys,
+		PlayerRateLimit:    playerRateLimit,
+		IpRateLimit:        ipRateLimit,
+		IpReadRateLimit:    ipReadRateLimit,
 	}, nil
 }
 
-func (h Handler) PutScore(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
-	score, err := models.NewScore(apiDefinition.Game, apiDefinition.PlayerId, body)
+// func boolFromEnv reads a flag from the env, which is false when unset
+func boolFromEnv(key string) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return false, nil
+	}
+	flag, err := strconv.ParseBool(value)
 	if err != nil {
-		return h.ResponseBadRequest(err)
+		return false, fmt.Errorf("Failed to parse %v: %w", key, err)
+	}
+	return flag, nil
+}
+
+// func gamesFromEnv reads a comma separated list of games from the env
+func gamesFromEnv(key string) map[string]bool {
+	games := make(map[string]bool)
+	for _, game := range strings.Split(os.Getenv(key), ",") {
+		game = strings.TrimSpace(game)
+		if game != "" {
+			games[game] = true
+		}
+	}
+	return games
+}
+
+// func defaultGameConfig is the config of a game missing from the registry, and the starting point for one being registered
+func (h Handler) defaultGameConfig(game string) models.GameConfig {
+	config := models.NewGameConfig(game)
+	config.UniquePlayers = h.UniquePlayersGames[game]
+	if h.AscendingGames[game] {
+		config.Order = models.LowestFirst
+	}
+	if h.Location != nil {
+		config.Timezone = h.Location.String()
+	}
+	return config
+}
+
+// func gameConfig returns the game's config from the registry, or the default config when the game is missing and unknown games are accepted
+// the bool is false when the game cannot be played
+func (h Handler) gameConfig(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	config, ok, err := h.Database.GetGame(ctx, game)
+	if err != nil {
+		return models.GameConfig{}, false, err
+	}
+	if ok {
+		return config, true, nil
+	}
+	if h.RejectUnknownGames {
+		return models.GameConfig{}, false, nil
+	}
+	return h.defaultGameConfig(game), true, nil
+}
+
+// the memory database must outlive a single request, so every handler shares one instance
+var memoryDatabase = sync.OnceValue(memory.New)
+
+// func newDatabase selects the storage backend named by the STORAGE_BACKEND env, defaulting to dynamodb
+func newDatabase(ctx context.Context) (HandlerDatabase, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "dynamodb":
+		return ddb.New(ctx)
+	case "sqlite":
+		return sqlite.New(ctx)
+	case "postgres":
+		return postgres.New(ctx)
+	case "memory":
+		return memoryDatabase(), nil
+	default:
+		return nil, fmt.Errorf("Unknown storage backend %q", backend)
+	}
+}
+
+// func PutScore submits a score, a score sent with an Idempotency-Key header is submitted once however many times it is retried
+func (h Handler) PutScore(ctx context.Context, apiDefinition api.ApiDefinition, headers map[string]string, body string) events.APIGatewayProxyResponse {
+	key := header(headers, "Idempotency-Key")
+	if key == "" {
+		return h.putScore(ctx, apiDefinition, headers, body)
+	}
+	return h.idempotent(ctx, apiDefinition, key, body, func() events.APIGatewayProxyResponse {
+		return h.putScore(ctx, apiDefinition, headers, body)
+	})
+}
+
+// func putScore records a score, the headers must carry a signature when the game has a signing secret and a session token when it requires sessions
+func (h Handler) putScore(ctx context.Context, apiDefinition api.ApiDefinition, headers map[string]string, body string) events.APIGatewayProxyResponse {
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	if response, ok := h.checkBan(ctx, apiDefinition.Game, apiDefinition.PlayerId); !ok {
+		return response
+	}
+	if config.SigningSecret != "" {
+		ok, err := h.verifyScoreSignature(ctx, config, "PUT", ScorePath(apiDefinition), headers, body)
+		if err != nil {
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to verify score signature: %w", err))
+		}
+		if !ok {
+			return h.ResponseUnauthorized()
+		}
+	}
+	score, response, ok := h.checkScore(ctx, config, apiDefinition.PlayerId, headers, body)
+	if !ok {
+		return response
 	}
 	err = h.Database.PutScore(ctx, score)
 	if err != nil {
+		h.releaseNonces(ctx, config, headers, []string{header(headers, SessionTokenHeader)})
 		return h.ResponseInternalServerError(fmt.Errorf("Failed to put score: %w", err))
 	}
 
 	return h.ResponseCreated()
 }
 
+// func releaseNonces gives back the signature nonce of a submission and the sessions of its scores which were to be stored, so that retrying a submission which failed to be stored is not refused as a replay
+// a nonce which cannot be released is logged, as the retry is then refused in the same way it would have been
+func (h Handler) releaseNonces(ctx context.Context, config models.GameConfig, headers map[string]string, sessionTokens []string) {
+	release := func(game string, nonce string) {
+		if err := h.Database.ReleaseNonce(ctx, game, nonce); err != nil {
+			h.Logger.Error(fmt.Sprintf("Failed to release nonce for game %q: %v", game, err))
+		}
+	}
+	if config.SigningSecret != "" {
+		release(config.Game, header(headers, SignatureNonceHeader))
+	}
+	if !config.RequireSessions {
+		return
+	}
+	for _, token := range sessionTokens {
+		// the token was parsed when the score was checked, so it parses again
+		session, err := models.ParseSessionToken(h.SessionSecret, token)
+		if err == nil {
+			release(models.PartitionGame(config.Game), sessionNoncePrefix+session.Id)
+		}
+	}
+}
+
+// func checkScore builds the player's score from the body and checks it against the game's session requirement and rules
+// when the bool is false the score must not be stored and the submission is responded to with the response, which is accepted for a quarantined score
+func (h Handler) checkScore(ctx context.Context, config models.GameConfig, playerId string, headers map[string]string, body string) (models.Score, events.APIGatewayProxyResponse, bool) {
+	score, err := models.NewScore(config, playerId, body)
+	if err != nil {
+		return models.Score{}, h.ResponseBadRequest(err), false
+	}
+	score, err = h.withProfileName(ctx, score)
+	if err != nil {
+		return models.Score{}, h.ResponseInternalServerError(err), false
+	}
+	if config.RequireSessions {
+		response, ok := h.useSession(ctx, config, score, headers)
+		if !ok {
+			return models.Score{}, response, false
+		}
+	}
+	reason, err := h.checkRules(ctx, config, score)
+	if err != nil {
+		return models.Score{}, h.ResponseInternalServerError(fmt.Errorf("Failed to check score rules: %w", err)), false
+	}
+	if reason != "" {
+		if !config.Quarantine {
+			return models.Score{}, h.ResponseBadRequest(errors.New(reason)), false
+		}
+		return models.Score{}, h.quarantineScore(ctx, score, reason), false
+	}
+	return score, events.APIGatewayProxyResponse{}, true
+}
+
 func (h Handler) GetTopPlayerScores(ctx context.Context, apiDefinition api.ApiDefinition, params map[string]string) events.APIGatewayProxyResponse {
-	scoreRequest, err := models.NewPlayerScoreRequest(params, apiDefinition.Game, apiDefinition.PlayerId)
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	scoreRequest, err := models.NewPlayerScoreRequest(params, config, apiDefinition.PlayerId)
 	if err != nil {
 		return h.ResponseBadRequest(err)
 	}
@@ -67,7 +339,14 @@ func (h Handler) GetTopPlayerScores(ctx context.Context, apiDefinition api.ApiDe
 }
 
 func (h Handler) GetTopRanks(ctx context.Context, apiDefinition api.ApiDefinition, params map[string]string) events.APIGatewayProxyResponse {
-	ranksRequest, err := models.NewRanksRequest(params, apiDefinition.Game)
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	ranksRequest, err := models.NewRanksRequest(params, config)
 	if err != nil {
 		return h.ResponseBadRequest(err)
 	}
@@ -83,37 +362,24 @@ func (h Handler) GetTopRanks(ctx context.Context, apiDefinition api.ApiDefinitio
 }
 
 func (h Handler) GetRanksAroundPlayer(ctx context.Context, apiDefinition api.ApiDefinition, params map[string]string) events.APIGatewayProxyResponse {
-	playerRanksRequest, err := models.NewPlayerRanksRequest(params, apiDefinition.Game, apiDefinition.PlayerId)
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	playerRanksRequest, err := models.NewPlayerRanksRequest(params, config, apiDefinition.PlayerId)
 	if err != nil {
 		return h.ResponseBadRequest(err)
 	}
 
-	var playerScores []models.Score
-	var ranks models.Ranks
-	g := new(errgroup.Group)
-
-	g.Go(func() error {
-		scores, err := h.Database.GetTopPlayerScores(ctx, models.PlayerScoreRequest{
-			PlayerId:     apiDefinition.PlayerId,
-			ScoreRequest: models.ScoreRequest{Game: apiDefinition.Game, Limit: 1},
-		})
-
-		if err != nil {
-			return fmt.Errorf("Failed to get top player score: %w", err)
-		}
-		playerScores = scores
-		return nil
+	playerScores, err := h.Database.GetTopPlayerScores(ctx, models.PlayerScoreRequest{
+		PlayerId:     apiDefinition.PlayerId,
+		ScoreRequest: models.ScoreRequest{Game: config.Game, Limit: 1, Period: playerRanksRequest.Period, Order: playerRanksRequest.Order, Filter: playerRanksRequest.Filter},
 	})
-	g.Go(func() error {
-		allRanks, err := h.Database.GetTopRanks(ctx, models.RanksRequest{Game: apiDefinition.Game})
-		if err != nil {
-			return fmt.Errorf("Failed to get top ranks: %w", err)
-		}
-		ranks = allRanks
-		return nil
-	})
-	if err := g.Wait(); err != nil {
-		return h.ResponseInternalServerError(err)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get top player score: %w", err))
 	}
 
 	// Player hasn't scored yet
@@ -122,13 +388,30 @@ func (h Handler) GetRanksAroundPlayer(ctx context.Context, apiDefinition api.Api
 	}
 	topScore := playerScores[0]
 
-	index := ranks.BinarySearch(topScore.Score, 0, len(ranks))
+	ranks, err := h.Database.GetRanksAround(ctx, models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{
+			Game:          config.Game,
+			UniquePlayers: playerRanksRequest.UniquePlayers,
+			Period:        playerRanksRequest.Period,
+			Order:         playerRanksRequest.Order,
+			Filter:        playerRanksRequest.Filter,
+		},
+		PlayerId:   apiDefinition.PlayerId,
+		Score:      topScore.Score,
+		Timestamp:  topScore.Timestamp,
+		ReceivedAt: topScore.ReceivedAt,
+		Around:     playerRanksRequest.Around,
+	})
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get ranks around player: %w", err))
+	}
+
+	index := ranks.BinarySearch(models.Rank{Score: topScore.Score, Timestamp: topScore.Timestamp, ReceivedAt: topScore.ReceivedAt, PlayerId: apiDefinition.PlayerId}, playerRanksRequest.Order, 0, len(ranks)-1)
 	// Player is not ranked
 	if index == -1 {
 		return h.ResponseOk("[]")
 	}
 	ranksAround := ranks.Around(index, playerRanksRequest.Around)
-
 	out, err := json.Marshal(&ranksAround)
 	if err != nil {
 		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal player ranks: %w", err))
@@ -157,6 +440,19 @@ func (h Handler) ResponseCreated() events.APIGatewayProxyResponse {
 	}
 }
 
+func (h Handler) ResponseCreatedWith(data string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		Body:       data,
+	}
+}
+
+func (h Handler) ResponseAccepted() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusAccepted,
+	}
+}
+
 func (h Handler) ResponseBadRequest(err error) events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
 		Body:       fmt.Sprint(err),
@@ -164,6 +460,20 @@ func (h Handler) ResponseBadRequest(err error) events.APIGatewayProxyResponse {
 	}
 }
 
+func (h Handler) ResponseConflict(err error) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		Body:       fmt.Sprint(err),
+		StatusCode: http.StatusConflict,
+	}
+}
+
+func (h Handler) ResponseUnprocessableEntity(err error) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		Body:       fmt.Sprint(err),
+		StatusCode: http.StatusUnprocessableEntity,
+	}
+}
+
 func (h Handler) ResponseMethodNotAllowed() events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusMethodNotAllowed,
@@ -171,6 +481,26 @@ func (h Handler) ResponseMethodNotAllowed() events.APIGatewayProxyResponse {
 	}
 }
 
+func (h Handler) ResponseNoContent() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusNoContent,
+	}
+}
+
+func (h Handler) ResponseUnauthorized() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusUnauthorized,
+		Body:       "Unauthorized",
+	}
+}
+
+func (h Handler) ResponseForbidden() events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusForbidden,
+		Body:       "Forbidden",
+	}
+}
+
 func (h Handler) ResponseNotFound() events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
 		StatusCode: http.StatusNotFound,
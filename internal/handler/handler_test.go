@@ -2,15 +2,26 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/memory"
 	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/indimeco/cheerleader/internal/storagetest"
 )
 
+// every backend the handler can be given is one the conformance suite verifies
+var _ storagetest.Database = HandlerDatabase(nil)
+
 type testDatabase struct{}
 
 func (t testDatabase) PutScore(ctx context.Context, score models.Score) error {
@@ -20,6 +31,15 @@ func (t testDatabase) PutScore(ctx context.Context, score models.Score) error {
 	return nil
 }
 
+func (t testDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	for _, score := range scores {
+		if err := t.PutScore(ctx, score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (testDatabase) GetTopPlayerScores(ctx context.Context, scoreRequest models.PlayerScoreRequest) ([]models.Score, error) {
 	if scoreRequest.Game == "error" {
 		return nil, errors.New("an error occurred")
@@ -69,6 +89,138 @@ func (testDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRe
 	}, nil
 }
 
+func (t testDatabase) GetRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	return t.GetTopRanks(ctx, request.RanksRequest)
+}
+
+func (testDatabase) GetGame(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	return models.GameConfig{}, false, nil
+}
+
+func (testDatabase) GetGames(ctx context.Context) ([]models.GameConfig, error) {
+	return []models.GameConfig{}, nil
+}
+
+func (testDatabase) PutGame(ctx context.Context, config models.GameConfig) error {
+	return nil
+}
+
+func (testDatabase) DeleteGame(ctx context.Context, game string) error {
+	return nil
+}
+
+func (testDatabase) GetApiKey(ctx context.Context, id string) (models.ApiKey, bool, error) {
+	return models.ApiKey{}, false, nil
+}
+
+func (testDatabase) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	return []models.ApiKey{}, nil
+}
+
+func (testDatabase) PutApiKey(ctx context.Context, key models.ApiKey) error {
+	return nil
+}
+
+func (testDatabase) DeleteApiKey(ctx context.Context, id string) error {
+	return nil
+}
+
+func (testDatabase) Increment(ctx context.Context, key string, expiry int) (int, error) {
+	return 1, nil
+}
+
+func (testDatabase) PutQuarantinedScore(ctx context.Context, score models.QuarantinedScore) error {
+	return nil
+}
+
+func (testDatabase) GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error) {
+	return models.QuarantinedScore{}, false, nil
+}
+
+func (testDatabase) GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error) {
+	return []models.QuarantinedScore{}, nil
+}
+
+func (testDatabase) DeleteQuarantinedScore(ctx context.Context, game string, id string) error {
+	return nil
+}
+
+func (testDatabase) DeleteScore(ctx context.Context, score models.Score) error {
+	return nil
+}
+
+func (testDatabase) DeletePlayerScores(ctx context.Context, game string, playerId string) error {
+	return nil
+}
+
+func (testDatabase) GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (testDatabase) PutBan(ctx context.Context, ban models.Ban) error {
+	return nil
+}
+
+func (testDatabase) GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error) {
+	return models.Ban{}, false, nil
+}
+
+func (testDatabase) GetBans(ctx context.Context, game string) ([]models.Ban, error) {
+	return []models.Ban{}, nil
+}
+
+func (testDatabase) DeleteBan(ctx context.Context, game string, playerId string) error {
+	return nil
+}
+
+func (testDatabase) PutProfile(ctx context.Context, profile models.Profile) error {
+	return nil
+}
+
+func (testDatabase) RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error {
+	return nil
+}
+
+func (testDatabase) GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error) {
+	return []models.Profile{}, nil
+}
+
+func (testDatabase) PutGroup(ctx context.Context, group models.Group) error {
+	return nil
+}
+
+func (testDatabase) GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error) {
+	return models.Group{}, false, nil
+}
+
+func (testDatabase) DeleteGroup(ctx context.Context, game string, id string) error {
+	return nil
+}
+
+func (testDatabase) TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error) {
+	return models.TokenBucket{}, true, nil
+}
+
+func (testDatabase) ClaimIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	return record, true, nil
+}
+
+func (testDatabase) PutIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord) error {
+	return nil
+}
+
+func (testDatabase) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	return nil
+}
+
+func (testDatabase) ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error) {
+	return true, nil
+}
+
+func (testDatabase) ReleaseNonce(ctx context.Context, game string, nonce string) error {
+	return nil
+}
+
 func createTestHandler() Handler {
 	return Handler{
 		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
@@ -84,7 +236,7 @@ func TestPutScore(t *testing.T) {
 		PlayerId: "2",
 	}
 	body := `{"score": 10, "playerName": "goose"}`
-	response := handler.PutScore(ctx, apiDefinition, body)
+	response := handler.PutScore(ctx, apiDefinition, nil, body)
 
 	if response.StatusCode != 201 {
 		t.Errorf("want %v, got %v", 201, response.StatusCode)
@@ -99,7 +251,7 @@ func TestPutScoreInvalidDb(t *testing.T) {
 		PlayerId: "2",
 	}
 	body := `{"score": 10, "playerName": "goose"}`
-	response := handler.PutScore(ctx, apiDefinition, body)
+	response := handler.PutScore(ctx, apiDefinition, nil, body)
 
 	if response.StatusCode != 500 {
 		t.Errorf("want %v, got %v", 500, response.StatusCode)
@@ -114,7 +266,7 @@ func TestPutScoreInvalidBody(t *testing.T) {
 		PlayerId: "2",
 	}
 	body := `abcdefg`
-	response := handler.PutScore(ctx, apiDefinition, body)
+	response := handler.PutScore(ctx, apiDefinition, nil, body)
 
 	if response.StatusCode != 400 {
 		t.Errorf("want %v, got %v", 400, response.StatusCode)
@@ -210,3 +362,396 @@ func TestGetTopRanksDbError(t *testing.T) {
 		t.Errorf("want %v, got %v", 500, response.StatusCode)
 	}
 }
+
+func TestPutScoreThenGetRanks(t *testing.T) {
+	handler := Handler{
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database: memory.New(),
+	}
+	ctx := context.Background()
+
+	puts := []struct {
+		playerId string
+		body     string
+	}{
+		{playerId: "1", body: `{"score": 10, "playerName": "goose"}`},
+		{playerId: "2", body: `{"score": 30, "playerName": "duck"}`},
+		{playerId: "1", body: `{"score": 20, "playerName": "goose"}`},
+	}
+	for _, put := range puts {
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: put.playerId}, nil, put.body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	response := handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 1, PlayerName: "duck", Score: 30},
+		{Position: 2, PlayerName: "goose", Score: 20},
+		{Position: 3, PlayerName: "goose", Score: 10},
+	}
+	if diff := cmp.Diff(want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	response = handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, map[string]string{"ranks_around": "1"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want = models.Ranks{
+		{Position: 1, PlayerName: "duck", Score: 30},
+		{Position: 2, PlayerName: "goose", Score: 20},
+		{Position: 3, PlayerName: "goose", Score: 10},
+	}
+	if diff := cmp.Diff(want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetTopRanksWindow(t *testing.T) {
+	database := memory.New()
+	handler := Handler{
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database: database,
+		Location: time.UTC,
+	}
+	ctx := context.Background()
+
+	lastYear := int(time.Now().AddDate(-1, 0, 0).Unix())
+	err := database.PutScore(ctx, models.Score{PlayerId: "2", PlayerName: "duck", Game: "Tetris", Score: 30, Timestamp: lastYear})
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 10, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+
+	type test struct {
+		window string
+		want   models.Ranks
+	}
+	testCases := []test{
+		{window: "alltime", want: models.Ranks{{Position: 1, PlayerName: "duck", Score: 30}, {Position: 2, PlayerName: "goose", Score: 10}}},
+		{window: "daily", want: models.Ranks{{Position: 1, PlayerName: "goose", Score: 10}}},
+		{window: "weekly", want: models.Ranks{{Position: 1, PlayerName: "goose", Score: 10}}},
+		{window: "monthly", want: models.Ranks{{Position: 1, PlayerName: "goose", Score: 10}}},
+	}
+	for _, tc := range testCases {
+		response := handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10", "window": tc.window})
+		if response.StatusCode != 200 {
+			t.Fatalf("want %v, got %v", 200, response.StatusCode)
+		}
+		var ranks models.Ranks
+		if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if diff := cmp.Diff(tc.want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+			t.Errorf("mismatch for %v (-want +got):\n%s", tc.window, diff)
+		}
+	}
+
+	response = handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10", "window": "hourly"})
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestPutScoreAchievedAt(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxAchievedAgeSeconds": 172800}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	yesterday := time.Now().UTC().Truncate(24 * time.Hour).Add(-time.Hour).Unix()
+
+	type test struct {
+		name string
+		body string
+		want int
+	}
+	testCases := []test{
+		{name: "yesterday", body: fmt.Sprintf(`{"score": 10, "playerName": "goose", "achievedAt": %v}`, yesterday), want: 201},
+		{name: "too old", body: fmt.Sprintf(`{"score": 20, "playerName": "goose", "achievedAt": %v}`, time.Now().AddDate(0, 0, -3).Unix()), want: 400},
+		{name: "future", body: fmt.Sprintf(`{"score": 30, "playerName": "goose", "achievedAt": %v}`, time.Now().Add(time.Hour).Unix()), want: 400},
+	}
+	for _, tc := range testCases {
+		response := handler.PutScore(ctx, apiDefinition, nil, tc.body)
+		if response.StatusCode != tc.want {
+			t.Errorf("%v: want %v, got %v", tc.name, tc.want, response.StatusCode)
+		}
+	}
+
+	for window, want := range map[string]int{"alltime": 1, "daily": 0} {
+		response := handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10", "window": window})
+		var ranks models.Ranks
+		if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if len(ranks) != want {
+			t.Errorf("%v: want %v ranks, got %v", window, want, ranks)
+		}
+	}
+	response = handler.GetTopPlayerScores(ctx, apiDefinition, map[string]string{"limit": "10"})
+	var scores []models.Score
+	if err := json.Unmarshal([]byte(response.Body), &scores); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(scores) != 1 || scores[0].Timestamp != int(yesterday) || scores[0].ReceivedAt <= scores[0].Timestamp {
+		t.Errorf("want the score achieved yesterday and received since, got %v", scores)
+	}
+}
+
+func TestGetRanksFilteredByMetadata(t *testing.T) {
+	handler := Handler{
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database: memory.New(),
+	}
+	ctx := context.Background()
+
+	puts := []struct {
+		playerId string
+		body     string
+	}{
+		{playerId: "1", body: `{"score": 30, "playerName": "goose", "metadata": {"platform": "switch"}}`},
+		{playerId: "1", body: `{"score": 10, "playerName": "goose", "metadata": {"platform": "pc", "level": "2"}}`},
+		{playerId: "2", body: `{"score": 20, "playerName": "duck", "metadata": {"platform": "pc"}}`},
+	}
+	for _, put := range puts {
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: put.playerId}, nil, put.body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	response := handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10", "filter": "platform:pc"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 1, PlayerName: "duck", Score: 20, Metadata: models.Metadata{"platform": "pc"}},
+		{Position: 2, PlayerName: "goose", Score: 10, Metadata: models.Metadata{"platform": "pc", "level": "2"}},
+	}
+	if diff := cmp.Diff(want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// the player's position is among the scores matching the filter, not their best score on every platform
+	response = handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, map[string]string{"ranks_around": "1", "filter": "platform:pc"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	ranks = nil
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	response = handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10", "filter": "platform"})
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestGetRanksAroundPlayerAscendingGame(t *testing.T) {
+	handler := Handler{
+		Logger:         slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database:       memory.New(),
+		AscendingGames: map[string]bool{"Golf": true},
+	}
+	ctx := context.Background()
+
+	puts := []struct {
+		playerId string
+		body     string
+	}{
+		{playerId: "1", body: `{"score": 72, "playerName": "goose"}`},
+		{playerId: "1", body: `{"score": 68, "playerName": "goose"}`},
+		{playerId: "2", body: `{"score": 70, "playerName": "duck"}`},
+		{playerId: "3", body: `{"score": 75, "playerName": "swan"}`},
+	}
+	for _, put := range puts {
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", PlayerId: put.playerId}, nil, put.body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	response := handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Golf", PlayerId: "2"}, map[string]string{"ranks_around": "1", "unique_players": "true"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 1, PlayerName: "goose", Score: 68},
+		{Position: 2, PlayerName: "duck", Score: 70},
+		{Position: 3, PlayerName: "swan", Score: 75},
+	}
+	if diff := cmp.Diff(want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetRanksAroundPlayerWithEqualScores(t *testing.T) {
+	database := memory.New()
+	handler := Handler{
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database: database,
+	}
+	ctx := context.Background()
+
+	for _, score := range []models.Score{
+		{PlayerId: "1", PlayerName: "goose", Game: "Tetris", Score: 100, Timestamp: 111},
+		{PlayerId: "3", PlayerName: "swan", Game: "Tetris", Score: 100, Timestamp: 222},
+		{PlayerId: "2", PlayerName: "duck", Game: "Tetris", Score: 100, Timestamp: 333},
+		{PlayerId: "2", PlayerName: "duck", Game: "Tetris", Score: 100, Timestamp: 444},
+	} {
+		if err := database.PutScore(ctx, score); err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+	}
+
+	response := handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, map[string]string{"ranks_around": "1"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 2, PlayerName: "swan", Score: 100, Timestamp: 222},
+		{Position: 3, PlayerName: "duck", Score: 100, Timestamp: 333},
+		{Position: 4, PlayerName: "duck", Score: 100, Timestamp: 444},
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("STORAGE_BACKEND", "memory")
+	first, err := newDatabase(ctx)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	second, err := newDatabase(ctx)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if first != second {
+		t.Errorf("want the memory database to be shared between handlers")
+	}
+
+	t.Setenv("STORAGE_BACKEND", "sqlite")
+	t.Setenv("SQLITE_PATH", filepath.Join(t.TempDir(), "test.db"))
+	_, err = newDatabase(ctx)
+	if err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+
+	t.Setenv("STORAGE_BACKEND", "papyrus")
+	_, err = newDatabase(ctx)
+	if err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestGetTopRanksUniquePlayersGame(t *testing.T) {
+	handler := Handler{
+		Logger:             slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database:           memory.New(),
+		UniquePlayersGames: map[string]bool{"Tetris": true},
+	}
+	ctx := context.Background()
+	for _, body := range []string{`{"score": 10, "playerName": "goose"}`, `{"score": 20, "playerName": "goose"}`} {
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	type test struct {
+		params map[string]string
+		want   models.Ranks
+	}
+	testCases := []test{
+		{
+			params: map[string]string{"limit": "10"},
+			want:   models.Ranks{{Position: 1, PlayerName: "goose", Score: 20}},
+		},
+		{
+			params: map[string]string{"limit": "10", "unique_players": "false"},
+			want:   models.Ranks{{Position: 1, PlayerName: "goose", Score: 20}, {Position: 2, PlayerName: "goose", Score: 10}},
+		},
+	}
+	for _, tc := range testCases {
+		response := handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, tc.params)
+		if response.StatusCode != 200 {
+			t.Fatalf("want %v, got %v", 200, response.StatusCode)
+		}
+		var ranks models.Ranks
+		if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if diff := cmp.Diff(tc.want, ranks, cmpopts.IgnoreFields(models.Rank{}, "Timestamp")); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestGetRanksAroundPlayerOutsideTopRanks(t *testing.T) {
+	database := memory.New()
+	handler := Handler{
+		Logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		Database: database,
+	}
+	ctx := context.Background()
+	for i := 1; i <= 1500; i++ {
+		err := database.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: fmt.Sprint("player", i), Game: "Tetris", Score: i})
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+	}
+
+	response := handler.GetRanksAroundPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "100"}, map[string]string{"ranks_around": "1"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 1400, PlayerName: "player101", Score: 101},
+		{Position: 1401, PlayerName: "player100", Score: 100},
+		{Position: 1402, PlayerName: "player99", Score: 99},
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
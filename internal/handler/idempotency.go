@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func idempotent runs the request once for each Idempotency-Key, retries of the request get the response to the first
+// only a response to a write which was made completes the key, any other response releases it so that its retries run again
+// a request refused for its credentials or body may be fixed and retried with the same key, as may one which failed with a server error
+func (h Handler) idempotent(ctx context.Context, apiDefinition api.ApiDefinition, key string, body string, request func() events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	record, err := models.NewIdempotencyRecord(models.BoardPartition(apiDefinition.Game, apiDefinition.Board), apiDefinition.PlayerId, key, body, time.Now())
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	existing, ok, err := h.Database.ClaimIdempotencyKey(ctx, record)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to claim idempotency key: %w", err))
+	}
+	if !ok {
+		if existing.Pending() {
+			return h.ResponseConflict(errors.New("A request with the Idempotency-Key is in progress"))
+		}
+		if !existing.Matches(body) {
+			return h.ResponseUnprocessableEntity(errors.New("The Idempotency-Key was used for a different request"))
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: existing.StatusCode,
+			Headers:    map[string]string{"Idempotent-Replayed": "true"},
+			Body:       existing.Body,
+		}
+	}
+
+	response := request()
+	if !isExecutedWrite(response.StatusCode) {
+		err = h.Database.DeleteIdempotencyRecord(ctx, record.Key)
+		if err != nil {
+			h.Logger.Error(fmt.Sprintf("Failed to release idempotency key: %v", err))
+		}
+		return response
+	}
+	err = h.Database.PutIdempotencyRecord(ctx, record.Completed(response.StatusCode, response.Body, time.Now()))
+	if err != nil {
+		// the request has already been made, so its response is still sent and retries are refused as in progress until the claim times out
+		h.Logger.Error(fmt.Sprintf("Failed to put idempotency record: %v", err))
+	}
+	return response
+}
+
+// func isExecutedWrite is whether a response reflects a write that was made
+func isExecutedWrite(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
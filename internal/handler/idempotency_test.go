@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func TestPutScoreReplaysIdempotentResponse(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	// a second submission within the minute would break the rule, unless it is recognised as a retry and not submitted again
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxSubmissionsPerMinute": 1}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	headers := map[string]string{"Idempotency-Key": "7c0f5a3e"}
+	body := `{"score": 100, "playerName": "goose"}`
+
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 201 {
+		t.Errorf("want %v, got %v", 201, response.StatusCode)
+	}
+	if response.Headers["Idempotent-Replayed"] != "true" {
+		t.Errorf("want the response to be marked as replayed, got %v", response.Headers)
+	}
+
+	response = handler.PutScore(ctx, apiDefinition, headers, `{"score": 200, "playerName": "goose"}`)
+	if response.StatusCode != 422 {
+		t.Errorf("want %v, got %v", 422, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, nil, body)
+	if response.StatusCode != 400 {
+		t.Errorf("want a submission without the key to be submitted again, got %v", response.StatusCode)
+	}
+}
+
+func TestPutScoreWithIdempotencyKeyInProgress(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	body := `{"score": 100, "playerName": "goose"}`
+	record, err := models.NewIdempotencyRecord("Tetris", "1", "7c0f5a3e", body, time.Now())
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	_, _, err = handler.Database.ClaimIdempotencyKey(ctx, record)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+
+	response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, map[string]string{"Idempotency-Key": "7c0f5a3e"}, body)
+	if response.StatusCode != 409 {
+		t.Errorf("want %v, got %v", 409, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, map[string]string{"Idempotency-Key": "7c0f5a3e"}, body)
+	if response.StatusCode != 201 {
+		t.Errorf("want keys to be scoped to the player, got %v", response.StatusCode)
+	}
+}
+
+func TestPutScoreWithInvalidIdempotencyKey(t *testing.T) {
+	handler := createTestAdminHandler()
+	response := handler.PutScore(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, map[string]string{"Idempotency-Key": "two words"}, `{"score": 100, "playerName": "goose"}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+// failingPutScoreDatabase fails to store the first scores put to it
+type failingPutScoreDatabase struct {
+	HandlerDatabase
+	failures *int
+}
+
+func (d failingPutScoreDatabase) PutScore(ctx context.Context, score models.Score) error {
+	if *d.failures > 0 {
+		*d.failures--
+		return errors.New("table unavailable")
+	}
+	return d.HandlerDatabase.PutScore(ctx, score)
+}
+
+func TestPutScoreRetriedAfterFailedWrite(t *testing.T) {
+	handler := createTestAdminHandler()
+	failures := 1
+	handler.Database = failingPutScoreDatabase{HandlerDatabase: handler.Database, failures: &failures}
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"signingSecret": "`+testSigningSecret+`", "requireSessions": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	body := `{"score": 10, "playerName": "goose"}`
+	headers := signedHeaders(apiDefinition, testSigningSecret, time.Now().Unix(), "n1", body)
+	maps.Copy(headers, postTestSession(t, handler, apiDefinition))
+	headers["Idempotency-Key"] = "score-1"
+
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 500 {
+		t.Fatalf("failed write: want %v, got %v", 500, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 201 {
+		t.Errorf("retry: want %v, got %v", 201, response.StatusCode)
+	}
+	if ranks := getTestRanks(t, handler, "Tetris"); len(ranks) != 1 {
+		t.Errorf("want the retried score stored, got %v", ranks)
+	}
+}
+
+func TestPutScoreRetriedAfterRefusal(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	headers := map[string]string{"Idempotency-Key": "7c0f5a3e"}
+	body := `{"score": 100, "playerName": "goose"}`
+	// the player is banned, so the first attempt is refused without writing the score
+	response := handler.BanPlayer(ctx, apiDefinition, `{}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 403 {
+		t.Fatalf("want %v, got %v", 403, response.StatusCode)
+	}
+
+	response = handler.UnbanPlayer(ctx, apiDefinition)
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, headers, body)
+	if response.StatusCode != 201 {
+		t.Errorf("want %v, got %v", 201, response.StatusCode)
+	}
+	if response.Headers["Idempotent-Replayed"] != "" {
+		t.Errorf("want the retry to be submitted rather than replayed, got %v", response.Headers)
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func DeleteScore removes one of a player's scores from every leaderboard, a player's scores are identified by their value so each submission of it is removed
+func (h Handler) DeleteScore(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	value, err := strconv.Atoi(apiDefinition.ScoreId)
+	if err != nil {
+		return h.ResponseBadRequest(fmt.Errorf("Failed to parse score: %w", err))
+	}
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	// a game may have been removed from the registry after its scores were submitted, they are still deleted by the default order
+	if !ok {
+		config = h.defaultGameConfig(apiDefinition.Game)
+		config.Game = models.BoardPartition(apiDefinition.Game, apiDefinition.Board)
+	}
+
+	err = h.Database.DeleteScore(ctx, models.Score{
+		Game:     config.Game,
+		PlayerId: apiDefinition.PlayerId,
+		Score:    value,
+		Order:    config.Order,
+	})
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete score: %w", err))
+	}
+	return h.ResponseNoContent()
+}
+
+// func DeletePlayerScores removes the player's scores from the game and each of its boards, or from only the board the request is within
+func (h Handler) DeletePlayerScores(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	var err error
+	if apiDefinition.Board != "" {
+		err = h.Database.DeletePlayerScores(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board), apiDefinition.PlayerId)
+	} else {
+		err = h.deleteGamePlayerScores(ctx, apiDefinition.Game, apiDefinition.PlayerId)
+	}
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete player scores: %w", err))
+	}
+	return h.ResponseNoContent()
+}
+
+func (h Handler) GetBans(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	bans, err := h.Database.GetBans(ctx, apiDefinition.Game)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get bans: %w", err))
+	}
+	out, err := json.Marshal(&bans)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal bans: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func BanPlayer refuses the player's future scores for the game and its boards, and deletes those already submitted to any of them
+// the scores are not restored if the ban is lifted
+func (h Handler) BanPlayer(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
+	ban, err := models.NewBan(apiDefinition.Game, apiDefinition.PlayerId, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	// the ban is stored first so that no score can be submitted between deleting the player's scores and banning them
+	err = h.Database.PutBan(ctx, ban)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put ban: %w", err))
+	}
+	err = h.deleteGamePlayerScores(ctx, ban.Game, ban.PlayerId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete player scores: %w", err))
+	}
+	out, err := json.Marshal(&ban)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal ban: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func deleteGamePlayerScores removes the player's scores from the game's own leaderboard and from each of its boards, which storage finds from the player's scores
+func (h Handler) deleteGamePlayerScores(ctx context.Context, game string, playerId string) error {
+	partitions, err := h.Database.GetPlayerPartitions(ctx, game, playerId)
+	if err != nil {
+		return fmt.Errorf("Failed to get player partitions: %w", err)
+	}
+	for _, partition := range partitions {
+		err := h.Database.DeletePlayerScores(ctx, partition, playerId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h Handler) UnbanPlayer(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	err := h.Database.DeleteBan(ctx, apiDefinition.Game, apiDefinition.PlayerId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete ban: %w", err))
+	}
+	return h.ResponseNoContent()
+}
+
+// func checkBan refuses a request from a player banned from the game, the bool is false when the request must be refused with the response
+func (h Handler) checkBan(ctx context.Context, game string, playerId string) (events.APIGatewayProxyResponse, bool) {
+	_, banned, err := h.Database.GetBan(ctx, game, playerId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get ban: %w", err)), false
+	}
+	if banned {
+		return h.ResponseForbidden(), false
+	}
+	return events.APIGatewayProxyResponse{}, true
+}
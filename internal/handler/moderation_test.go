@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func getTestRanks(t *testing.T, handler Handler, game string) models.Ranks {
+	t.Helper()
+	response := handler.GetTopRanks(context.Background(), api.ApiDefinition{Game: game}, map[string]string{"limit": "10"})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return ranks
+}
+
+func putTestScores(t *testing.T, handler Handler, playerId string, bodies ...string) {
+	t.Helper()
+	for _, body := range bodies {
+		response := handler.PutScore(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: playerId}, nil, body)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+}
+
+func TestDeleteScore(t *testing.T) {
+	handler := createTestAdminHandler()
+	putTestScores(t, handler, "1", `{"score": 500, "playerName": "goose"}`, `{"score": 100, "playerName": "goose"}`)
+
+	response := handler.DeleteScore(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1", ScoreId: "500"})
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	ranks := getTestRanks(t, handler, "Tetris")
+	if len(ranks) != 1 || ranks[0].Score != 100 {
+		t.Errorf("want only the remaining score, got %v", ranks)
+	}
+}
+
+func TestDeletePlayerScores(t *testing.T) {
+	handler := createTestAdminHandler()
+	putTestScores(t, handler, "1", `{"score": 500, "playerName": "goose"}`, `{"score": 100, "playerName": "goose"}`)
+	putTestScores(t, handler, "2", `{"score": 300, "playerName": "duck"}`)
+
+	response := handler.DeletePlayerScores(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1"})
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	ranks := getTestRanks(t, handler, "Tetris")
+	if len(ranks) != 1 || ranks[0].PlayerName != "duck" {
+		t.Errorf("want only the other player's score, got %v", ranks)
+	}
+}
+
+func TestBanPlayerWithBoardScores(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris", Board: "level1"}, `{}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	// level2 is never registered, the game's config plays it
+	for _, board := range []string{"", "level1", "level2"} {
+		for playerId, body := range map[string]string{"1": `{"score": 500, "playerName": "goose"}`, "2": `{"score": 300, "playerName": "duck"}`} {
+			response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: board, PlayerId: playerId}, nil, body)
+			if response.StatusCode != 201 {
+				t.Fatalf("want %v, got %v", 201, response.StatusCode)
+			}
+		}
+	}
+
+	response = handler.BanPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, "")
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	for _, board := range []string{"", "level1", "level2"} {
+		ranks := getTestBoardRanks(t, handler, board)
+		if len(ranks) != 1 || ranks[0].PlayerName != "duck" {
+			t.Errorf("board %q: want the banned player's scores removed, got %v", board, ranks)
+		}
+	}
+}
+
+func TestBanPlayer(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	putTestScores(t, handler, "1", `{"score": 500, "playerName": "goose"}`)
+	putTestScores(t, handler, "2", `{"score": 300, "playerName": "duck"}`)
+
+	response := handler.BanPlayer(ctx, apiDefinition, `{"reason": "impossible scores"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	ranks := getTestRanks(t, handler, "Tetris")
+	if len(ranks) != 1 || ranks[0].PlayerName != "duck" {
+		t.Errorf("want the banned player's scores removed, got %v", ranks)
+	}
+
+	response = handler.PutScore(ctx, apiDefinition, nil, `{"score": 600, "playerName": "goose"}`)
+	if response.StatusCode != 403 {
+		t.Errorf("banned score: want %v, got %v", 403, response.StatusCode)
+	}
+	response = handler.PostSession(ctx, apiDefinition)
+	if response.StatusCode != 403 {
+		t.Errorf("banned session: want %v, got %v", 403, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Golf", PlayerId: "1"}, nil, `{"score": 600, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("another game: want %v, got %v", 201, response.StatusCode)
+	}
+
+	response = handler.GetBans(ctx, api.ApiDefinition{Game: "Tetris"})
+	var bans []models.Ban
+	if err := json.Unmarshal([]byte(response.Body), &bans); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(bans) != 1 || bans[0].PlayerId != "1" || bans[0].Reason != "impossible scores" {
+		t.Errorf("unexpected bans %v", bans)
+	}
+
+	response = handler.UnbanPlayer(ctx, apiDefinition)
+	if response.StatusCode != 204 {
+		t.Fatalf("want %v, got %v", 204, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, nil, `{"score": 600, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("after the ban is lifted: want %v, got %v", 201, response.StatusCode)
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func PatchProfile changes the player's display name, which every rank and score of the player shows from then on
+func (h Handler) PatchProfile(ctx context.Context, apiDefinition api.ApiDefinition, body string) events.APIGatewayProxyResponse {
+	config, ok, err := h.gameConfig(ctx, apiDefinition.Game)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	if response, ok := h.checkBan(ctx, apiDefinition.Game, apiDefinition.PlayerId); !ok {
+		return response
+	}
+	profile, err := models.NewProfile(config, apiDefinition.PlayerId, body)
+	if err != nil {
+		return h.ResponseBadRequest(err)
+	}
+	err = h.Database.PutProfile(ctx, profile)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put profile: %w", err))
+	}
+	// the profile is stored first so that scores submitted while the player's scores are renamed take the new name
+	partitions, err := h.Database.GetPlayerPartitions(ctx, apiDefinition.Game, apiDefinition.PlayerId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get player partitions: %w", err))
+	}
+	for _, partition := range partitions {
+		err := h.Database.RenamePlayer(ctx, partition, apiDefinition.PlayerId, profile.PlayerName)
+		if err != nil {
+			return h.ResponseInternalServerError(fmt.Errorf("Failed to rename player: %w", err))
+		}
+	}
+	out, err := json.Marshal(&profile)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal profile: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func withProfileName gives the score the name from the player's profile when they have one, in place of the name it was submitted with
+// names are stored with each score so that ranks are read without looking up the profile of every player ranked
+func (h Handler) withProfileName(ctx context.Context, score models.Score) (models.Score, error) {
+	profiles, err := h.Database.GetProfiles(ctx, models.PartitionGame(score.Game), []string{score.PlayerId})
+	if err != nil {
+		return models.Score{}, fmt.Errorf("Failed to get profiles: %w", err)
+	}
+	for _, profile := range profiles {
+		score.PlayerName = profile.PlayerName
+	}
+	return score, nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+)
+
+func TestPatchProfileRenamesRanks(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	putTestScores(t, handler, "1", `{"score": 500, "playerName": "goose"}`, `{"score": 100, "playerName": "goose"}`)
+	putTestScores(t, handler, "2", `{"score": 300, "playerName": "duck"}`)
+
+	response := handler.PatchProfile(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, `{"playerName": "swan"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	ranks := getTestRanks(t, handler, "Tetris")
+	names := []string{}
+	for _, rank := range ranks {
+		names = append(names, rank.PlayerName)
+	}
+	want := []string{"swan", "duck", "swan"}
+	if len(names) != len(want) {
+		t.Fatalf("want %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("want %v, got %v", want, names)
+		}
+	}
+}
+
+func TestPatchProfileRenamesUnregisteredBoards(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	for _, board := range []string{"", "level1"} {
+		response := handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: board, PlayerId: "1"}, nil, `{"score": 500, "playerName": "goose"}`)
+		if response.StatusCode != 201 {
+			t.Fatalf("want %v, got %v", 201, response.StatusCode)
+		}
+	}
+
+	response := handler.PatchProfile(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, `{"playerName": "swan"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	for _, board := range []string{"", "level1"} {
+		ranks := getTestBoardRanks(t, handler, board)
+		if len(ranks) != 1 || ranks[0].PlayerName != "swan" {
+			t.Errorf("board %q: want the profile's name, got %v", board, ranks)
+		}
+	}
+}
+
+func TestPatchProfileNamesLaterScores(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris", Board: "level1"}, `{}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", Board: "level1", PlayerId: "1"}, nil, `{"score": 500, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+
+	response = handler.PatchProfile(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, `{"playerName": "swan"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	putTestScores(t, handler, "1", `{"score": 100, "playerName": "goose"}`)
+
+	for _, board := range []string{"", "level1"} {
+		ranks := getTestBoardRanks(t, handler, board)
+		if len(ranks) != 1 || ranks[0].PlayerName != "swan" {
+			t.Errorf("board %q: want the profile's name, got %v", board, ranks)
+		}
+	}
+}
+
+func TestPatchProfileWithUnknownGame(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.RejectUnknownGames = true
+	response := handler.PatchProfile(context.Background(), api.ApiDefinition{Game: "Pong", PlayerId: "1"}, `{"playerName": "swan"}`)
+	if response.StatusCode != 404 {
+		t.Errorf("want %v, got %v", 404, response.StatusCode)
+	}
+}
+
+func TestPatchProfileWithoutName(t *testing.T) {
+	handler := createTestAdminHandler()
+	response := handler.PatchProfile(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, `{}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// func checkRules returns the first of the game's rules the score breaks, or an empty string when it breaks none
+// the submission counter is only read when the game limits submissions, and the player's best only when it limits improvement
+func (h Handler) checkRules(ctx context.Context, config models.GameConfig, score models.Score) (string, error) {
+	if reason := config.CheckBounds(score); reason != "" {
+		return reason, nil
+	}
+
+	if config.MaxSubmissionsPerMinute > 0 {
+		key, expiry := models.SubmissionCounter(score, time.Now())
+		submissions, err := h.Database.Increment(ctx, key, expiry)
+		if err != nil {
+			return "", fmt.Errorf("Failed to count submissions: %w", err)
+		}
+		if reason := config.CheckSubmissionRate(submissions); reason != "" {
+			return reason, nil
+		}
+	}
+
+	if config.MaxImprovement != nil {
+		best, err := h.Database.GetTopPlayerScores(ctx, models.PlayerScoreRequest{
+			PlayerId:     score.PlayerId,
+			ScoreRequest: models.ScoreRequest{Game: score.Game, Limit: 1, Order: config.Order},
+		})
+		if err != nil {
+			return "", fmt.Errorf("Failed to get previous best: %w", err)
+		}
+		// a player's first score has nothing to improve on
+		if len(best) > 0 {
+			if reason := config.CheckImprovement(score, best[0].Score); reason != "" {
+				return reason, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// func quarantineScore holds the score back from the leaderboards, responding that it was accepted but not yet recorded
+func (h Handler) quarantineScore(ctx context.Context, score models.Score, reason string) events.APIGatewayProxyResponse {
+	quarantined, err := models.NewQuarantinedScore(score, reason)
+	if err != nil {
+		return h.ResponseInternalServerError(err)
+	}
+	err = h.Database.PutQuarantinedScore(ctx, quarantined)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to quarantine score: %w", err))
+	}
+	return h.ResponseAccepted()
+}
+
+func (h Handler) GetQuarantinedScores(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	scores, err := h.Database.GetQuarantinedScores(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board))
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get quarantined scores: %w", err))
+	}
+	out, err := json.Marshal(&scores)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal quarantined scores: %w", err))
+	}
+	return h.ResponseOk(string(out))
+}
+
+// func ApproveScore records a quarantined score on the leaderboards it was submitted to, as the game is configured now
+func (h Handler) ApproveScore(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	quarantined, ok, err := h.Database.GetQuarantinedScore(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board), apiDefinition.ScoreId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get quarantined score: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	config, ok, err := h.boardConfig(ctx, apiDefinition)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	// the player may have been banned while the score was held back, and a ban keeps their scores out of every leaderboard
+	if response, ok := h.checkBan(ctx, apiDefinition.Game, quarantined.PlayerId); !ok {
+		return response
+	}
+
+	// the player may have been renamed while the score was held back
+	score, err := h.withProfileName(ctx, quarantined.Approved(config))
+	if err != nil {
+		return h.ResponseInternalServerError(err)
+	}
+	err = h.Database.PutScore(ctx, score)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to put score: %w", err))
+	}
+	err = h.Database.DeleteQuarantinedScore(ctx, quarantined.Game, quarantined.Id)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete quarantined score: %w", err))
+	}
+	return h.ResponseCreated()
+}
+
+func (h Handler) DiscardScore(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	err := h.Database.DeleteQuarantinedScore(ctx, models.BoardPartition(apiDefinition.Game, apiDefinition.Board), apiDefinition.ScoreId)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to delete quarantined score: %w", err))
+	}
+	return h.ResponseNoContent()
+}
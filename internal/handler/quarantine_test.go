@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func getTestQuarantine(t *testing.T, handler Handler, game string) []models.QuarantinedScore {
+	t.Helper()
+	response := handler.GetQuarantinedScores(context.Background(), api.ApiDefinition{Game: game})
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	var scores []models.QuarantinedScore
+	if err := json.Unmarshal([]byte(response.Body), &scores); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return scores
+}
+
+func TestPutScoreNegativeByDefault(t *testing.T) {
+	handler := createTestHandler()
+	response := handler.PutScore(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": -5, "playerName": "goose"}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestPutScoreMaxImprovement(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxImprovement": 100}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+
+	response = handler.PutScore(ctx, apiDefinition, nil, `{"score": 500, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("first score: want %v, got %v", 201, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, nil, `{"score": 600, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("within the improvement: want %v, got %v", 201, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, nil, `{"score": 701, "playerName": "goose"}`)
+	if response.StatusCode != 400 {
+		t.Errorf("beyond the improvement: want %v, got %v", 400, response.StatusCode)
+	}
+}
+
+func TestPutScoreMaxSubmissionsPerMinute(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxSubmissionsPerMinute": 2}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	for i, want := range []int{201, 201, 400} {
+		response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 10, "playerName": "goose"}`)
+		if response.StatusCode != want {
+			t.Errorf("submission %v: want %v, got %v", i+1, want, response.StatusCode)
+		}
+	}
+	// another player has their own count
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, nil, `{"score": 10, "playerName": "duck"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("another player: want %v, got %v", 201, response.StatusCode)
+	}
+}
+
+func TestQuarantineThenApprove(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxScore": 1000, "quarantine": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 5000, "playerName": "goose"}`)
+	if response.StatusCode != 202 {
+		t.Fatalf("want %v, got %v", 202, response.StatusCode)
+	}
+
+	response = handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10"})
+	if response.StatusCode != 200 || response.Body != "[]" {
+		t.Errorf("want no ranks while quarantined, got %v %v", response.StatusCode, response.Body)
+	}
+
+	quarantined := getTestQuarantine(t, handler, "Tetris")
+	if len(quarantined) != 1 {
+		t.Fatalf("want %v, got %v", 1, len(quarantined))
+	}
+	if quarantined[0].Score != 5000 || quarantined[0].PlayerId != "1" || quarantined[0].Reason == "" {
+		t.Errorf("unexpected quarantined score %+v", quarantined[0])
+	}
+
+	response = handler.ApproveScore(ctx, api.ApiDefinition{Game: "Tetris", ScoreId: quarantined[0].Id})
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+	if got := getTestQuarantine(t, handler, "Tetris"); len(got) != 0 {
+		t.Errorf("want an empty quarantine, got %v", got)
+	}
+
+	response = handler.GetTopRanks(ctx, api.ApiDefinition{Game: "Tetris"}, map[string]string{"limit": "10"})
+	var ranks models.Ranks
+	if err := json.Unmarshal([]byte(response.Body), &ranks); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(ranks) != 1 || ranks[0].Score != 5000 {
+		t.Errorf("want the approved score ranked, got %v", ranks)
+	}
+
+	response = handler.ApproveScore(ctx, api.ApiDefinition{Game: "Tetris", ScoreId: quarantined[0].Id})
+	if response.StatusCode != 404 {
+		t.Errorf("approving twice: want %v, got %v", 404, response.StatusCode)
+	}
+}
+
+func TestQuarantineThenBan(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxScore": 1000, "quarantine": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 5000, "playerName": "goose"}`)
+	if response.StatusCode != 202 {
+		t.Fatalf("want %v, got %v", 202, response.StatusCode)
+	}
+	quarantined := getTestQuarantine(t, handler, "Tetris")
+	if len(quarantined) != 1 {
+		t.Fatalf("want %v, got %v", 1, len(quarantined))
+	}
+
+	response = handler.BanPlayer(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, "")
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.ApproveScore(ctx, api.ApiDefinition{Game: "Tetris", ScoreId: quarantined[0].Id})
+	if response.StatusCode != 403 {
+		t.Errorf("want %v, got %v", 403, response.StatusCode)
+	}
+	if ranks := getTestRanks(t, handler, "Tetris"); len(ranks) != 0 {
+		t.Errorf("want the banned player's score kept out of the ranks, got %v", ranks)
+	}
+}
+
+func TestQuarantineThenDiscard(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"maxScore": 1000, "quarantine": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 5000, "playerName": "goose"}`)
+	if response.StatusCode != 202 {
+		t.Fatalf("want %v, got %v", 202, response.StatusCode)
+	}
+	quarantined := getTestQuarantine(t, handler, "Tetris")
+	if len(quarantined) != 1 {
+		t.Fatalf("want %v, got %v", 1, len(quarantined))
+	}
+
+	response = handler.DiscardScore(ctx, api.ApiDefinition{Game: "Tetris", ScoreId: quarantined[0].Id})
+	if response.StatusCode != 204 {
+		t.Errorf("want %v, got %v", 204, response.StatusCode)
+	}
+	if got := getTestQuarantine(t, handler, "Tetris"); len(got) != 0 {
+		t.Errorf("want an empty quarantine, got %v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+const (
+	defaultPlayerRateLimit = "30/60"
+	defaultIpRateLimit     = "120/60"
+	// reads are allowed more generously than writes, as a single page of a game may read several leaderboards
+	defaultIpReadRateLimit = "300/60"
+)
+
+// func rateLimitFromEnv reads a rate limit from the env, which is the default when unset
+func rateLimitFromEnv(key string, defaultLimit string) (models.RateLimit, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultLimit
+	}
+	limit, err := models.ParseRateLimit(value)
+	if err != nil {
+		return models.RateLimit{}, fmt.Errorf("Failed to parse %v: %w", key, err)
+	}
+	return limit, nil
+}
+
+// func RateLimit takes a token from the bucket of the request's source ip and the bucket of the player it is for, when the bool is false the request must be refused with the response
+// reads take a token only from a bucket of their source ip kept for reads, and requests made by an admin are not limited
+func (h Handler) RateLimit(ctx context.Context, apiDefinition api.ApiDefinition, httpMethod string, caller models.ApiKey, sourceIp string) (events.APIGatewayProxyResponse, bool) {
+	if caller.Role.Includes(models.RoleAdmin) {
+		return events.APIGatewayProxyResponse{}, true
+	}
+	type bucket struct {
+		key   string
+		limit models.RateLimit
+	}
+	var buckets []bucket
+	if !isWriteMethod(httpMethod) {
+		if sourceIp != "" && h.IpReadRateLimit.Enabled() {
+			buckets = append(buckets, bucket{key: models.IpReadRateLimitKey(sourceIp), limit: h.IpReadRateLimit})
+		}
+	} else if sourceIp != "" && h.IpRateLimit.Enabled() {
+		buckets = append(buckets, bucket{key: models.IpRateLimitKey(sourceIp), limit: h.IpRateLimit})
+	}
+	if apiDefinition.PlayerId != "" && h.PlayerRateLimit.Enabled() {
+		buckets = append(buckets, bucket{key: models.PlayerRateLimitKey(apiDefinition.Game, apiDefinition.PlayerId), limit: h.PlayerRateLimit})
+	}
+
+	now := time.Now()
+	for _, b := range buckets {
+		if response, ok := h.takeToken(ctx, b.key, b.limit, now); !ok {
+			return response, false
+		}
+	}
+	return events.APIGatewayProxyResponse{}, true
+}
+
+func isWriteMethod(httpMethod string) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// func takePlayerToken takes a token from the bucket of a player named in the body of a request rather than its path, which RateLimit cannot see
+func (h Handler) takePlayerToken(ctx context.Context, caller models.ApiKey, game string, playerId string) (events.APIGatewayProxyResponse, bool) {
+	if caller.Role.Includes(models.RoleAdmin) || !h.PlayerRateLimit.Enabled() {
+		return events.APIGatewayProxyResponse{}, true
+	}
+	return h.takeToken(ctx, models.PlayerRateLimitKey(game, playerId), h.PlayerRateLimit, time.Now())
+}
+
+func (h Handler) takeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (events.APIGatewayProxyResponse, bool) {
+	taken, ok, err := h.Database.TakeToken(ctx, key, limit, now)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to take rate limit token: %w", err)), false
+	}
+	if !ok {
+		return h.ResponseTooManyRequests(limit.RetryAfter(taken)), false
+	}
+	return events.APIGatewayProxyResponse{}, true
+}
+
+// func ResponseTooManyRequests tells the client how many whole seconds to wait before trying again
+func (h Handler) ResponseTooManyRequests(retryAfter time.Duration) events.APIGatewayProxyResponse {
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusTooManyRequests,
+		Headers:    map[string]string{"Retry-After": strconv.Itoa(seconds)},
+		Body:       "Too many requests",
+	}
+}
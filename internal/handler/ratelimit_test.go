@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+func TestRateLimitPlayer(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.PlayerRateLimit = models.RateLimit{Burst: 1, Rate: 0.1}
+	ctx := context.Background()
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+
+	if _, ok := handler.RateLimit(ctx, apiDefinition, "PUT", models.ApiKey{}, ""); !ok {
+		t.Fatalf("want the first request allowed")
+	}
+	response, ok := handler.RateLimit(ctx, apiDefinition, "PUT", models.ApiKey{}, "")
+	if ok || response.StatusCode != 429 {
+		t.Fatalf("want %v, got %v", 429, response.StatusCode)
+	}
+	if got := response.Headers["Retry-After"]; got != "10" {
+		t.Errorf("want %v, got %v", "10", got)
+	}
+	if _, ok := handler.RateLimit(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, "PUT", models.ApiKey{}, ""); !ok {
+		t.Errorf("want another player's request allowed")
+	}
+}
+
+func TestRateLimitIp(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.IpRateLimit = models.RateLimit{Burst: 1, Rate: 1}
+	ctx := context.Background()
+	apiDefinition := api.ApiDefinition{Game: "Tetris"}
+
+	if _, ok := handler.RateLimit(ctx, apiDefinition, "PUT", models.ApiKey{}, "192.0.2.1"); !ok {
+		t.Fatalf("want the first request allowed")
+	}
+	if response, ok := handler.RateLimit(ctx, apiDefinition, "PUT", models.ApiKey{}, "192.0.2.1"); ok || response.StatusCode != 429 {
+		t.Errorf("want %v, got %v", 429, response.StatusCode)
+	}
+	if _, ok := handler.RateLimit(ctx, apiDefinition, "PUT", models.ApiKey{}, "192.0.2.2"); !ok {
+		t.Errorf("want another ip's request allowed")
+	}
+	if _, ok := handler.RateLimit(ctx, apiDefinition, "PUT", adminTokenKey, "192.0.2.1"); !ok {
+		t.Errorf("want an admin's request allowed")
+	}
+}
+
+func TestRateLimitReads(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.IpRateLimit = models.RateLimit{Burst: 1, Rate: 0.1}
+	handler.IpReadRateLimit = models.RateLimit{Burst: 3, Rate: 0.1}
+	ctx := context.Background()
+	apiDefinition, err := api.EventPathToApiDefinition("/Tetris/ranks")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+
+	for range 3 {
+		if _, ok := handler.RateLimit(ctx, apiDefinition, "GET", models.ApiKey{}, "192.0.2.1"); !ok {
+			t.Fatalf("want the reads within the limit allowed")
+		}
+	}
+	response, ok := handler.RateLimit(ctx, apiDefinition, "GET", models.ApiKey{}, "192.0.2.1")
+	if ok || response.StatusCode != 429 {
+		t.Fatalf("want %v, got %v", 429, response.StatusCode)
+	}
+	if got := response.Headers["Retry-After"]; got != "10" {
+		t.Errorf("want %v, got %v", "10", got)
+	}
+	if _, ok := handler.RateLimit(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, "PUT", models.ApiKey{}, "192.0.2.1"); !ok {
+		t.Errorf("want a write allowed after the reads")
+	}
+	if _, ok := handler.RateLimit(ctx, apiDefinition, "GET", models.ApiKey{}, "192.0.2.2"); !ok {
+		t.Errorf("want another ip's read allowed")
+	}
+}
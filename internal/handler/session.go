@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+const SessionTokenHeader = "X-Session-Token"
+
+// sessionNoncePrefix keeps session ids apart from the signature nonces chosen by clients, which share the nonce store
+const sessionNoncePrefix = "#session|"
+
+// func PostSession starts a session for the player, the token it responds with is sent back with the score when play ends
+func (h Handler) PostSession(ctx context.Context, apiDefinition api.ApiDefinition) events.APIGatewayProxyResponse {
+	_, ok, err := h.gameConfig(ctx, apiDefinition.Game)
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to get game config: %w", err))
+	}
+	if !ok {
+		return h.ResponseNotFound()
+	}
+	if response, ok := h.checkBan(ctx, apiDefinition.Game, apiDefinition.PlayerId); !ok {
+		return response
+	}
+	if h.SessionSecret == "" {
+		return h.ResponseInternalServerError(errors.New("No session secret configured"))
+	}
+
+	session, err := models.NewSession(apiDefinition.Game, apiDefinition.PlayerId, time.Now())
+	if err != nil {
+		return h.ResponseInternalServerError(err)
+	}
+	token, err := session.Token(h.SessionSecret)
+	if err != nil {
+		return h.ResponseInternalServerError(err)
+	}
+
+	type sessionResponse struct {
+		Token   string `json:"token"`
+		Start   int    `json:"start"`
+		Expires int    `json:"expires"`
+	}
+	out, err := json.Marshal(sessionResponse{Token: token, Start: session.Start, Expires: session.Expiry()})
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to marshal session: %w", err))
+	}
+	return h.ResponseCreatedWith(string(out))
+}
+
+// func useSession checks the session token sent with a score and marks the session as used
+// the session is used up even when the score is refused as implausible, so a cheating client cannot retry with a smaller score
+func (h Handler) useSession(ctx context.Context, config models.GameConfig, score models.Score, headers map[string]string) (events.APIGatewayProxyResponse, bool) {
+	if h.SessionSecret == "" {
+		return h.ResponseInternalServerError(errors.New("No session secret configured")), false
+	}
+	session, err := models.ParseSessionToken(h.SessionSecret, header(headers, SessionTokenHeader))
+	if err != nil {
+		h.Logger.Warn(fmt.Sprintf("Refused session token for game %q: %v", config.Game, err))
+		return h.ResponseUnauthorized(), false
+	}
+	now := time.Now()
+	err = session.Check(score, now)
+	if err != nil {
+		h.Logger.Warn(fmt.Sprintf("Refused session %q for game %q: %v", session.Id, config.Game, err))
+		return h.ResponseUnauthorized(), false
+	}
+
+	// the session is used up on the game rather than the board, so that it cannot be used again on another board
+	claimed, err := h.Database.ClaimNonce(ctx, models.PartitionGame(config.Game), sessionNoncePrefix+session.Id, session.Expiry())
+	if err != nil {
+		return h.ResponseInternalServerError(fmt.Errorf("Failed to use session: %w", err)), false
+	}
+	if !claimed {
+		h.Logger.Warn(fmt.Sprintf("Refused reused session %q for game %q", session.Id, config.Game))
+		return h.ResponseUnauthorized(), false
+	}
+
+	// the score was reached by the time it says it was achieved, which may be before it was received
+	err = session.CheckRate(score, config.MaxScoreRate, time.Unix(int64(score.Timestamp), 0))
+	if err != nil {
+		return h.ResponseBadRequest(err), false
+	}
+	return events.APIGatewayProxyResponse{}, true
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/api"
+)
+
+func postTestSession(t *testing.T, handler Handler, apiDefinition api.ApiDefinition) map[string]string {
+	t.Helper()
+	response := handler.PostSession(context.Background(), apiDefinition)
+	if response.StatusCode != 201 {
+		t.Fatalf("want %v, got %v", 201, response.StatusCode)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &session); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	return map[string]string{"x-session-token": session.Token}
+}
+
+func TestPutScoreWithSession(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"requireSessions": true}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	body := `{"score": 10, "playerName": "goose"}`
+
+	response = handler.PutScore(ctx, apiDefinition, map[string]string{}, body)
+	if response.StatusCode != 401 {
+		t.Errorf("without a session: want %v, got %v", 401, response.StatusCode)
+	}
+
+	session := postTestSession(t, handler, apiDefinition)
+	response = handler.PutScore(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "2"}, session, body)
+	if response.StatusCode != 401 {
+		t.Errorf("another player's session: want %v, got %v", 401, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, session, body)
+	if response.StatusCode != 201 {
+		t.Errorf("with a session: want %v, got %v", 201, response.StatusCode)
+	}
+	response = handler.PutScore(ctx, apiDefinition, session, body)
+	if response.StatusCode != 401 {
+		t.Errorf("reused session: want %v, got %v", 401, response.StatusCode)
+	}
+}
+
+func TestPutScoreWithImplausibleSession(t *testing.T) {
+	handler := createTestAdminHandler()
+	handler.SessionSecret = "server secret"
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"requireSessions": true, "maxScoreRate": 1}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+
+	session := postTestSession(t, handler, apiDefinition)
+	response = handler.PutScore(ctx, apiDefinition, session, `{"score": 100000, "playerName": "goose"}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+	// the refused score used up the session
+	response = handler.PutScore(ctx, apiDefinition, session, `{"score": 1, "playerName": "goose"}`)
+	if response.StatusCode != 401 {
+		t.Errorf("want %v, got %v", 401, response.StatusCode)
+	}
+}
+
+func TestSessionsWithoutSecret(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"requireSessions": true}`)
+	if response.StatusCode != 400 {
+		t.Errorf("want %v, got %v", 400, response.StatusCode)
+	}
+	response = handler.PostSession(ctx, api.ApiDefinition{Game: "Tetris", PlayerId: "1"})
+	if response.StatusCode != 500 {
+		t.Errorf("want %v, got %v", 500, response.StatusCode)
+	}
+}
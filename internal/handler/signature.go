@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+)
+
+// func ScorePath is the path signed for a score submission
+// it is built from the route rather than taken from the request, so a trailing slash or an api gateway stage does not change the signature
+func ScorePath(apiDefinition api.ApiDefinition) string {
+	return fmt.Sprintf("%v/%v/scores", gamePath(apiDefinition), apiDefinition.PlayerId)
+}
+
+// func BatchPath is the path signed for a batch of scores
+func BatchPath(apiDefinition api.ApiDefinition) string {
+	return fmt.Sprintf("%v/scores:batch", gamePath(apiDefinition))
+}
+
+// func gamePath is the path of the game or of the board the request is within, which the paths of its routes begin with
+func gamePath(apiDefinition api.ApiDefinition) string {
+	if apiDefinition.Board == "" {
+		return fmt.Sprintf("/%v", apiDefinition.Game)
+	}
+	return fmt.Sprintf("/%v/boards/%v", apiDefinition.Game, apiDefinition.Board)
+}
+
+// func verifyScoreSignature reports whether the headers carry a valid signature of the request made with the game's secret and a nonce not used before
+// the reason a signature is refused is logged rather than returned, so that clients cannot probe for it
+func (h Handler) verifyScoreSignature(ctx context.Context, config models.GameConfig, method string, path string, headers map[string]string, body string) (bool, error) {
+	signature, err := models.NewSignature(
+		header(headers, SignatureHeader),
+		header(headers, SignatureTimestampHeader),
+		header(headers, SignatureNonceHeader),
+	)
+	if err == nil {
+		err = signature.Verify(config.SigningSecret, method, path, body, time.Now())
+	}
+	if err != nil {
+		h.Logger.Warn(fmt.Sprintf("Refused score signature for game %q: %v", config.Game, err))
+		return false, nil
+	}
+
+	claimed, err := h.Database.ClaimNonce(ctx, config.Game, signature.Nonce, signature.NonceExpiry())
+	if err != nil {
+		return false, err
+	}
+	if !claimed {
+		h.Logger.Warn(fmt.Sprintf("Refused replayed score signature for game %q", config.Game))
+	}
+	return claimed, nil
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/api"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+const testSigningSecret = "0123456789abcdef"
+
+func signedHeaders(apiDefinition api.ApiDefinition, secret string, timestamp int64, nonce string, body string) map[string]string {
+	return map[string]string{
+		"x-signature":           models.Sign(secret, "PUT", ScorePath(apiDefinition), timestamp, nonce, body),
+		"x-signature-timestamp": strconv.FormatInt(timestamp, 10),
+		"x-signature-nonce":     nonce,
+	}
+}
+
+func TestPutScoreSigned(t *testing.T) {
+	handler := createTestAdminHandler()
+	ctx := context.Background()
+	response := handler.PutGame(ctx, api.ApiDefinition{Game: "Tetris"}, `{"signingSecret": "`+testSigningSecret+`"}`)
+	if response.StatusCode != 200 {
+		t.Fatalf("want %v, got %v", 200, response.StatusCode)
+	}
+
+	apiDefinition := api.ApiDefinition{Game: "Tetris", PlayerId: "1"}
+	body := `{"score": 10, "playerName": "goose"}`
+	now := time.Now().Unix()
+
+	type test struct {
+		name    string
+		headers map[string]string
+		body    string
+		want    int
+	}
+	testCases := []test{
+		{name: "unsigned", headers: map[string]string{}, body: body, want: 401},
+		{name: "wrong secret", headers: signedHeaders(apiDefinition, "fedcba9876543210", now, "n1", body), body: body, want: 401},
+		{name: "tampered body", headers: signedHeaders(apiDefinition, testSigningSecret, now, "n1", body), body: `{"score": 9999, "playerName": "goose"}`, want: 401},
+		{name: "stale", headers: signedHeaders(apiDefinition, testSigningSecret, now-3600, "n1", body), body: body, want: 401},
+		{name: "signed", headers: signedHeaders(apiDefinition, testSigningSecret, now, "n1", body), body: body, want: 201},
+		{name: "replayed", headers: signedHeaders(apiDefinition, testSigningSecret, now, "n1", body), body: body, want: 401},
+		{name: "new nonce", headers: signedHeaders(apiDefinition, testSigningSecret, now, "n2", body), body: body, want: 201},
+	}
+
+	for _, tc := range testCases {
+		response := handler.PutScore(ctx, apiDefinition, tc.headers, tc.body)
+		if response.StatusCode != tc.want {
+			t.Errorf("%v: want %v, got %v", tc.name, tc.want, response.StatusCode)
+		}
+	}
+}
+
+func TestPutScoreUnsignedGame(t *testing.T) {
+	handler := createTestAdminHandler()
+
+	response := handler.PutScore(context.Background(), api.ApiDefinition{Game: "Tetris", PlayerId: "1"}, nil, `{"score": 10, "playerName": "goose"}`)
+	if response.StatusCode != 201 {
+		t.Errorf("want %v, got %v", 201, response.StatusCode)
+	}
+}
@@ -0,0 +1,608 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// MemoryScoreDatabase keeps scores in process memory
+// it follows the same key design as the dynamodb table, so a player submitting the same score twice in the same second overwrites the earlier submission
+type MemoryScoreDatabase struct {
+	mu       sync.RWMutex
+	games    map[string]map[scoreKey]models.Score
+	configs  map[string]models.GameConfig
+	nonces   map[nonceKey]int
+	apiKeys  map[string]models.ApiKey
+	counters map[string]counter
+	buckets  map[string]bucket
+	// idempotency holds the responses to requests made with an Idempotency-Key by their scoped key
+	idempotency map[string]models.IdempotencyRecord
+	// quarantine holds each game's quarantined scores by id
+	quarantine map[string]map[string]models.QuarantinedScore
+	bans       map[playerKey]models.Ban
+	profiles   map[playerKey]models.Profile
+	groups     map[groupKey]models.Group
+	rankLimit  int
+}
+
+type scoreKey struct {
+	playerId  string
+	score     int
+	timestamp int
+}
+
+type nonceKey struct {
+	game  string
+	nonce string
+}
+
+// playerKey identifies a player within a game
+type playerKey struct {
+	game     string
+	playerId string
+}
+
+// groupKey identifies a group within a game
+type groupKey struct {
+	game string
+	id   string
+}
+
+type counter struct {
+	count  int
+	expiry int
+}
+
+type bucket struct {
+	models.TokenBucket
+	expiry int
+}
+
+func New() *MemoryScoreDatabase {
+	// the same single page limit as the dynamodb implementation, so the two behave the same when swapped
+	const memoryMaxRanksLimit = 1000
+
+	return &MemoryScoreDatabase{
+		games:       make(map[string]map[scoreKey]models.Score),
+		configs:     make(map[string]models.GameConfig),
+		nonces:      make(map[nonceKey]int),
+		apiKeys:     make(map[string]models.ApiKey),
+		counters:    make(map[string]counter),
+		buckets:     make(map[string]bucket),
+		idempotency: make(map[string]models.IdempotencyRecord),
+		quarantine:  make(map[string]map[string]models.QuarantinedScore),
+		bans:        make(map[playerKey]models.Ban),
+		profiles:    make(map[playerKey]models.Profile),
+		groups:      make(map[groupKey]models.Group),
+		rankLimit:   memoryMaxRanksLimit,
+	}
+}
+
+func (m *MemoryScoreDatabase) PutScore(ctx context.Context, score models.Score) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.putScore(score)
+	return nil
+}
+
+func (m *MemoryScoreDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, score := range scores {
+		m.putScore(score)
+	}
+	return nil
+}
+
+// func putScore stores the score, the caller must hold the lock
+func (m *MemoryScoreDatabase) putScore(score models.Score) {
+	game, ok := m.games[score.Game]
+	if !ok {
+		game = make(map[scoreKey]models.Score)
+		m.games[score.Game] = game
+	}
+	// only the fields the other backends store are kept, windows are selected by timestamp and the game settings come with each request
+	game[scoreKey{playerId: score.PlayerId, score: score.Score, timestamp: score.Timestamp}] = models.Score{
+		Game:       score.Game,
+		Score:      score.Score,
+		PlayerId:   score.PlayerId,
+		PlayerName: score.PlayerName,
+		Timestamp:  score.Timestamp,
+		ReceivedAt: score.ReceivedAt,
+		Metadata:   maps.Clone(score.Metadata),
+	}
+}
+
+func (m *MemoryScoreDatabase) GetTopPlayerScores(ctx context.Context, scoreRequest models.PlayerScoreRequest) ([]models.Score, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	start, end := scoreRequest.Period.Bounds()
+	scores := make([]models.Score, 0)
+	for k, score := range m.games[scoreRequest.Game] {
+		if k.playerId == scoreRequest.PlayerId && score.Timestamp >= start && score.Timestamp < end && score.Metadata.Matches(scoreRequest.Filter) {
+			scores = append(scores, score)
+		}
+	}
+	slices.SortFunc(scores, compareScores(scoreRequest.Order))
+
+	if scoreRequest.Limit > 0 && len(scores) > scoreRequest.Limit {
+		scores = scores[:scoreRequest.Limit]
+	}
+	return scores, nil
+}
+
+func (m *MemoryScoreDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRequest) (models.Ranks, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scores := m.rankedScores(ranksRequest)
+	limit := m.rankLimit
+	if ranksRequest.Limit > 0 {
+		limit = ranksRequest.Limit
+	}
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+
+	return toRanks(scores, 0), nil
+}
+
+func (m *MemoryScoreDatabase) GetRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scores := m.rankedScores(request.RanksRequest)
+	index := slices.IndexFunc(scores, func(s models.Score) bool {
+		return s.PlayerId == request.PlayerId && s.Score == request.Score && s.Timestamp == request.Timestamp
+	})
+	if index == -1 {
+		return models.Ranks{}, nil
+	}
+
+	start := max(0, index-request.Around)
+	end := min(len(scores), index+request.Around+1)
+	return toRanks(scores[start:end], start), nil
+}
+
+func (m *MemoryScoreDatabase) GetGame(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config, ok := m.configs[game]
+	return config, ok, nil
+}
+
+func (m *MemoryScoreDatabase) GetGames(ctx context.Context) ([]models.GameConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	configs := make([]models.GameConfig, 0, len(m.configs))
+	for _, config := range m.configs {
+		configs = append(configs, config)
+	}
+	slices.SortFunc(configs, func(a models.GameConfig, b models.GameConfig) int {
+		return cmp.Compare(a.Game, b.Game)
+	})
+	return configs, nil
+}
+
+func (m *MemoryScoreDatabase) PutGame(ctx context.Context, config models.GameConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.configs[config.Game] = config
+	return nil
+}
+
+func (m *MemoryScoreDatabase) DeleteGame(ctx context.Context, game string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.configs, game)
+	return nil
+}
+
+// func ClaimNonce records the nonce as used for the game until expiry, reporting false if it is already in use
+func (m *MemoryScoreDatabase) ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := int(time.Now().Unix())
+	key := nonceKey{game: game, nonce: nonce}
+	if m.nonces[key] > now {
+		return false, nil
+	}
+	// expired nonces are dropped as new ones are claimed, so the map holds only those within the signature window
+	for k, v := range m.nonces {
+		if v <= now {
+			delete(m.nonces, k)
+		}
+	}
+	m.nonces[key] = expiry
+	return true, nil
+}
+
+func (m *MemoryScoreDatabase) ReleaseNonce(ctx context.Context, game string, nonce string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.nonces, nonceKey{game: game, nonce: nonce})
+	return nil
+}
+
+// func ClaimIdempotencyKey stores the pending record unless its key is already in use, in which case the record using it is returned with false
+func (m *MemoryScoreDatabase) ClaimIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := int(time.Now().Unix())
+	if existing, ok := m.idempotency[record.Key]; ok && existing.Expires > now {
+		return existing, false, nil
+	}
+	for k, v := range m.idempotency {
+		if v.Expires <= now {
+			delete(m.idempotency, k)
+		}
+	}
+	m.idempotency[record.Key] = record
+	return record, true, nil
+}
+
+func (m *MemoryScoreDatabase) PutIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.idempotency[record.Key] = record
+	return nil
+}
+
+func (m *MemoryScoreDatabase) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.idempotency, key)
+	return nil
+}
+
+func (m *MemoryScoreDatabase) GetApiKey(ctx context.Context, id string) (models.ApiKey, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.apiKeys[id]
+	return key, ok, nil
+}
+
+func (m *MemoryScoreDatabase) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]models.ApiKey, 0, len(m.apiKeys))
+	for _, key := range m.apiKeys {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a models.ApiKey, b models.ApiKey) int {
+		return cmp.Or(cmp.Compare(a.Game, b.Game), cmp.Compare(a.Id, b.Id))
+	})
+	return keys, nil
+}
+
+func (m *MemoryScoreDatabase) PutApiKey(ctx context.Context, key models.ApiKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.apiKeys[key.Id] = key
+	return nil
+}
+
+func (m *MemoryScoreDatabase) DeleteApiKey(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.apiKeys, id)
+	return nil
+}
+
+// func Increment adds one to the counter and returns its new count, a counter past its expiry starts again from zero
+func (m *MemoryScoreDatabase) Increment(ctx context.Context, key string, expiry int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := int(time.Now().Unix())
+	c := m.counters[key]
+	if c.expiry <= now {
+		c = counter{}
+		// expired counters are dropped as they are found, in the same way as nonces
+		for k, v := range m.counters {
+			if v.expiry <= now {
+				delete(m.counters, k)
+			}
+		}
+	}
+	c.count++
+	c.expiry = expiry
+	m.counters[key] = c
+	return c.count, nil
+}
+
+func (m *MemoryScoreDatabase) TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, v := range m.buckets {
+		if v.expiry <= int(now.Unix()) {
+			delete(m.buckets, k)
+		}
+	}
+	taken, ok := limit.Take(m.buckets[key].TokenBucket, now)
+	if ok {
+		m.buckets[key] = bucket{TokenBucket: taken, expiry: limit.Expiry(taken)}
+	}
+	return taken, ok, nil
+}
+
+func (m *MemoryScoreDatabase) PutQuarantinedScore(ctx context.Context, score models.QuarantinedScore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.quarantine[score.Game]
+	if !ok {
+		game = make(map[string]models.QuarantinedScore)
+		m.quarantine[score.Game] = game
+	}
+	game[score.Id] = score
+	return nil
+}
+
+func (m *MemoryScoreDatabase) GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	score, ok := m.quarantine[game][id]
+	if !ok || score.Expires <= int(time.Now().Unix()) {
+		return models.QuarantinedScore{}, false, nil
+	}
+	return score, true, nil
+}
+
+// func GetQuarantinedScores returns the game's quarantined scores in the order they were submitted
+func (m *MemoryScoreDatabase) GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := int(time.Now().Unix())
+	scores := make([]models.QuarantinedScore, 0, len(m.quarantine[game]))
+	for _, score := range m.quarantine[game] {
+		if score.Expires > now {
+			scores = append(scores, score)
+		}
+	}
+	slices.SortFunc(scores, func(a models.QuarantinedScore, b models.QuarantinedScore) int {
+		return cmp.Or(cmp.Compare(a.Timestamp, b.Timestamp), cmp.Compare(a.Id, b.Id))
+	})
+	return scores, nil
+}
+
+func (m *MemoryScoreDatabase) DeleteQuarantinedScore(ctx context.Context, game string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.quarantine[game], id)
+	return nil
+}
+
+// func DeleteScore removes every submission of the score by the player
+func (m *MemoryScoreDatabase) DeleteScore(ctx context.Context, score models.Score) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.games[score.Game] {
+		if k.playerId == score.PlayerId && k.score == score.Score {
+			delete(m.games[score.Game], k)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryScoreDatabase) DeletePlayerScores(ctx context.Context, game string, playerId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.games[game] {
+		if k.playerId == playerId {
+			delete(m.games[game], k)
+		}
+	}
+	return nil
+}
+
+// func GetPlayerPartitions finds the game's partitions holding scores of the player by reading each of them
+func (m *MemoryScoreDatabase) GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	partitions := []string{}
+	for partition, scores := range m.games {
+		if models.PartitionGame(partition) != game {
+			continue
+		}
+		for k := range scores {
+			if k.playerId == playerId {
+				partitions = append(partitions, partition)
+				break
+			}
+		}
+	}
+	return partitions, nil
+}
+
+// func RenamePlayer sets the name shown on every score of the player in the game
+func (m *MemoryScoreDatabase) RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, score := range m.games[game] {
+		if k.playerId == playerId {
+			score.PlayerName = playerName
+			m.games[game][k] = score
+		}
+	}
+	return nil
+}
+
+func (m *MemoryScoreDatabase) PutBan(ctx context.Context, ban models.Ban) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bans[playerKey{game: ban.Game, playerId: ban.PlayerId}] = ban
+	return nil
+}
+
+func (m *MemoryScoreDatabase) GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ban, ok := m.bans[playerKey{game: game, playerId: playerId}]
+	return ban, ok, nil
+}
+
+func (m *MemoryScoreDatabase) GetBans(ctx context.Context, game string) ([]models.Ban, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bans := make([]models.Ban, 0)
+	for k, ban := range m.bans {
+		if k.game == game {
+			bans = append(bans, ban)
+		}
+	}
+	slices.SortFunc(bans, func(a models.Ban, b models.Ban) int {
+		return cmp.Compare(a.PlayerId, b.PlayerId)
+	})
+	return bans, nil
+}
+
+func (m *MemoryScoreDatabase) DeleteBan(ctx context.Context, game string, playerId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.bans, playerKey{game: game, playerId: playerId})
+	return nil
+}
+
+func (m *MemoryScoreDatabase) PutProfile(ctx context.Context, profile models.Profile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.profiles[playerKey{game: profile.Game, playerId: profile.PlayerId}] = profile
+	return nil
+}
+
+// func GetProfiles returns the profiles of those players in the game who have one, in no particular order
+func (m *MemoryScoreDatabase) GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profiles := make([]models.Profile, 0, len(playerIds))
+	for _, playerId := range playerIds {
+		if profile, ok := m.profiles[playerKey{game: game, playerId: playerId}]; ok {
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles, nil
+}
+
+// func PutGroup keeps a copy of the group's players, so the caller changing them afterwards does not change the stored group
+func (m *MemoryScoreDatabase) PutGroup(ctx context.Context, group models.Group) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group.PlayerIds = slices.Clone(group.PlayerIds)
+	m.groups[groupKey{game: group.Game, id: group.Id}] = group
+	return nil
+}
+
+func (m *MemoryScoreDatabase) GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, ok := m.groups[groupKey{game: game, id: id}]
+	group.PlayerIds = slices.Clone(group.PlayerIds)
+	return group, ok, nil
+}
+
+func (m *MemoryScoreDatabase) DeleteGroup(ctx context.Context, game string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.groups, groupKey{game: game, id: id})
+	return nil
+}
+
+// func rankedScores returns every score in the requested game and period matching the filter from best to worst by the requested order, the caller must hold the lock
+func (m *MemoryScoreDatabase) rankedScores(ranksRequest models.RanksRequest) []models.Score {
+	start, end := ranksRequest.Period.Bounds()
+	scores := make([]models.Score, 0, len(m.games[ranksRequest.Game]))
+	for _, score := range m.games[ranksRequest.Game] {
+		if score.Timestamp >= start && score.Timestamp < end && score.Metadata.Matches(ranksRequest.Filter) {
+			scores = append(scores, score)
+		}
+	}
+	slices.SortFunc(scores, compareScores(ranksRequest.Order))
+	if ranksRequest.UniquePlayers {
+		scores = bestPerPlayer(scores)
+	}
+	return scores
+}
+
+// func toRanks converts sorted scores into ranks, offset is the number of scores ranked before the first
+func toRanks(scores []models.Score, offset int) models.Ranks {
+	ranks := make(models.Ranks, 0, len(scores))
+	for i, score := range scores {
+		ranks = append(ranks, models.Rank{
+			Score:      score.Score,
+			Position:   offset + i + 1,
+			PlayerName: score.PlayerName,
+			Timestamp:  score.Timestamp,
+			Metadata:   score.Metadata,
+			ReceivedAt: score.ReceivedAt,
+			PlayerId:   score.PlayerId,
+		})
+	}
+	return ranks
+}
+
+// func bestPerPlayer keeps the first score of each player from sorted scores
+func bestPerPlayer(scores []models.Score) []models.Score {
+	seen := make(map[string]bool)
+	best := make([]models.Score, 0, len(scores))
+	for _, score := range scores {
+		if seen[score.PlayerId] {
+			continue
+		}
+		seen[score.PlayerId] = true
+		best = append(best, score)
+	}
+	return best
+}
+
+// func compareScores orders scores from best to worst, equal scores rank the earliest received first and then the earliest achieved
+// submissions at the same moment have no defined order in dynamodb, here they fall back to the player id so that results are stable
+func compareScores(order models.Order) func(a models.Score, b models.Score) int {
+	return func(a models.Score, b models.Score) int {
+		return cmp.Or(
+			order.Compare(a.Score, b.Score),
+			cmp.Compare(a.ReceivedAt, b.ReceivedAt),
+			cmp.Compare(a.Timestamp, b.Timestamp),
+			cmp.Compare(a.PlayerId, b.PlayerId),
+		)
+	}
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/indimeco/cheerleader/internal/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Database {
+		return New()
+	})
+}
+
+func TestGetTopRanksCeiling(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	for i := 1; i <= 1200; i++ {
+		err := m.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Goose", Game: "Comedy", Score: i})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+
+	ranks, err := m.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy", Limit: 5})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 5 {
+		t.Errorf("want %v, got %v", 5, len(ranks))
+	}
+
+	ranks, err = m.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1000 {
+		t.Errorf("want %v, got %v", 1000, len(ranks))
+	}
+	if ranks[0].Score != 1200 || ranks[999].Score != 201 {
+		t.Errorf("want ranks from %v to %v, got %v to %v", 1200, 201, ranks[0].Score, ranks[999].Score)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Goose", Game: "Racing", Score: i})
+		}()
+		go func() {
+			defer wg.Done()
+			m.GetTopRanks(ctx, models.RanksRequest{Game: "Racing"})
+		}()
+	}
+	wg.Wait()
+
+	ranks, err := m.GetTopRanks(ctx, models.RanksRequest{Game: "Racing"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 50 {
+		t.Errorf("want %v, got %v", 50, len(ranks))
+	}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// achievedAtSkew is how far ahead of the server's clock a client's clock may run, a score said to be achieved within it is taken as achieved when received
+const achievedAtSkew = 5 * time.Minute
+
+// func MaxAchievedAge is how long before it is received a score of the game may say it was achieved
+func (c GameConfig) MaxAchievedAge() time.Duration {
+	if c.MaxAchievedAgeSeconds == 0 {
+		return defaultMaxAchievedAge * time.Second
+	}
+	return time.Duration(c.MaxAchievedAgeSeconds) * time.Second
+}
+
+// func CheckAchievedAt returns the timestamp of a score the client says was achieved at achievedAt, which must be no older than the game allows and not in the future
+// the timestamp is shown with the score and enters it into the leaderboards of the periods it was achieved in, so this bounds how far a client can backdate it
+// equal scores are ordered by when they were received instead, so that backdating a score cannot rank it above those received before it
+func (c GameConfig) CheckAchievedAt(achievedAt int, received time.Time) (int, error) {
+	achieved := time.Unix(int64(achievedAt), 0)
+	if achieved.After(received.Add(achievedAtSkew)) {
+		return 0, fmt.Errorf("achievedAt %v is in the future", achievedAt)
+	}
+	if achieved.Before(received.Add(-c.MaxAchievedAge())) {
+		return 0, fmt.Errorf("achievedAt must be within %v seconds before the score is submitted", int(c.MaxAchievedAge().Seconds()))
+	}
+	return int(min(achieved.Unix(), received.Unix())), nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckAchievedAt(t *testing.T) {
+	config := NewGameConfig("tetris")
+	config.MaxAchievedAgeSeconds = 3600
+	received := time.Unix(1739253593, 0)
+
+	testCases := []struct {
+		achievedAt int
+		want       int
+	}{
+		{1739253593, 1739253593},
+		{1739253593 - 3600, 1739253593 - 3600},
+		// a client clock running slightly fast is taken as the time the score was received
+		{1739253593 + 60, 1739253593},
+	}
+	for _, tc := range testCases {
+		got, err := config.CheckAchievedAt(tc.achievedAt, received)
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+
+	for _, achievedAt := range []int{1739253593 - 3601, 1739253593 + 3600} {
+		_, err := config.CheckAchievedAt(achievedAt, received)
+		if err == nil {
+			t.Errorf("want error, got nil, achievedAt %v", achievedAt)
+		}
+	}
+}
+
+func TestMaxAchievedAgeDefault(t *testing.T) {
+	// configs stored before scores said when they were achieved have no age of their own
+	config := GameConfig{Game: "tetris"}
+	if got := config.MaxAchievedAge(); got != 24*time.Hour {
+		t.Errorf("want %v, got %v", 24*time.Hour, got)
+	}
+}
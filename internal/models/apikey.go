@@ -0,0 +1,142 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Role is what an api key may do, each role may also do everything the roles before it may
+type Role int
+
+const (
+	// RoleReader reads scores and ranks
+	RoleReader Role = iota + 1
+	// RoleSubmitter also submits scores
+	RoleSubmitter
+	// RoleAdmin also configures games and manages api keys
+	RoleAdmin
+)
+
+func (r Role) MarshalText() ([]byte, error) {
+	switch r {
+	case RoleReader:
+		return []byte("reader"), nil
+	case RoleSubmitter:
+		return []byte("submitter"), nil
+	case RoleAdmin:
+		return []byte("admin"), nil
+	}
+	return nil, fmt.Errorf("Unknown role %d", r)
+}
+
+func (r *Role) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "reader":
+		*r = RoleReader
+	case "submitter":
+		*r = RoleSubmitter
+	case "admin":
+		*r = RoleAdmin
+	default:
+		return fmt.Errorf("Unknown role %q, expected reader, submitter or admin", text)
+	}
+	return nil
+}
+
+// func Includes reports whether the role may do what the required role may
+func (r Role) Includes(required Role) bool {
+	return r >= required
+}
+
+// AllGames scopes an api key to every game, game names are alphanumeric so no game can be named it
+const AllGames = "*"
+
+var keyGamePattern = regexp.MustCompile(`^([\w\d]+|\*)$`)
+
+// ApiKey grants its role over a single game, or over every game when its game is AllGames
+// only a hash of the key is stored, the key itself is shown once when it is created
+type ApiKey struct {
+	Id      string `json:"id" dynamodbav:"kid"`
+	Game    string `json:"game" dynamodbav:"kgame"`
+	Role    Role   `json:"role" dynamodbav:"role"`
+	Name    string `json:"name" dynamodbav:"name"`
+	Created int    `json:"created" dynamodbav:"created"`
+	Hash    string `json:"-" dynamodbav:"hash"`
+}
+
+// func NewApiKey reads the key to create from a request body and generates its secret, returning the key and the token a client authenticates with
+func NewApiKey(requestBody string) (ApiKey, string, error) {
+	type newApiKeyRequestBody struct {
+		Game string `json:"game"`
+		Role Role   `json:"role"`
+		Name string `json:"name"`
+	}
+	var body newApiKeyRequestBody
+	err := json.Unmarshal([]byte(requestBody), &body)
+	if err != nil {
+		return ApiKey{}, "", fmt.Errorf("Failed to parse api key: %w", err)
+	}
+	if !keyGamePattern.MatchString(body.Game) {
+		return ApiKey{}, "", errors.New("game must be the name of a game or *")
+	}
+	if body.Role == 0 {
+		return ApiKey{}, "", errors.New("role must be one of reader, submitter or admin")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return ApiKey{}, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return ApiKey{}, "", err
+	}
+	token := id + "." + secret
+
+	return ApiKey{
+		Id:      id,
+		Game:    body.Game,
+		Role:    body.Role,
+		Name:    body.Name,
+		Created: int(time.Now().Unix()),
+		Hash:    hashToken(token),
+	}, token, nil
+}
+
+// func ApiKeyId reads the id from an api key token, which is sent as the id and the secret joined by a dot
+func ApiKeyId(token string) (string, bool) {
+	id, _, ok := strings.Cut(token, ".")
+	return id, ok && id != ""
+}
+
+// func Matches reports whether token is this key's token
+func (k ApiKey) Matches(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(k.Hash)) == 1
+}
+
+// func Allows reports whether the key may act with the role on the game
+func (k ApiKey) Allows(game string, role Role) bool {
+	return k.Role.Includes(role) && (k.Game == AllGames || k.Game == game)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewApiKey(t *testing.T) {
+	key, token, err := NewApiKey(`{"game": "tetris", "role": "submitter", "name": "ios client"}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if key.Game != "tetris" || key.Role != RoleSubmitter || key.Name != "ios client" {
+		t.Errorf("unexpected key %+v", key)
+	}
+	id, ok := ApiKeyId(token)
+	if !ok || id != key.Id {
+		t.Errorf("want %v, got %v", key.Id, id)
+	}
+	if !key.Matches(token) {
+		t.Errorf("want the key to match its token")
+	}
+	if key.Matches(key.Id + ".wrong") {
+		t.Errorf("want the key not to match another token")
+	}
+
+	out, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	var fields map[string]any
+	json.Unmarshal(out, &fields)
+	if _, ok := fields["hash"]; ok {
+		t.Errorf("want the hash left out of json, got %s", out)
+	}
+}
+
+func TestNewApiKeyInvalid(t *testing.T) {
+	testCases := []string{
+		`{"game": "tetris"}`,
+		`{"game": "tetris", "role": "owner"}`,
+		`{"game": "", "role": "reader"}`,
+		`{"game": "te/tris", "role": "reader"}`,
+		`not json`,
+	}
+
+	for _, body := range testCases {
+		_, _, err := NewApiKey(body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
+
+func TestApiKeyAllows(t *testing.T) {
+	type test struct {
+		key  ApiKey
+		game string
+		role Role
+		want bool
+	}
+	testCases := []test{
+		{key: ApiKey{Game: "tetris", Role: RoleReader}, game: "tetris", role: RoleReader, want: true},
+		{key: ApiKey{Game: "tetris", Role: RoleReader}, game: "tetris", role: RoleSubmitter, want: false},
+		{key: ApiKey{Game: "tetris", Role: RoleSubmitter}, game: "tetris", role: RoleReader, want: true},
+		{key: ApiKey{Game: "tetris", Role: RoleAdmin}, game: "golf", role: RoleReader, want: false},
+		{key: ApiKey{Game: AllGames, Role: RoleSubmitter}, game: "golf", role: RoleSubmitter, want: true},
+		{key: ApiKey{Game: AllGames, Role: RoleSubmitter}, game: AllGames, role: RoleAdmin, want: false},
+		{key: ApiKey{Game: AllGames, Role: RoleAdmin}, game: AllGames, role: RoleAdmin, want: true},
+		{key: ApiKey{Game: "tetris", Role: RoleAdmin}, game: AllGames, role: RoleAdmin, want: false},
+	}
+
+	for _, tc := range testCases {
+		got := tc.key.Allows(tc.game, tc.role)
+		if got != tc.want {
+			t.Errorf("want %v, got %v, key %+v game %v role %v", tc.want, got, tc.key, tc.game, tc.role)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const maxBanReasonLength = 256
+
+// Ban refuses a player's scores for a game until it is lifted
+type Ban struct {
+	Game     string `json:"game"`
+	PlayerId string `json:"playerId"`
+	Reason   string `json:"reason"`
+	Created  int    `json:"created"`
+}
+
+// func NewBan reads the reason for banning the player from a request body, which may be empty
+func NewBan(game string, playerId string, requestBody string) (Ban, error) {
+	type newBanRequestBody struct {
+		Reason string `json:"reason"`
+	}
+	var body newBanRequestBody
+	if requestBody != "" {
+		err := json.Unmarshal([]byte(requestBody), &body)
+		if err != nil {
+			return Ban{}, fmt.Errorf("Failed to parse ban: %w", err)
+		}
+	}
+	if len(body.Reason) > maxBanReasonLength {
+		return Ban{}, errors.New("Ban reason was too long")
+	}
+	return Ban{
+		Game:     game,
+		PlayerId: playerId,
+		Reason:   body.Reason,
+		Created:  int(time.Now().Unix()),
+	}, nil
+}
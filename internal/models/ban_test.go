@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewBan(t *testing.T) {
+	ban, err := NewBan("tetris", "1", `{"reason": "impossible scores"}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if ban.Game != "tetris" || ban.PlayerId != "1" || ban.Reason != "impossible scores" || ban.Created == 0 {
+		t.Errorf("unexpected ban %+v", ban)
+	}
+
+	ban, err = NewBan("tetris", "1", "")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if ban.Reason != "" {
+		t.Errorf("want no reason, got %v", ban.Reason)
+	}
+}
+
+func TestNewBanInvalid(t *testing.T) {
+	testCases := []string{
+		`not json`,
+		`{"reason": "` + strings.Repeat("a", maxBanReasonLength+1) + `"}`,
+	}
+
+	for _, body := range testCases {
+		_, err := NewBan("tetris", "1", body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
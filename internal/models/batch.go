@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// playerIdPattern matches the player ids the api paths accept, so a batch cannot store a score no path could read
+var playerIdPattern = regexp.MustCompile(`^[\w\d]+$`)
+
+// BatchScore is one score of a batch submission, Body is the score as it would be submitted alone
+type BatchScore struct {
+	PlayerId string `json:"playerId"`
+	// SessionToken stands in for the session header of a single submission, as each score of the batch may be from a different session
+	SessionToken string `json:"sessionToken"`
+	Body         string `json:"-"`
+}
+
+// func NewBatch splits a batch submission into its scores, each of which is then checked as if it were submitted alone
+// a batch which is not a list of scores is refused whole, a score of it with no valid player is refused when it is checked
+func NewBatch(config GameConfig, requestBody string) ([]BatchScore, error) {
+	var items []json.RawMessage
+	err := json.Unmarshal([]byte(requestBody), &items)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse batch: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("Expected at least one score")
+	}
+	if len(items) > config.BatchLimit() {
+		return nil, fmt.Errorf("A batch must have at most %v scores", config.BatchLimit())
+	}
+
+	batch := make([]BatchScore, 0, len(items))
+	for i, item := range items {
+		var score BatchScore
+		err := json.Unmarshal(item, &score)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse score %v of batch: %w", i, err)
+		}
+		score.Body = string(item)
+		batch = append(batch, score)
+	}
+	return batch, nil
+}
+
+// func Validate checks the player of a batch score, which a single submission takes from its path instead
+func (b BatchScore) Validate() error {
+	if !playerIdPattern.MatchString(b.PlayerId) {
+		return errors.New("Expected a playerId of letters, digits and underscores")
+	}
+	return nil
+}
+
+// BatchResult reports what became of one score of a batch, by the status its submission alone would have been responded to with
+type BatchResult struct {
+	Index    int    `json:"index"`
+	PlayerId string `json:"playerId"`
+	Status   int    `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewBatch(t *testing.T) {
+	config := NewGameConfig("tetris")
+	body := `[{"playerId": "1", "score": 505, "sessionToken": "abc"}, {"playerId": "2", "score": 7}]`
+	batch, err := NewBatch(config, body)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := []BatchScore{
+		{PlayerId: "1", SessionToken: "abc", Body: `{"playerId": "1", "score": 505, "sessionToken": "abc"}`},
+		{PlayerId: "2", Body: `{"playerId": "2", "score": 7}`},
+	}
+	if diff := cmp.Diff(want, batch); diff != "" {
+		t.Errorf("NewBatch() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewBatchInvalid(t *testing.T) {
+	config := NewGameConfig("tetris")
+	config.MaxBatchScores = 2
+	testCases := []string{
+		``,
+		`{"playerId": "1", "score": 505}`,
+		`[]`,
+		`[{"playerId": 1, "score": 505}]`,
+		`[{"score": 1}, {"score": 2}, {"score": 3}]`,
+	}
+
+	for _, body := range testCases {
+		_, err := NewBatch(config, body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
+
+func TestNewBatchDefaultLimit(t *testing.T) {
+	// configs stored before batches existed have no limit of their own
+	config := GameConfig{Game: "tetris"}
+	items := make([]string, defaultMaxBatchScores)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"playerId": "%v", "score": 1}`, i)
+	}
+	_, err := NewBatch(config, "["+strings.Join(items, ",")+"]")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	_, err = NewBatch(config, "["+strings.Join(append(items, items[0]), ",")+"]")
+	if err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestBatchScoreValidate(t *testing.T) {
+	if err := (BatchScore{PlayerId: "player_1"}).Validate(); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	for _, playerId := range []string{"", "a/b", "a b"} {
+		if err := (BatchScore{PlayerId: playerId}).Validate(); err == nil {
+			t.Errorf("want error, got nil, playerId %q", playerId)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "strings"
+
+// boardSeparator joins a game and one of its boards, game and board names are alphanumeric so neither can contain it
+const boardSeparator = "/"
+
+// func BoardPartition names the partition holding a board's scores and config, which storage keeps as a game of its own
+// the game's own leaderboard is the partition named by the game
+func BoardPartition(game string, board string) string {
+	if board == "" {
+		return game
+	}
+	return game + boardSeparator + board
+}
+
+// func BoardsPrefix begins the partition of each of the game's boards, and of no other game's as game names cannot contain the separator
+func BoardsPrefix(game string) string {
+	return game + boardSeparator
+}
+
+// func PartitionGame is the game a partition belongs to, whose players, sessions and bans are shared by all of its boards
+func PartitionGame(partition string) string {
+	game, _, _ := strings.Cut(partition, boardSeparator)
+	return game
+}
@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestBoardPartition(t *testing.T) {
+	testCases := []struct {
+		game      string
+		board     string
+		partition string
+	}{
+		{"tetris", "", "tetris"},
+		{"tetris", "level1", "tetris/level1"},
+	}
+	for _, tc := range testCases {
+		got := BoardPartition(tc.game, tc.board)
+		if got != tc.partition {
+			t.Errorf("want %v, got %v", tc.partition, got)
+		}
+		if game := PartitionGame(got); game != tc.game {
+			t.Errorf("want %v, got %v", tc.game, game)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	defaultMaxNameLength  = 32
+	defaultMaxScoresLimit = 100
+	defaultMaxRanksLimit  = 1000
+	defaultMaxRanksAround = 500
+	defaultRetentionDays  = 365
+	defaultMinScore       = 1
+	defaultMaxBatchScores = 25
+	// defaultMaxAchievedAge allows a score to be submitted up to a day after it was achieved, long enough for a game played offline to reconnect
+	defaultMaxAchievedAge = 24 * 60 * 60
+)
+
+// func defaultReservedNames are the names of those running a game, which players could take to impersonate them
+func defaultReservedNames() []string {
+	return []string{"admin", "administrator", "moderator", "mod", "staff", "support", "system", "official"}
+}
+
+// Order is the direction in which a game ranks its scores, the zero Order ranks the highest score first
+type Order int
+
+const (
+	HighestFirst Order = iota
+	// LowestFirst suits games where less is better, such as the fastest time or the fewest strokes
+	LowestFirst
+)
+
+// func Compare returns a negative number when score a ranks before score b, a positive number when it ranks after and zero when they are equal
+func (o Order) Compare(a int, b int) int {
+	if o == LowestFirst {
+		return cmp.Compare(a, b)
+	}
+	return cmp.Compare(b, a)
+}
+
+// func CompareRanks orders ranks as Compare orders their scores, equal scores rank the earliest received first
+// when those were received in the same second, the earliest achieved ranks first
+func (o Order) CompareRanks(a Rank, b Rank) int {
+	return cmp.Or(o.Compare(a.Score, b.Score), cmp.Compare(a.ReceivedAt, b.ReceivedAt), cmp.Compare(a.Timestamp, b.Timestamp))
+}
+
+func (o Order) MarshalText() ([]byte, error) {
+	switch o {
+	case HighestFirst:
+		return []byte("highestFirst"), nil
+	case LowestFirst:
+		return []byte("lowestFirst"), nil
+	}
+	return nil, fmt.Errorf("Unknown order %d", o)
+}
+
+func (o *Order) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "highestFirst":
+		*o = HighestFirst
+	case "lowestFirst":
+		*o = LowestFirst
+	default:
+		return fmt.Errorf("Unknown order %q, expected highestFirst or lowestFirst", text)
+	}
+	return nil
+}
+
+// GameConfig holds the settings of a single game, a game missing from the registry is played with NewGameConfig
+type GameConfig struct {
+	Game  string `json:"game"`
+	Order Order  `json:"order"`
+	// UniquePlayers is the default for ranks requests which do not set unique_players
+	UniquePlayers bool `json:"uniquePlayers"`
+	// MinScore and MaxScore bound the scores accepted for the game, a nil bound is unbounded
+	MinScore *int `json:"minScore"`
+	MaxScore *int `json:"maxScore"`
+	// MaxImprovement is the most a score may beat the player's previous best by when set
+	MaxImprovement *int `json:"maxImprovement"`
+	// MaxSubmissionsPerMinute is the most scores a player may submit to the game in a minute, zero is unlimited
+	MaxSubmissionsPerMinute int `json:"maxSubmissionsPerMinute"`
+	// Quarantine holds scores breaking the rules above for an admin to approve or discard, rather than rejecting them
+	Quarantine    bool `json:"quarantine"`
+	RetentionDays int  `json:"retentionDays"`
+	// MaxNameLength is the most characters a player name may have
+	MaxNameLength int `json:"maxNameLength"`
+	// ReservedNames are names no player may take, or any name which looks like one of them
+	ReservedNames []string `json:"reservedNames"`
+	// BlockedNames are words no player name may contain, matched however they are disguised with case, accents, spacing or lookalike characters
+	BlockedNames []string `json:"blockedNames"`
+	// MaxScoresLimit, MaxRanksLimit and MaxRanksAround are the largest page sizes a request may ask for
+	MaxScoresLimit int `json:"maxScoresLimit"`
+	MaxRanksLimit  int `json:"maxRanksLimit"`
+	MaxRanksAround int `json:"maxRanksAround"`
+	// MaxBatchScores is the most scores a batch submission may hold, zero is the default so that configs stored before batches existed accept them
+	MaxBatchScores int `json:"maxBatchScores"`
+	// MaxAchievedAgeSeconds is how long before it is received a score may say it was achieved, zero is the default for configs stored before scores said so
+	MaxAchievedAgeSeconds int `json:"maxAchievedAgeSeconds"`
+	// Timezone is the IANA timezone in which the game's daily, weekly and monthly leaderboards begin, empty is UTC
+	Timezone string `json:"timezone"`
+	// SigningSecret is shared with the game's clients, when set every score submitted for the game must be signed with it
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// RequireSessions refuses scores without an unused session token issued by the server
+	RequireSessions bool `json:"requireSessions"`
+	// MaxScoreRate is the most points a player can plausibly earn per second of a session, zero is unlimited
+	MaxScoreRate float64 `json:"maxScoreRate"`
+}
+
+func NewGameConfig(game string) GameConfig {
+	minScore := defaultMinScore
+	return GameConfig{
+		Game:                  game,
+		Order:                 HighestFirst,
+		MinScore:              &minScore,
+		RetentionDays:         defaultRetentionDays,
+		MaxNameLength:         defaultMaxNameLength,
+		ReservedNames:         defaultReservedNames(),
+		MaxScoresLimit:        defaultMaxScoresLimit,
+		MaxRanksLimit:         defaultMaxRanksLimit,
+		MaxRanksAround:        defaultMaxRanksAround,
+		MaxBatchScores:        defaultMaxBatchScores,
+		MaxAchievedAgeSeconds: defaultMaxAchievedAge,
+	}
+}
+
+// func ParseGameConfig reads a game's config from a request body, any setting missing from the body keeps its value in defaults
+func ParseGameConfig(defaults GameConfig, requestBody string) (GameConfig, error) {
+	// the defaults may be another game's stored config, which the body must not change through the pointers and slices they would share
+	config := defaults.clone()
+	err := json.Unmarshal([]byte(requestBody), &config)
+	if err != nil {
+		return GameConfig{}, fmt.Errorf("Failed to parse game config: %w", err)
+	}
+	// the game is named by the path, not the body
+	config.Game = defaults.Game
+
+	err = config.Validate()
+	if err != nil {
+		return GameConfig{}, err
+	}
+	return config, nil
+}
+
+// func clone copies the config along with the bounds and names its pointers and slices refer to
+func (c GameConfig) clone() GameConfig {
+	c.MinScore = cloneInt(c.MinScore)
+	c.MaxScore = cloneInt(c.MaxScore)
+	c.MaxImprovement = cloneInt(c.MaxImprovement)
+	c.ReservedNames = slices.Clone(c.ReservedNames)
+	c.BlockedNames = slices.Clone(c.BlockedNames)
+	return c
+}
+
+func cloneInt(i *int) *int {
+	if i == nil {
+		return nil
+	}
+	v := *i
+	return &v
+}
+
+func (c GameConfig) Validate() error {
+	if c.MinScore != nil && c.MaxScore != nil && *c.MinScore > *c.MaxScore {
+		return errors.New("minScore must not be greater than maxScore")
+	}
+	if c.RetentionDays < 1 {
+		return errors.New("retentionDays must be at least 1")
+	}
+	if c.MaxNameLength < 1 {
+		return errors.New("maxNameLength must be at least 1")
+	}
+	for _, name := range slices.Concat(c.ReservedNames, c.BlockedNames) {
+		if FoldName(name) == "" {
+			return fmt.Errorf("reservedNames and blockedNames must contain a letter or digit, %q does not", name)
+		}
+	}
+	if c.MaxScoresLimit < 1 {
+		return errors.New("maxScoresLimit must be at least 1")
+	}
+	if c.MaxRanksLimit < 1 {
+		return errors.New("maxRanksLimit must be at least 1")
+	}
+	if c.MaxRanksAround < 0 {
+		return errors.New("maxRanksAround must not be negative")
+	}
+	if c.MaxBatchScores < 0 {
+		return errors.New("maxBatchScores must not be negative")
+	}
+	if c.MaxAchievedAgeSeconds < 0 {
+		return errors.New("maxAchievedAgeSeconds must not be negative")
+	}
+	if c.MaxImprovement != nil && *c.MaxImprovement < 0 {
+		return errors.New("maxImprovement must not be negative")
+	}
+	if c.MaxSubmissionsPerMinute < 0 {
+		return errors.New("maxSubmissionsPerMinute must not be negative")
+	}
+	if c.MaxScoreRate < 0 {
+		return errors.New("maxScoreRate must not be negative")
+	}
+	if c.SigningSecret != "" && len(c.SigningSecret) < minSigningSecretLength {
+		return fmt.Errorf("signingSecret must be at least %v characters", minSigningSecretLength)
+	}
+	_, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("Unknown timezone %q", c.Timezone)
+	}
+	return nil
+}
+
+// func BatchLimit is the most scores a batch submission to the game may hold
+func (c GameConfig) BatchLimit() int {
+	if c.MaxBatchScores == 0 {
+		return defaultMaxBatchScores
+	}
+	return c.MaxBatchScores
+}
+
+// func Location is the timezone of the game's windowed leaderboards, falling back to UTC if the timezone cannot be loaded
+func (c GameConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseGameConfig(t *testing.T) {
+	minScore := 1
+	want := NewGameConfig("golf")
+	want.Order = LowestFirst
+	want.MinScore = &minScore
+	want.MaxNameLength = 12
+	want.Timezone = "Europe/London"
+
+	got, err := ParseGameConfig(NewGameConfig("golf"), `{"game": "other", "order": "lowestFirst", "minScore": 1, "maxNameLength": 12, "timezone": "Europe/London"}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGameConfigKeepsDefaults(t *testing.T) {
+	maxScore := 100
+	defaults := NewGameConfig("golf")
+	defaults.MaxScore = &maxScore
+	want := NewGameConfig("golf")
+	want.MaxScore = &maxScore
+
+	_, err := ParseGameConfig(defaults, `{"minScore": 5, "maxScore": 200, "reservedNames": ["caddy"]}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if diff := cmp.Diff(want, defaults); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseGameConfigInvalid(t *testing.T) {
+	testCases := []string{
+		`{"order": "sideways"}`,
+		`{"minScore": 10, "maxScore": 5}`,
+		`{"retentionDays": 0}`,
+		`{"maxNameLength": 0}`,
+		`{"maxScoresLimit": -1}`,
+		`{"maxRanksLimit": 0}`,
+		`{"maxRanksAround": -1}`,
+		`{"maxBatchScores": -1}`,
+		`{"maxAchievedAgeSeconds": -1}`,
+		`{"timezone": "Mars/Olympus_Mons"}`,
+		`{"signingSecret": "tooshort"}`,
+		`{"maxScoreRate": -1}`,
+		`{"maxImprovement": -1}`,
+		`{"maxSubmissionsPerMinute": -1}`,
+		`{"blockedNames": ["-"]}`,
+		`not json`,
+	}
+
+	for _, body := range testCases {
+		_, err := ParseGameConfig(NewGameConfig("golf"), body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
+
+func TestGameConfigRoundTrip(t *testing.T) {
+	maxScore := 100
+	config := NewGameConfig("golf")
+	config.Order = LowestFirst
+	config.MaxScore = &maxScore
+
+	out, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	var got GameConfig
+	err = json.Unmarshal(out, &got)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if diff := cmp.Diff(config, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewScoreWithGameConfig(t *testing.T) {
+	minScore := 10
+	maxScore := 100
+	config := NewGameConfig("tag")
+	config.MinScore = &minScore
+	config.MaxScore = &maxScore
+	config.MaxNameLength = 5
+
+	type test struct {
+		body    string
+		wantErr bool
+	}
+	testCases := []test{
+		{body: `{"score": 10, "playerName": "goose"}`},
+		{body: `{"score": 100, "playerName": "goose"}`},
+		{body: `{"score": 9, "playerName": "goose"}`, wantErr: true},
+		{body: `{"score": 101, "playerName": "goose"}`, wantErr: true},
+		{body: `{"score": 50, "playerName": "gooses"}`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		score, err := NewScore(config, "goosey", tc.body)
+		if err == nil && config.CheckBounds(score) != "" {
+			err = errors.New(config.CheckBounds(score))
+		}
+		if tc.wantErr && err == nil {
+			t.Errorf("want error, got nil, body %v", tc.body)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("want nil, got %v, body %v", err, tc.body)
+		}
+	}
+}
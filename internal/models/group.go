@@ -0,0 +1,157 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxGroupPlayers bounds the players of a group, as ranking a group reads each player's best score separately
+	MaxGroupPlayers    = 100
+	maxGroupNameLength = 64
+)
+
+// Group is a set of players in a game, such as a player's friends, who can be ranked among themselves
+type Group struct {
+	Id        string   `json:"id"`
+	Game      string   `json:"game"`
+	Name      string   `json:"name"`
+	PlayerIds []string `json:"playerIds"`
+	Updated   int      `json:"updated"`
+}
+
+// GroupRanksRequest asks for the best score of each of a set of players, ranked among themselves
+// the players are either listed in the request or those of a stored group, in which case PlayerIds is empty until the group is read
+type GroupRanksRequest struct {
+	Game      string
+	GroupId   string
+	PlayerIds []string
+	Period    Period
+	Order     Order
+	Filter    Metadata
+}
+
+// func NewGroup reads a new group from a request body and generates its id
+func NewGroup(game string, requestBody string) (Group, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return Group{}, fmt.Errorf("Failed to generate group id: %w", err)
+	}
+	return ParseGroup(game, id, requestBody)
+}
+
+// func ParseGroup reads the group with the id from a request body, which replaces the group's name and players
+func ParseGroup(game string, id string, requestBody string) (Group, error) {
+	type groupRequestBody struct {
+		Name      string   `json:"name"`
+		PlayerIds []string `json:"playerIds"`
+	}
+	var body groupRequestBody
+	err := json.Unmarshal([]byte(requestBody), &body)
+	if err != nil {
+		return Group{}, fmt.Errorf("Failed to parse group: %w", err)
+	}
+	name := NormaliseName(body.Name)
+	if len([]rune(name)) > maxGroupNameLength {
+		return Group{}, fmt.Errorf("Group name must be at most %v characters", maxGroupNameLength)
+	}
+	playerIds, err := checkPlayerIds(body.PlayerIds)
+	if err != nil {
+		return Group{}, err
+	}
+	return Group{
+		Id:        id,
+		Game:      game,
+		Name:      name,
+		PlayerIds: playerIds,
+		Updated:   int(time.Now().Unix()),
+	}, nil
+}
+
+// func NewGroupRanksRequest builds a group ranks request for the game from either the players param, a comma separated list of player ids, or the group param
+// the window param selects the current period in the game's timezone
+func NewGroupRanksRequest(params map[string]string, config GameConfig) (GroupRanksRequest, error) {
+	playersStr, hasPlayers := params["players"]
+	groupId, hasGroup := params["group"]
+	if hasPlayers == hasGroup {
+		return GroupRanksRequest{}, errors.New("Expected either players or group")
+	}
+	var playerIds []string
+	if hasPlayers {
+		var err error
+		playerIds, err = checkPlayerIds(strings.Split(playersStr, ","))
+		if err != nil {
+			return GroupRanksRequest{}, err
+		}
+	}
+	if hasGroup && !playerIdPattern.MatchString(groupId) {
+		return GroupRanksRequest{}, errors.New("Expected a group of letters, digits and underscores")
+	}
+	period, err := parsePeriod(params, config.Location())
+	if err != nil {
+		return GroupRanksRequest{}, err
+	}
+	filter, err := parseFilter(params)
+	if err != nil {
+		return GroupRanksRequest{}, err
+	}
+	return GroupRanksRequest{
+		Game:      config.Game,
+		GroupId:   groupId,
+		PlayerIds: playerIds,
+		Period:    period,
+		Order:     config.Order,
+		Filter:    filter,
+	}, nil
+}
+
+// func checkPlayerIds returns the players of a group once each, in the order they were given
+func checkPlayerIds(playerIds []string) ([]string, error) {
+	seen := make(map[string]bool)
+	checked := make([]string, 0, len(playerIds))
+	for _, playerId := range playerIds {
+		playerId = strings.TrimSpace(playerId)
+		if !playerIdPattern.MatchString(playerId) {
+			return nil, errors.New("Expected player ids of letters, digits and underscores")
+		}
+		if !seen[playerId] {
+			seen[playerId] = true
+			checked = append(checked, playerId)
+		}
+	}
+	if len(checked) == 0 {
+		return nil, errors.New("Expected at least one player")
+	}
+	if len(checked) > MaxGroupPlayers {
+		return nil, fmt.Errorf("A group must have at most %v players", MaxGroupPlayers)
+	}
+	return checked, nil
+}
+
+// func NewGroupRanks ranks the best scores of a group's players among themselves, players who have not scored are left out
+// positions count from one within the group, with equal scores earliest received first
+func NewGroupRanks(bestScores []Score, order Order) Ranks {
+	ranks := make(Ranks, 0, len(bestScores))
+	for _, score := range bestScores {
+		ranks = append(ranks, Rank{
+			Score:      score.Score,
+			PlayerName: score.PlayerName,
+			Timestamp:  score.Timestamp,
+			Metadata:   score.Metadata,
+			ReceivedAt: score.ReceivedAt,
+			PlayerId:   score.PlayerId,
+		})
+	}
+	slices.SortFunc(ranks, func(a Rank, b Rank) int {
+		return cmp.Or(order.CompareRanks(a, b), cmp.Compare(a.PlayerId, b.PlayerId))
+	})
+	for i := range ranks {
+		ranks[i].Position = i + 1
+	}
+	return ranks
+}
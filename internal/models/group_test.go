@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewGroup(t *testing.T) {
+	group, err := NewGroup("tetris", `{"name": " Friends ", "playerIds": ["1", "2", "1", "3"]}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if group.Id == "" || group.Game != "tetris" || group.Name != "Friends" || group.Updated == 0 {
+		t.Errorf("unexpected group %+v", group)
+	}
+	if fmt.Sprint(group.PlayerIds) != "[1 2 3]" {
+		t.Errorf("want each player once in order, got %v", group.PlayerIds)
+	}
+}
+
+func TestNewGroupInvalid(t *testing.T) {
+	tooMany := make([]string, MaxGroupPlayers+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprint(i))
+	}
+	testCases := []string{
+		`not json`,
+		`{"name": "Friends"}`,
+		`{"playerIds": []}`,
+		`{"playerIds": ["1", "a b"]}`,
+		`{"playerIds": ["1"], "name": "` + strings.Repeat("a", maxGroupNameLength+1) + `"}`,
+		`{"playerIds": [` + strings.Join(tooMany, ",") + `]}`,
+	}
+
+	for _, body := range testCases {
+		_, err := NewGroup("tetris", body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
+
+func TestNewGroupRanksRequest(t *testing.T) {
+	config := NewGameConfig("tetris")
+	request, err := NewGroupRanksRequest(map[string]string{"players": "1, 2,1"}, config)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if fmt.Sprint(request.PlayerIds) != "[1 2]" || request.GroupId != "" {
+		t.Errorf("unexpected request %+v", request)
+	}
+
+	request, err = NewGroupRanksRequest(map[string]string{"group": "9c1e", "window": "daily"}, config)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if request.GroupId != "9c1e" || request.PlayerIds != nil || request.Period.IsAllTime() {
+		t.Errorf("unexpected request %+v", request)
+	}
+
+	for _, params := range []map[string]string{
+		{},
+		{"players": "1", "group": "9c1e"},
+		{"players": ""},
+		{"group": "../keys"},
+	} {
+		_, err := NewGroupRanksRequest(params, config)
+		if err == nil {
+			t.Errorf("want error, got nil, params %v", params)
+		}
+	}
+}
+
+func TestNewGroupRanks(t *testing.T) {
+	scores := []Score{
+		{PlayerId: "1", PlayerName: "goose", Score: 10, Timestamp: 5},
+		{PlayerId: "2", PlayerName: "duck", Score: 30, Timestamp: 5},
+		{PlayerId: "3", PlayerName: "swan", Score: 10, Timestamp: 3},
+	}
+	testCases := []struct {
+		order Order
+		want  string
+	}{
+		{order: HighestFirst, want: "1 duck 30, 2 swan 10, 3 goose 10"},
+		{order: LowestFirst, want: "1 swan 10, 2 goose 10, 3 duck 30"},
+	}
+	for _, tc := range testCases {
+		got := []string{}
+		for _, rank := range NewGroupRanks(scores, tc.order) {
+			got = append(got, fmt.Sprintf("%v %v %v", rank.Position, rank.PlayerName, rank.Score))
+		}
+		if strings.Join(got, ", ") != tc.want {
+			t.Errorf("want %v, got %v", tc.want, strings.Join(got, ", "))
+		}
+	}
+}
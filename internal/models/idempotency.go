@@ -0,0 +1,68 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// IdempotencyRetention is how long the response to a request is kept for its retries
+	IdempotencyRetention = 24 * time.Hour
+	// idempotencyClaimTimeout is how long a key stays claimed by a request which never finished, such as one whose lambda timed out
+	idempotencyClaimTimeout = time.Minute
+)
+
+// IdempotencyRecord is the response to a request made with an Idempotency-Key, kept so that retries of the request get the same response without repeating it
+type IdempotencyRecord struct {
+	// Key is the client's key scoped to the game and player, so that a client cannot replay the responses of another player
+	Key string
+	// RequestHash identifies the body of the request, a key sent again with a different body is refused
+	RequestHash string
+	// StatusCode is zero while the first request with the key is in progress
+	StatusCode int
+	Body       string
+	Expires    int
+}
+
+// func NewIdempotencyRecord claims the client's key for a request until it completes or the claim times out
+func NewIdempotencyRecord(game string, playerId string, key string, requestBody string, now time.Time) (IdempotencyRecord, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return IdempotencyRecord{}, fmt.Errorf("Idempotency-Key must be at most %v characters", maxIdempotencyKeyLength)
+	}
+	for _, r := range key {
+		if r < '!' || r > '~' {
+			return IdempotencyRecord{}, errors.New("Idempotency-Key must be printable ascii without spaces")
+		}
+	}
+	return IdempotencyRecord{
+		Key:         fmt.Sprintf("%v|%v|%v", game, playerId, key),
+		RequestHash: hashRequest(requestBody),
+		Expires:     int(now.Add(idempotencyClaimTimeout).Unix()),
+	}, nil
+}
+
+func (r IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
+
+// func Matches reports whether the record is of a request with the same body
+func (r IdempotencyRecord) Matches(requestBody string) bool {
+	return r.RequestHash == hashRequest(requestBody)
+}
+
+// func Completed records the response to the request, which is kept for the retention period
+func (r IdempotencyRecord) Completed(statusCode int, body string, now time.Time) IdempotencyRecord {
+	r.StatusCode = statusCode
+	r.Body = body
+	r.Expires = int(now.Add(IdempotencyRetention).Unix())
+	return r
+}
+
+func hashRequest(requestBody string) string {
+	sum := sha256.Sum256([]byte(requestBody))
+	return hex.EncodeToString(sum[:])
+}
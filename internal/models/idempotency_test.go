@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIdempotencyRecord(t *testing.T) {
+	now := time.Unix(1739253593, 0)
+	record, err := NewIdempotencyRecord("Tetris", "1", "7c0f5a3e", `{"score": 10}`, now)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if record.Key != "Tetris|1|7c0f5a3e" {
+		t.Errorf("want %v, got %v", "Tetris|1|7c0f5a3e", record.Key)
+	}
+	if !record.Pending() {
+		t.Errorf("want a new record to be pending")
+	}
+	if !record.Matches(`{"score": 10}`) || record.Matches(`{"score": 11}`) {
+		t.Errorf("want the record to match only its own request")
+	}
+
+	completed := record.Completed(201, "", now)
+	if completed.Pending() {
+		t.Errorf("want a completed record not to be pending")
+	}
+	if want := int(now.Add(IdempotencyRetention).Unix()); completed.Expires != want {
+		t.Errorf("want %v, got %v", want, completed.Expires)
+	}
+}
+
+func TestNewIdempotencyRecordInvalidKey(t *testing.T) {
+	for _, key := range []string{"two words", "ключ", strings.Repeat("a", maxIdempotencyKeyLength+1)} {
+		_, err := NewIdempotencyRecord("Tetris", "1", key, "", time.Now())
+		if err == nil {
+			t.Errorf("want error, got nil, key %v", key)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxMetadataKeys  = 10
+	maxMetadataBytes = 1024
+)
+
+// metadataKeyPattern keeps metadata keys to names that can be written in a filter param and a dynamodb attribute path
+var metadataKeyPattern = regexp.MustCompile(`^\w{1,32}$`)
+
+// Metadata describes how a score was achieved, such as its replay id, level, character or platform
+// the values are strings so that a filter param can match them exactly
+type Metadata map[string]string
+
+// func Validate checks the metadata is small enough to store with every copy of its score
+func (m Metadata) Validate() error {
+	if len(m) > maxMetadataKeys {
+		return fmt.Errorf("metadata must have at most %v keys", maxMetadataKeys)
+	}
+	for key := range m {
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("metadata key %q must be at most 32 letters, digits and underscores", key)
+		}
+	}
+	if len(m.Encode()) > maxMetadataBytes {
+		return fmt.Errorf("metadata must be at most %v bytes of json", maxMetadataBytes)
+	}
+	return nil
+}
+
+// func Matches is whether the metadata has every key of the filter with the same value
+func (m Metadata) Matches(filter Metadata) bool {
+	for key, value := range filter {
+		v, ok := m[key]
+		if !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// func Encode is the metadata as a json object, which is how the sql backends store it and filter by it
+func (m Metadata) Encode() string {
+	if len(m) == 0 {
+		return "{}"
+	}
+	// a map of strings cannot fail to marshal
+	out, _ := json.Marshal(m)
+	return string(out)
+}
+
+// func DecodeMetadata reads metadata stored by Encode, empty metadata is nil as it is for a score submitted without any
+func DecodeMetadata(data string) (Metadata, error) {
+	var m Metadata
+	err := json.Unmarshal([]byte(data), &m)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse metadata: %w", err)
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// func parseFilter reads the filter param, a comma separated list of key:value pairs which a score's metadata must all match
+func parseFilter(params map[string]string) (Metadata, error) {
+	filterStr, ok := params["filter"]
+	if !ok {
+		return nil, nil
+	}
+	filter := make(Metadata)
+	for _, pair := range strings.Split(filterStr, ",") {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("Failed to parse filter %q, expected key:value pairs separated by commas", pair)
+		}
+		if _, ok := filter[key]; ok {
+			return nil, fmt.Errorf("Filter has more than one value for %q", key)
+		}
+		filter[key] = value
+	}
+	if len(filter) > maxMetadataKeys {
+		return nil, errors.New("Filter has more keys than metadata may have")
+	}
+	return filter, nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetadataValidate(t *testing.T) {
+	valid := Metadata{"replay_id": "r-123", "level": "7", "character": "Goose", "platform": "pc"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+
+	tooMany := make(Metadata)
+	for i := 0; i <= maxMetadataKeys; i++ {
+		tooMany[fmt.Sprintf("key%v", i)] = "value"
+	}
+	invalid := []Metadata{
+		tooMany,
+		{"": "empty"},
+		{"has space": "value"},
+		{"a.b": "value"},
+		{strings.Repeat("k", 33): "value"},
+		{"replay": strings.Repeat("r", maxMetadataBytes)},
+	}
+	for _, m := range invalid {
+		if err := m.Validate(); err == nil {
+			t.Errorf("want error, got nil, metadata %v", m)
+		}
+	}
+}
+
+func TestMetadataMatches(t *testing.T) {
+	m := Metadata{"level": "7", "platform": "pc"}
+	testCases := []struct {
+		filter Metadata
+		want   bool
+	}{
+		{nil, true},
+		{Metadata{"platform": "pc"}, true},
+		{Metadata{"platform": "pc", "level": "7"}, true},
+		{Metadata{"platform": "switch"}, false},
+		{Metadata{"platform": "pc", "character": "Goose"}, false},
+	}
+	for _, tc := range testCases {
+		if got := m.Matches(tc.filter); got != tc.want {
+			t.Errorf("want %v, got %v, filter %v", tc.want, got, tc.filter)
+		}
+	}
+}
+
+func TestMetadataEncode(t *testing.T) {
+	for _, m := range []Metadata{nil, {"level": "7", "platform": "pc"}} {
+		got, err := DecodeMetadata(m.Encode())
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if diff := cmp.Diff(m, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	got, err := parseFilter(map[string]string{"filter": "platform:pc,level:7"})
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := Metadata{"platform": "pc", "level": "7"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = parseFilter(map[string]string{})
+	if err != nil || got != nil {
+		t.Errorf("want nil, got %v, %v", got, err)
+	}
+
+	for _, filter := range []string{"platform", "platform:pc,", ":pc", "platform:pc,platform:switch", "a b:c"} {
+		_, err := parseFilter(map[string]string{"filter": filter})
+		if err == nil {
+			t.Errorf("want error, got nil, filter %q", filter)
+		}
+	}
+}
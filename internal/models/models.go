@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
@@ -16,12 +17,29 @@ type Score struct {
 	Score      int    `json:"score"`
 	PlayerId   string `json:"playerId"`
 	PlayerName string `json:"playerName"`
-	Timestamp  int    `json:"timestamp"`
+	// Timestamp is when the score was achieved, which the client may set to earlier than it was received
+	Timestamp int `json:"timestamp"`
+	// ReceivedAt is when the server received the score, kept to audit scores submitted some time after they were achieved
+	ReceivedAt int `json:"receivedAt"`
+	// Metadata is how the score was achieved, as the client described it
+	Metadata Metadata `json:"metadata,omitempty"`
+	// Periods are the windowed leaderboards the score is entered into alongside the all time leaderboard
+	Periods []Period `json:"-"`
+	// Order is how the game ranks scores, which decides whether a submission is a player's new best
+	Order Order `json:"-"`
+	// RetentionDays is how long the game keeps the score, zero keeps it for the default retention
+	RetentionDays int `json:"-"`
 }
 
 type ScoreRequest struct {
 	Game  string
 	Limit int
+	// Period restricts the scores to those submitted within it, the zero Period is all time
+	Period Period
+	// Order sorts the scores best first
+	Order Order
+	// Filter restricts the scores to those whose metadata matches it
+	Filter Metadata
 }
 
 type PlayerScoreRequest struct {
@@ -30,10 +48,16 @@ type PlayerScoreRequest struct {
 }
 
 type Rank struct {
-	Score      int    `json:"score" dynamodbav:"sk"`
-	Position   int    `json:"position" dynamodbav:"-"`
-	PlayerName string `json:"playerName" dynamodbav:"pname"`
-	Timestamp  int    `json:"timestamp" dynamodbav:"ts"`
+	Score      int    `json:"score"`
+	Position   int    `json:"position"`
+	PlayerName string `json:"playerName"`
+	Timestamp  int    `json:"timestamp"`
+	// Metadata is the metadata of the score ranked
+	Metadata Metadata `json:"metadata,omitempty"`
+	// ReceivedAt is when the score ranked was received, which orders equal scores and is kept from responses
+	ReceivedAt int `json:"-"`
+	// PlayerId is kept from responses, it lets the handler show the name from the player's profile
+	PlayerId string `json:"-"`
 }
 
 type Ranks []Rank
@@ -41,18 +65,44 @@ type Ranks []Rank
 type RanksRequest struct {
 	Game  string
 	Limit int
+	// UniquePlayers ranks only the best score of each player, so a player occupies at most one position
+	UniquePlayers bool
+	// Period restricts the ranks to scores submitted within it, the zero Period is all time
+	Period Period
+	Order  Order
+	// Filter ranks only the scores whose metadata matches it, positions are counted among those scores alone
+	Filter Metadata
+}
+
+// RanksAroundRequest asks for the ranks either side of a player's score, with positions counted across the whole game
+// a player may have submitted the same score more than once, the timestamp picks out the submission
+type RanksAroundRequest struct {
+	RanksRequest
+	PlayerId   string
+	Score      int
+	Timestamp  int
+	ReceivedAt int
+	Around     int
 }
 
 type PlayerRanksRequest struct {
-	Game     string
-	Around   int
-	PlayerId string
+	Game          string
+	Around        int
+	PlayerId      string
+	UniquePlayers bool
+	Period        Period
+	Order         Order
+	Filter        Metadata
 }
 
-func NewScore(game string, playerId string, requestBody string) (Score, error) {
+// func NewScore builds a score submitted to the game, enforcing the game's bounds on the player name and on when the score was achieved
+// the game's rules for the score itself are checked by the handler, which may quarantine a score rather than reject it
+func NewScore(config GameConfig, playerId string, requestBody string) (Score, error) {
 	type putNewScoreRequestBody struct {
-		Score      int    `json:"score"`
-		PlayerName string `json:"playerName"`
+		Score      int      `json:"score"`
+		PlayerName string   `json:"playerName"`
+		AchievedAt *int     `json:"achievedAt"`
+		Metadata   Metadata `json:"metadata"`
 	}
 	b := putNewScoreRequestBody{}
 	err := json.Unmarshal([]byte(requestBody), &b)
@@ -62,23 +112,39 @@ func NewScore(game string, playerId string, requestBody string) (Score, error) {
 	if b.Score == 0 {
 		return Score{}, errors.New("Expected a score")
 	}
-	if b.PlayerName == "" {
-		return Score{}, errors.New("Expected a player_name")
+	playerName := NormaliseName(b.PlayerName)
+	err = config.NamePolicy().Check(playerName)
+	if err != nil {
+		return Score{}, err
 	}
-	if len(b.PlayerName) > 32 {
-		return Score{}, errors.New("Player name was too long")
+	err = b.Metadata.Validate()
+	if err != nil {
+		return Score{}, err
 	}
 
+	received := time.Now()
+	timestamp := int(received.Unix())
+	if b.AchievedAt != nil {
+		timestamp, err = config.CheckAchievedAt(*b.AchievedAt, received)
+		if err != nil {
+			return Score{}, err
+		}
+	}
 	return Score{
-		PlayerId:   playerId,
-		PlayerName: b.PlayerName,
-		Game:       game,
-		Score:      b.Score,
-		Timestamp:  int(time.Now().Unix()),
+		PlayerId:      playerId,
+		PlayerName:    playerName,
+		Game:          config.Game,
+		Score:         b.Score,
+		Timestamp:     timestamp,
+		ReceivedAt:    int(received.Unix()),
+		Metadata:      b.Metadata,
+		Periods:       NewPeriods(timestamp, config.Location()),
+		Order:         config.Order,
+		RetentionDays: config.RetentionDays,
 	}, nil
 }
 
-func NewScoreRequest(params map[string]string, game string) (ScoreRequest, error) {
+func NewScoreRequest(params map[string]string, config GameConfig) (ScoreRequest, error) {
 	limitStr, ok := params["limit"]
 	if !ok {
 		return ScoreRequest{}, errors.New("Expected a limit")
@@ -87,18 +153,28 @@ func NewScoreRequest(params map[string]string, game string) (ScoreRequest, error
 	if err != nil {
 		return ScoreRequest{}, fmt.Errorf("Failed to parse limit: %w", err)
 	}
-	if limit > 100 || limit < 0 {
-		return ScoreRequest{}, errors.New("Limit must be between 0 and 100")
+	if limit > config.MaxScoresLimit || limit < 0 {
+		return ScoreRequest{}, fmt.Errorf("Limit must be between 0 and %v", config.MaxScoresLimit)
+	}
+	// a limit of zero asks for as many as the game allows, rather than every score
+	if limit == 0 {
+		limit = config.MaxScoresLimit
+	}
+	filter, err := parseFilter(params)
+	if err != nil {
+		return ScoreRequest{}, err
 	}
 
 	return ScoreRequest{
-		Game:  game,
-		Limit: limit,
+		Game:   config.Game,
+		Limit:  limit,
+		Order:  config.Order,
+		Filter: filter,
 	}, nil
 }
 
-func NewPlayerScoreRequest(params map[string]string, game string, playerId string) (PlayerScoreRequest, error) {
-	scoreRequest, err := NewScoreRequest(params, game)
+func NewPlayerScoreRequest(params map[string]string, config GameConfig, playerId string) (PlayerScoreRequest, error) {
+	scoreRequest, err := NewScoreRequest(params, config)
 	if err != nil {
 		return PlayerScoreRequest{}, err
 	}
@@ -109,7 +185,9 @@ func NewPlayerScoreRequest(params map[string]string, game string, playerId strin
 	}, nil
 }
 
-func NewRanksRequest(params map[string]string, game string) (RanksRequest, error) {
+// func NewRanksRequest builds a ranks request for the game, the unique_players param may override the game's default
+// the window param selects the current period in the game's timezone
+func NewRanksRequest(params map[string]string, config GameConfig) (RanksRequest, error) {
 	limitStr, ok := params["limit"]
 	if !ok {
 		return RanksRequest{}, errors.New("Expected a limit")
@@ -118,16 +196,38 @@ func NewRanksRequest(params map[string]string, game string) (RanksRequest, error
 	if err != nil {
 		return RanksRequest{}, fmt.Errorf("Failed to parse limit: %w", err)
 	}
-	if limit > 1000 || limit < 0 {
-		return RanksRequest{}, errors.New("Limit must be between 0 and 1000")
+	if limit > config.MaxRanksLimit || limit < 0 {
+		return RanksRequest{}, fmt.Errorf("Limit must be between 0 and %v", config.MaxRanksLimit)
+	}
+	// a limit of zero asks for as many as the game allows, rather than every rank
+	if limit == 0 {
+		limit = config.MaxRanksLimit
+	}
+	uniquePlayers, err := parseUniquePlayers(params, config.UniquePlayers)
+	if err != nil {
+		return RanksRequest{}, err
+	}
+	period, err := parsePeriod(params, config.Location())
+	if err != nil {
+		return RanksRequest{}, err
+	}
+	filter, err := parseFilter(params)
+	if err != nil {
+		return RanksRequest{}, err
 	}
 	return RanksRequest{
-		game,
+		config.Game,
 		limit,
+		uniquePlayers,
+		period,
+		config.Order,
+		filter,
 	}, nil
 }
 
-func NewPlayerRanksRequest(params map[string]string, game string, playerId string) (PlayerRanksRequest, error) {
+// func NewPlayerRanksRequest builds a player ranks request for the game, the unique_players param may override the game's default
+// the window param selects the current period in the game's timezone
+func NewPlayerRanksRequest(params map[string]string, config GameConfig, playerId string) (PlayerRanksRequest, error) {
 	aroundStr, ok := params["ranks_around"]
 	if !ok {
 		return PlayerRanksRequest{}, errors.New("Expected ranks_around")
@@ -136,20 +236,58 @@ func NewPlayerRanksRequest(params map[string]string, game string, playerId strin
 	if err != nil {
 		return PlayerRanksRequest{}, fmt.Errorf("Failed to parse ranks_around: %w", err)
 	}
-	if around > 500 || around < 0 {
-		return PlayerRanksRequest{}, errors.New("ranks_around must be between 0 and 500")
+	if around > config.MaxRanksAround || around < 0 {
+		return PlayerRanksRequest{}, fmt.Errorf("ranks_around must be between 0 and %v", config.MaxRanksAround)
+	}
+	uniquePlayers, err := parseUniquePlayers(params, config.UniquePlayers)
+	if err != nil {
+		return PlayerRanksRequest{}, err
 	}
+	period, err := parsePeriod(params, config.Location())
+	if err != nil {
+		return PlayerRanksRequest{}, err
+	}
+	filter, err := parseFilter(params)
 	if err != nil {
 		return PlayerRanksRequest{}, err
 	}
 
 	return PlayerRanksRequest{
-		Game:     game,
-		Around:   around,
-		PlayerId: playerId,
+		Game:          config.Game,
+		Around:        around,
+		PlayerId:      playerId,
+		UniquePlayers: uniquePlayers,
+		Period:        period,
+		Order:         config.Order,
+		Filter:        filter,
 	}, nil
 }
 
+func parseUniquePlayers(params map[string]string, defaultValue bool) (bool, error) {
+	uniqueStr, ok := params["unique_players"]
+	if !ok {
+		return defaultValue, nil
+	}
+	unique, err := strconv.ParseBool(uniqueStr)
+	if err != nil {
+		return false, fmt.Errorf("Failed to parse unique_players: %w", err)
+	}
+	return unique, nil
+}
+
+// func parsePeriod returns the current period of the window param, which defaults to all time
+func parsePeriod(params map[string]string, loc *time.Location) (Period, error) {
+	windowStr, ok := params["window"]
+	if !ok {
+		return Period{}, nil
+	}
+	window, err := ParseWindow(windowStr)
+	if err != nil {
+		return Period{}, err
+	}
+	return NewPeriod(window, time.Now(), loc), nil
+}
+
 // Fulfills the Unmarshaler interface https://pkg.go.dev/github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue#Unmarshaler
 func (s *Score) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 	avM, ok := av.(*types.AttributeValueMemberM)
@@ -175,9 +313,9 @@ func (s *Score) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 				if !ok {
 					return errors.New("Wrong type stored at sk")
 				}
-				score, err := strconv.Atoi(i.Value)
+				score, err := ParseSortKey(i.Value)
 				if err != nil {
-					return fmt.Errorf("Failed to parse sk for score: %w", err)
+					return err
 				}
 				s.Score = score
 			}
@@ -203,6 +341,25 @@ func (s *Score) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 				s.Timestamp = ts
 
 			}
+		case "rts":
+			{
+				i, ok := kv.(*types.AttributeValueMemberN)
+				if !ok {
+					return errors.New("Wrong type stored at received timestamp")
+				}
+				rts, err := strconv.Atoi(i.Value)
+				if err != nil {
+					return fmt.Errorf("Failed to parse received timestamp for score: %w", err)
+				}
+				s.ReceivedAt = rts
+			}
+		case "meta":
+			{
+				err := attributevalue.Unmarshal(kv, &s.Metadata)
+				if err != nil {
+					return fmt.Errorf("Failed to unmarshal metadata for score: %w", err)
+				}
+			}
 		}
 	}
 	return nil
@@ -211,31 +368,69 @@ func (s *Score) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 // Fulfills the Marshaler interface https://pkg.go.dev/github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue#Marshaler
 func (s *Score) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
 	m := make(map[string]types.AttributeValue)
-	score := strconv.Itoa(s.Score)
 	m["pk"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("%s|%s", s.PlayerId, s.Game)}
-	m["sk"] = &types.AttributeValueMemberN{Value: score}
+	m["sk"] = &types.AttributeValueMemberN{Value: s.SortKey()}
 	m["game"] = &types.AttributeValueMemberS{Value: s.Game}
 	m["pname"] = &types.AttributeValueMemberS{Value: s.PlayerName}
-	m["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(int(time.Now().AddDate(1, 0, 0).Unix()))}
+	m["ttl"] = &types.AttributeValueMemberN{Value: strconv.Itoa(s.Expiry())}
 	m["ts"] = &types.AttributeValueMemberN{Value: strconv.Itoa(s.Timestamp)}
+	m["rts"] = &types.AttributeValueMemberN{Value: strconv.Itoa(s.ReceivedAt)}
+	if len(s.Metadata) > 0 {
+		meta, err := attributevalue.Marshal(s.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal metadata for score: %w", err)
+		}
+		m["meta"] = meta
+	}
 	return &types.AttributeValueMemberM{
 		Value: m,
 	}, nil
 }
 
-// func binarySearch returns -1 if the score is not in the ranks, otherwise the index of the score within the ranks
-func (r Ranks) BinarySearch(score int, left int, right int) int {
-	mid := (right-left)/2 + left
-	if left > right {
-		return -1
+// func Expiry is the unix time after which storage may discard the score
+func (s Score) Expiry() int {
+	days := s.RetentionDays
+	if days == 0 {
+		days = defaultRetentionDays
 	}
-	if r[mid].Score == score {
-		return mid
+	return int(time.Now().AddDate(0, 0, days).Unix())
+}
+
+// func BinarySearch returns -1 if the target is not in the ranks between left and right inclusive, otherwise the index of the target within the ranks
+// ranks are matched by score, receipt time and timestamp, and also by player when the target has one, as players may submit the same score at the same moment
+// the ranks must be sorted best first by order, with equal scores earliest received first
+func (r Ranks) BinarySearch(target Rank, order Order, left int, right int) int {
+	right = min(right, len(r)-1)
+	for left <= right {
+		mid := (right-left)/2 + left
+		switch c := order.CompareRanks(r[mid], target); {
+		case c == 0:
+			return r.findPlayer(target, order, mid, left, right)
+		case c < 0:
+			left = mid + 1
+		default:
+			right = mid - 1
+		}
+	}
+	return -1
+}
+
+// func findPlayer looks either side of index for the target's player among the ranks equal to the target
+func (r Ranks) findPlayer(target Rank, order Order, index int, left int, right int) int {
+	if target.PlayerId == "" {
+		return index
 	}
-	if r[mid].Score > score {
-		return r.BinarySearch(score, mid+1, right)
+	for i := index; i >= left && order.CompareRanks(r[i], target) == 0; i-- {
+		if r[i].PlayerId == target.PlayerId {
+			return i
+		}
+	}
+	for i := index + 1; i <= right && order.CompareRanks(r[i], target) == 0; i++ {
+		if r[i].PlayerId == target.PlayerId {
+			return i
+		}
 	}
-	return r.BinarySearch(score, left, right-1)
+	return -1
 }
 
 func (r Ranks) Around(index int, around int) Ranks {
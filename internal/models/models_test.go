@@ -1,7 +1,9 @@
 package models
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -45,9 +47,11 @@ func TestUnmarshalScore(t *testing.T) {
 			"sk":    &types.AttributeValueMemberN{Value: "505"},
 			"game":  &types.AttributeValueMemberS{Value: "Singing"},
 			"pname": &types.AttributeValueMemberS{Value: "David Bowie"},
+			"ts":    &types.AttributeValueMemberN{Value: "1739253000"},
+			"rts":   &types.AttributeValueMemberN{Value: "1739253593"},
 		},
 	}
-	want := Score{PlayerId: "123", PlayerName: "David Bowie", Game: "Singing", Score: 505}
+	want := Score{PlayerId: "123", PlayerName: "David Bowie", Game: "Singing", Score: 505, Timestamp: 1739253000, ReceivedAt: 1739253593}
 	result := Score{}
 	err := attributevalue.Unmarshal(av, &result)
 	if err != nil {
@@ -61,10 +65,11 @@ func TestUnmarshalScore(t *testing.T) {
 
 func TestNewScoreFromParams(t *testing.T) {
 	want := Score{
-		Game:       "tag",
-		Score:      99,
-		PlayerId:   "goosey",
-		PlayerName: "BIG GOOSE",
+		Game:          "tag",
+		Score:         99,
+		PlayerId:      "goosey",
+		PlayerName:    "BIG GOOSE",
+		RetentionDays: 365,
 	}
 
 	body := `
@@ -74,14 +79,56 @@ func TestNewScoreFromParams(t *testing.T) {
 	}
 	`
 
-	result, err := NewScore("tag", "goosey", body)
+	result, err := NewScore(NewGameConfig("tag"), "goosey", body)
 	if err != nil {
 		t.Errorf("want nil, got %v", err)
 	}
-	diff := cmp.Diff(want, result, cmpopts.IgnoreFields(Score{}, "Timestamp"))
+	diff := cmp.Diff(want, result, cmpopts.IgnoreFields(Score{}, "Timestamp", "ReceivedAt", "Periods"))
 	if diff != "" {
 		t.Errorf("mismatch (want +, got -)\n%v", diff)
 	}
+	if result.Timestamp != result.ReceivedAt {
+		t.Errorf("want %v, got %v", result.ReceivedAt, result.Timestamp)
+	}
+}
+
+func TestNewScoreAchievedAt(t *testing.T) {
+	config := NewGameConfig("tag")
+	config.Timezone = "Australia/Sydney"
+	yesterday := time.Now().Add(-20 * time.Hour)
+	body := fmt.Sprintf(`{"score": 99, "playerName": "BIG GOOSE", "achievedAt": %v}`, yesterday.Unix())
+
+	result, err := NewScore(config, "goosey", body)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if result.Timestamp != int(yesterday.Unix()) {
+		t.Errorf("want %v, got %v", yesterday.Unix(), result.Timestamp)
+	}
+	if result.ReceivedAt <= result.Timestamp {
+		t.Errorf("want a receipt time after %v, got %v", result.Timestamp, result.ReceivedAt)
+	}
+	want := NewPeriods(int(yesterday.Unix()), config.Location())
+	if diff := cmp.Diff(want, result.Periods); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewScoreMetadata(t *testing.T) {
+	body := `{"score": 99, "playerName": "BIG GOOSE", "metadata": {"platform": "pc", "level": "7"}}`
+	result, err := NewScore(NewGameConfig("tag"), "goosey", body)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	want := Metadata{"platform": "pc", "level": "7"}
+	if diff := cmp.Diff(want, result.Metadata); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = NewScore(NewGameConfig("tag"), "goosey", `{"score": 99, "playerName": "BIG GOOSE", "metadata": {"not a key": "pc"}}`)
+	if err == nil {
+		t.Errorf("want error, got nil")
+	}
 }
 
 func TestRanksBinarySearch(t *testing.T) {
@@ -92,24 +139,74 @@ func TestRanksBinarySearch(t *testing.T) {
 		{Position: 4, PlayerName: "Dobby", Score: 10},
 	}
 
-	search := ranks.BinarySearch(100, 0, len(ranks))
+	search := ranks.BinarySearch(Rank{Score: 100}, HighestFirst, 0, len(ranks))
 	if search != 0 {
 		t.Errorf("wanted to find index at %v, got %v", 0, search)
 	}
-	search = ranks.BinarySearch(50, 0, len(ranks))
+	search = ranks.BinarySearch(Rank{Score: 50}, HighestFirst, 0, len(ranks))
 	if search != 1 {
 		t.Errorf("wanted to find index at %v, got %v", 1, search)
 	}
-	search = ranks.BinarySearch(20, 0, len(ranks))
+	search = ranks.BinarySearch(Rank{Score: 20}, HighestFirst, 0, len(ranks))
 	if search != 2 {
 		t.Errorf("wanted to find index at %v, got %v", 2, search)
 	}
-	search = ranks.BinarySearch(10, 0, len(ranks))
+	search = ranks.BinarySearch(Rank{Score: 10}, HighestFirst, 0, len(ranks))
 	if search != 3 {
 		t.Errorf("wanted to find index at %v, got %v", 3, search)
 	}
 }
 
+func TestRanksBinarySearchLowestFirst(t *testing.T) {
+	ranks := Ranks{
+		{Position: 1, PlayerName: "Albus", Score: 10},
+		{Position: 2, PlayerName: "Harry", Score: 20},
+		{Position: 3, PlayerName: "Potter", Score: 50},
+		{Position: 4, PlayerName: "Dobby", Score: 100},
+	}
+
+	for want, rank := range ranks {
+		search := ranks.BinarySearch(rank, LowestFirst, 0, len(ranks)-1)
+		if search != want {
+			t.Errorf("wanted to find index at %v, got %v", want, search)
+		}
+	}
+	search := ranks.BinarySearch(Rank{Score: 30}, LowestFirst, 0, len(ranks)-1)
+	if search != -1 {
+		t.Errorf("wanted to find index at %v, got %v", -1, search)
+	}
+	search = ranks.BinarySearch(Rank{Score: 10}, HighestFirst, 0, len(ranks)-1)
+	if search != -1 {
+		t.Errorf("wanted to find index at %v, got %v", -1, search)
+	}
+}
+
+func TestRanksBinarySearchWithEqualScores(t *testing.T) {
+	ranks := Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 111},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: 111},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 50, Timestamp: 222},
+		{Position: 4, PlayerId: "4", PlayerName: "Dobby", Score: 50, Timestamp: 222},
+		{Position: 5, PlayerId: "5", PlayerName: "Hedwig", Score: 50, Timestamp: 333},
+		{Position: 6, PlayerId: "6", PlayerName: "Fawkes", Score: 10, Timestamp: 111},
+	}
+
+	for want, rank := range ranks {
+		search := ranks.BinarySearch(Rank{Score: rank.Score, Timestamp: rank.Timestamp, PlayerId: rank.PlayerId}, HighestFirst, 0, len(ranks)-1)
+		if search != want {
+			t.Errorf("wanted to find index at %v, got %v", want, search)
+		}
+	}
+	search := ranks.BinarySearch(Rank{Score: 50, Timestamp: 222, PlayerId: "1"}, HighestFirst, 0, len(ranks)-1)
+	if search != -1 {
+		t.Errorf("wanted to find index at %v, got %v", -1, search)
+	}
+	search = ranks.BinarySearch(Rank{Score: 50, Timestamp: 444}, HighestFirst, 0, len(ranks)-1)
+	if search != -1 {
+		t.Errorf("wanted to find index at %v, got %v", -1, search)
+	}
+}
+
 func TestRanksAround(t *testing.T) {
 
 	r0 := Rank{Position: 1, PlayerName: "Albus", Score: 100}
@@ -142,3 +239,80 @@ func TestRanksAround(t *testing.T) {
 		t.Errorf("mismatch (-want, got +)\n%v", diff)
 	}
 }
+
+func TestNewRanksRequestUniquePlayers(t *testing.T) {
+	type test struct {
+		params      map[string]string
+		gameDefault bool
+		wantUnique  bool
+		wantErr     bool
+	}
+	testCases := []test{
+		{params: map[string]string{"limit": "10"}, gameDefault: false, wantUnique: false},
+		{params: map[string]string{"limit": "10"}, gameDefault: true, wantUnique: true},
+		{params: map[string]string{"limit": "10", "unique_players": "true"}, gameDefault: false, wantUnique: true},
+		{params: map[string]string{"limit": "10", "unique_players": "false"}, gameDefault: true, wantUnique: false},
+		{params: map[string]string{"limit": "10", "unique_players": "sometimes"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		config := NewGameConfig("tag")
+		config.UniquePlayers = tc.gameDefault
+		got, err := NewRanksRequest(tc.params, config)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("want error, got nil, params %v", tc.params)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("want nil, got %v, params %v", err, tc.params)
+		}
+		if got.UniquePlayers != tc.wantUnique {
+			t.Errorf("want %v, got %v, params %v", tc.wantUnique, got.UniquePlayers, tc.params)
+		}
+	}
+}
+
+func TestNewRanksRequestWithoutLimit(t *testing.T) {
+	config := NewGameConfig("tag")
+	got, err := NewRanksRequest(map[string]string{"limit": "0"}, config)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if got.Limit != config.MaxRanksLimit {
+		t.Errorf("want %v, got %v", config.MaxRanksLimit, got.Limit)
+	}
+
+	scoreRequest, err := NewScoreRequest(map[string]string{"limit": "0"}, config)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if scoreRequest.Limit != config.MaxScoresLimit {
+		t.Errorf("want %v, got %v", config.MaxScoresLimit, scoreRequest.Limit)
+	}
+}
+
+func TestNewRanksRequestWindow(t *testing.T) {
+	got, err := NewRanksRequest(map[string]string{"limit": "10", "window": "daily"}, NewGameConfig("tag"))
+	if err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	want := NewPeriod(WindowDaily, time.Now(), time.UTC)
+	if got.Period.Key != want.Key {
+		t.Errorf("want %v, got %v", want.Key, got.Period.Key)
+	}
+
+	got, err = NewRanksRequest(map[string]string{"limit": "10"}, NewGameConfig("tag"))
+	if err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	if !got.Period.IsAllTime() {
+		t.Errorf("want all time, got %v", got.Period)
+	}
+
+	_, err = NewRanksRequest(map[string]string{"limit": "10", "window": "yearly"}, NewGameConfig("tag"))
+	if err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameRule is one check made of a player name, a game's NamePolicy is the rules it applies in order
+type NameRule interface {
+	// Check returns a NameViolation when the name breaks the rule
+	Check(name string) error
+}
+
+type NamePolicy []NameRule
+
+// NameViolation is the error for a player name refused by a rule, naming the rule for the player
+type NameViolation struct {
+	Rule   string
+	Reason string
+}
+
+func (v NameViolation) Error() string {
+	return fmt.Sprintf("Player name broke the %v rule: %v", v.Rule, v.Reason)
+}
+
+// func NamePolicy is the rules a player name must pass to be shown in the game's leaderboards
+func (c GameConfig) NamePolicy() NamePolicy {
+	return NamePolicy{
+		requiredName{},
+		nameLength{max: c.MaxNameLength},
+		nameCharacters{},
+		reservedNames{names: c.ReservedNames},
+		blockedNames{words: c.BlockedNames},
+	}
+}
+
+// func Check returns the violation of the first rule the name breaks, or nil when it passes them all
+func (p NamePolicy) Check(name string) error {
+	for _, rule := range p {
+		if err := rule.Check(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// func NormaliseName is the name as it is checked and stored, composed so that each accented letter counts as one character
+func NormaliseName(name string) string {
+	return strings.TrimSpace(norm.NFC.String(name))
+}
+
+// confusables maps characters commonly used in place of a latin letter to the letter
+// it is applied after lowercasing, so only lowercase lookalikes are listed
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', 'l': 'i', 'ı': 'i',
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'ԁ': 'd', 'ѕ': 's', 'т': 't', 'у': 'y', 'х': 'x',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// func FoldName reduces a name to the letters and digits it appears to spell, so that lookalikes of a name fold to the same string
+// accents, case, spacing and punctuation are dropped and confusable characters are replaced by the latin letter they resemble
+func FoldName(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if latin, ok := confusables[r]; ok {
+			r = latin
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+type requiredName struct{}
+
+func (requiredName) Check(name string) error {
+	if name == "" {
+		return NameViolation{Rule: "required", Reason: "Expected a player_name"}
+	}
+	return nil
+}
+
+// nameLength counts characters rather than bytes, so names in any script have the same limit
+type nameLength struct {
+	max int
+}
+
+func (l nameLength) Check(name string) error {
+	if utf8.RuneCountInString(name) > l.max {
+		return NameViolation{Rule: "length", Reason: fmt.Sprintf("Names may be at most %v characters", l.max)}
+	}
+	return nil
+}
+
+// nameCharacters refuses invisible characters, which can make a name look identical to another
+type nameCharacters struct{}
+
+func (nameCharacters) Check(name string) error {
+	for _, r := range name {
+		if !utf8.ValidRune(r) || r == utf8.RuneError || unicode.In(r, unicode.Cc, unicode.Cf, unicode.Co, unicode.Zl, unicode.Zp) {
+			return NameViolation{Rule: "characters", Reason: "Names may not contain control or invisible characters"}
+		}
+	}
+	return nil
+}
+
+// reservedNames refuses names which look like one of the names, so that players cannot impersonate the game's staff
+type reservedNames struct {
+	names []string
+}
+
+func (r reservedNames) Check(name string) error {
+	folded := FoldName(name)
+	for _, reserved := range r.names {
+		if folded == FoldName(reserved) {
+			return NameViolation{Rule: "reserved", Reason: "The name is reserved"}
+		}
+	}
+	return nil
+}
+
+// blockedNames refuses names containing any of the words, however they are disguised
+type blockedNames struct {
+	words []string
+}
+
+func (b blockedNames) Check(name string) error {
+	folded := FoldName(name)
+	for _, word := range b.words {
+		if strings.Contains(folded, FoldName(word)) {
+			return NameViolation{Rule: "blocklist", Reason: "The name contains a blocked word"}
+		}
+	}
+	return nil
+}
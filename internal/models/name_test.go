@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFoldName(t *testing.T) {
+	testCases := map[string]string{
+		"Admin":       "admin",
+		"ADM1N":       "admin",
+		"a.d.m.i.n":   "admin",
+		"Ádmín":       "admin",
+		"аdmin":       "admin", // cyrillic a
+		"ＡＤＭＩＮ":       "admin", // fullwidth
+		"Banana Lord": "bananaiord",
+	}
+
+	for name, want := range testCases {
+		got := FoldName(name)
+		if got != want {
+			t.Errorf("want %v, got %v, name %v", want, got, name)
+		}
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	config := NewGameConfig("tag")
+	config.MaxNameLength = 8
+	config.BlockedNames = []string{"heck"}
+
+	type test struct {
+		name     string
+		wantRule string
+	}
+	testCases := []test{
+		{name: "goose"},
+		{name: "ガチョウ"}, // 4 characters but 12 bytes
+		{name: "", wantRule: "required"},
+		{name: "goosegoose", wantRule: "length"},
+		{name: "goo\u200bse", wantRule: "characters"},
+		{name: "Mod", wantRule: "reserved"},
+		{name: "M0D", wantRule: "reserved"},
+		{name: "h3ck!", wantRule: "blocklist"},
+		{name: "H E C K", wantRule: "blocklist"},
+	}
+
+	for _, tc := range testCases {
+		err := config.NamePolicy().Check(tc.name)
+		if tc.wantRule == "" {
+			if err != nil {
+				t.Errorf("want nil, got %v, name %v", err, tc.name)
+			}
+			continue
+		}
+		var violation NameViolation
+		if !errors.As(err, &violation) {
+			t.Errorf("want NameViolation, got %v, name %v", err, tc.name)
+			continue
+		}
+		if violation.Rule != tc.wantRule {
+			t.Errorf("want %v, got %v, name %v", tc.wantRule, violation.Rule, tc.name)
+		}
+		if !strings.Contains(err.Error(), tc.wantRule) {
+			t.Errorf("want the rule named in %q", err.Error())
+		}
+	}
+}
+
+func TestNewScoreNormalisesName(t *testing.T) {
+	// e followed by a combining acute accent
+	score, err := NewScore(NewGameConfig("tag"), "goosey", `{"score": 10, "playerName": " René "}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if score.PlayerName != "René" {
+		t.Errorf("want %v, got %v", "René", score.PlayerName)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Profile is a player's current display name in a game, shown in place of the name each score was submitted with
+type Profile struct {
+	Game       string `json:"game"`
+	PlayerId   string `json:"playerId"`
+	PlayerName string `json:"playerName"`
+	Updated    int    `json:"updated"`
+}
+
+// func NewProfile reads the player's new name from a request body, enforcing the game's bounds on the player name
+func NewProfile(config GameConfig, playerId string, requestBody string) (Profile, error) {
+	type patchProfileRequestBody struct {
+		PlayerName string `json:"playerName"`
+	}
+	var body patchProfileRequestBody
+	err := json.Unmarshal([]byte(requestBody), &body)
+	if err != nil {
+		return Profile{}, fmt.Errorf("Failed to parse profile: %w", err)
+	}
+	playerName := NormaliseName(body.PlayerName)
+	err = config.NamePolicy().Check(playerName)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		Game:       config.Game,
+		PlayerId:   playerId,
+		PlayerName: playerName,
+		Updated:    int(time.Now().Unix()),
+	}, nil
+}
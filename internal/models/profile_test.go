@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewProfile(t *testing.T) {
+	profile, err := NewProfile(NewGameConfig("tetris"), "1", `{"playerName": "Banana Lord"}`)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if profile.Game != "tetris" || profile.PlayerId != "1" || profile.PlayerName != "Banana Lord" || profile.Updated == 0 {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+}
+
+func TestNewProfileInvalid(t *testing.T) {
+	testCases := []string{
+		`{}`,
+		`{"playerName": ""}`,
+		`{"playerName": "` + strings.Repeat("a", defaultMaxNameLength+1) + `"}`,
+		`not json`,
+	}
+
+	for _, body := range testCases {
+		_, err := NewProfile(NewGameConfig("tetris"), "1", body)
+		if err == nil {
+			t.Errorf("want error, got nil, body %v", body)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is a token bucket holding up to Burst tokens, which refills at Rate tokens per second and loses one token to each request
+// the zero RateLimit is disabled
+type RateLimit struct {
+	Burst int
+	Rate  float64
+}
+
+// TokenBucket is the state of a rate limit for one key, such as a player or an ip address
+type TokenBucket struct {
+	Tokens float64
+	// Updated is the unix time in milliseconds the tokens were counted at, a bucket which was never updated is full
+	Updated int64
+}
+
+// func ParseRateLimit reads a limit written as requests/seconds, which allows a burst of that many requests refilling over that many seconds
+// off disables the limit
+func ParseRateLimit(value string) (RateLimit, error) {
+	if value == "off" {
+		return RateLimit{}, nil
+	}
+	requests, seconds, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("Expected a rate limit of requests/seconds, got %q", value)
+	}
+	burst, err := strconv.Atoi(requests)
+	if err != nil || burst < 1 {
+		return RateLimit{}, fmt.Errorf("Expected a positive number of requests, got %q", requests)
+	}
+	period, err := strconv.Atoi(seconds)
+	if err != nil || period < 1 {
+		return RateLimit{}, fmt.Errorf("Expected a positive number of seconds, got %q", seconds)
+	}
+	return RateLimit{Burst: burst, Rate: float64(burst) / float64(period)}, nil
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Burst > 0 && l.Rate > 0
+}
+
+// func Take refills the bucket for the time since it was last updated and takes a token from it
+// the bool is false when the bucket has no whole token to take, the bucket is still refilled to now so that RetryAfter can be read from it
+func (l RateLimit) Take(bucket TokenBucket, now time.Time) (TokenBucket, bool) {
+	tokens := float64(l.Burst)
+	if bucket.Updated != 0 {
+		elapsed := float64(now.UnixMilli()-bucket.Updated) / 1000
+		tokens = math.Min(tokens, bucket.Tokens+math.Max(elapsed, 0)*l.Rate)
+	}
+	refilled := TokenBucket{Tokens: tokens, Updated: now.UnixMilli()}
+	if tokens < 1 {
+		return refilled, false
+	}
+	refilled.Tokens--
+	return refilled, true
+}
+
+// func RetryAfter is how long from when the bucket was updated until it holds a whole token again
+func (l RateLimit) RetryAfter(bucket TokenBucket) time.Duration {
+	if bucket.Tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - bucket.Tokens) / l.Rate * float64(time.Second))
+}
+
+// func Expiry is the unix time at which the bucket will be full again, after which it is the same as a bucket never updated and can be forgotten
+func (l RateLimit) Expiry(bucket TokenBucket) int {
+	refill := (float64(l.Burst) - bucket.Tokens) / l.Rate
+	return int(math.Ceil(float64(bucket.Updated)/1000 + refill))
+}
+
+// func PlayerRateLimitKey is the bucket of a player's requests to a game
+func PlayerRateLimitKey(game string, playerId string) string {
+	return fmt.Sprintf("ratelimit|player|%v|%v", game, playerId)
+}
+
+// func IpRateLimitKey is the bucket of every write from an ip address
+func IpRateLimitKey(sourceIp string) string {
+	return fmt.Sprintf("ratelimit|ip|%v", sourceIp)
+}
+
+// func IpReadRateLimitKey is the bucket of every read from an ip address, which is kept apart so that reading does not use up the tokens for writing
+func IpReadRateLimitKey(sourceIp string) string {
+	return fmt.Sprintf("ratelimit|ipread|%v", sourceIp)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := map[string]RateLimit{
+		"30/60": {Burst: 30, Rate: 0.5},
+		"5/1":   {Burst: 5, Rate: 5},
+		"off":   {},
+	}
+	for value, want := range testCases {
+		got, err := ParseRateLimit(value)
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	for _, value := range []string{"30", "0/60", "30/0", "a/60", "30/b", ""} {
+		_, err := ParseRateLimit(value)
+		if err == nil {
+			t.Errorf("want error, got nil, value %q", value)
+		}
+	}
+}
+
+func TestTakeToken(t *testing.T) {
+	limit := RateLimit{Burst: 2, Rate: 0.5}
+	now := time.Unix(1739253593, 0)
+
+	bucket, ok := limit.Take(TokenBucket{}, now)
+	if !ok || bucket.Tokens != 1 {
+		t.Fatalf("want a new bucket to be full, got %v", bucket)
+	}
+	bucket, ok = limit.Take(bucket, now)
+	if !ok || bucket.Tokens != 0 {
+		t.Fatalf("want the last token taken, got %v", bucket)
+	}
+	bucket, ok = limit.Take(bucket, now.Add(time.Second))
+	if ok {
+		t.Fatalf("want half a token to be refused, got %v", bucket)
+	}
+	if got := limit.RetryAfter(bucket); got != time.Second {
+		t.Errorf("want %v, got %v", time.Second, got)
+	}
+	bucket, ok = limit.Take(bucket, now.Add(2*time.Second))
+	if !ok {
+		t.Errorf("want a token after refilling, got %v", bucket)
+	}
+	if got, want := limit.Expiry(bucket), int(now.Unix())+6; got != want {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestTakeTokenRefillsToBurst(t *testing.T) {
+	limit := RateLimit{Burst: 2, Rate: 1}
+	now := time.Unix(1739253593, 0)
+
+	bucket, _ := limit.Take(TokenBucket{Tokens: 0, Updated: now.UnixMilli()}, now.Add(time.Hour))
+	if bucket.Tokens != 1 {
+		t.Errorf("want %v, got %v", 1, bucket.Tokens)
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuarantinedScore is a score held back from the leaderboards for breaking one of its game's rules, until an admin approves or discards it
+type QuarantinedScore struct {
+	Id         string   `json:"id"`
+	Game       string   `json:"game"`
+	PlayerId   string   `json:"playerId"`
+	PlayerName string   `json:"playerName"`
+	Score      int      `json:"score"`
+	Timestamp  int      `json:"timestamp"`
+	ReceivedAt int      `json:"receivedAt"`
+	Metadata   Metadata `json:"metadata,omitempty"`
+	// Reason is the rule the score broke
+	Reason string `json:"reason"`
+	// Expires is when the score would have expired from the leaderboards, after which it is discarded
+	Expires int `json:"expires"`
+}
+
+func NewQuarantinedScore(score Score, reason string) (QuarantinedScore, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return QuarantinedScore{}, fmt.Errorf("Failed to generate quarantined score id: %w", err)
+	}
+	return QuarantinedScore{
+		Id:         id,
+		Game:       score.Game,
+		PlayerId:   score.PlayerId,
+		PlayerName: score.PlayerName,
+		Score:      score.Score,
+		Timestamp:  score.Timestamp,
+		ReceivedAt: score.ReceivedAt,
+		Metadata:   score.Metadata,
+		Reason:     reason,
+		Expires:    score.Expiry(),
+	}, nil
+}
+
+// func Approved is the quarantined score as it is submitted to the leaderboards, entered into the periods it was submitted in
+func (q QuarantinedScore) Approved(config GameConfig) Score {
+	return Score{
+		Game:          q.Game,
+		Score:         q.Score,
+		PlayerId:      q.PlayerId,
+		PlayerName:    q.PlayerName,
+		Timestamp:     q.Timestamp,
+		ReceivedAt:    q.ReceivedAt,
+		Metadata:      q.Metadata,
+		Periods:       NewPeriods(q.Timestamp, config.Location()),
+		Order:         config.Order,
+		RetentionDays: config.RetentionDays,
+	}
+}
+
+// func CheckBounds returns the reason the score is outside the game's minimum and maximum, or an empty string when it is within them
+func (c GameConfig) CheckBounds(score Score) string {
+	if c.MinScore != nil && score.Score < *c.MinScore {
+		return fmt.Sprintf("Score must be at least %v", *c.MinScore)
+	}
+	if c.MaxScore != nil && score.Score > *c.MaxScore {
+		return fmt.Sprintf("Score must be at most %v", *c.MaxScore)
+	}
+	return ""
+}
+
+// func CheckImprovement returns the reason the score beats the player's previous best by more than the game allows, or an empty string when it does not
+func (c GameConfig) CheckImprovement(score Score, previousBest int) string {
+	if c.MaxImprovement == nil {
+		return ""
+	}
+	improvement := score.Score - previousBest
+	if c.Order == LowestFirst {
+		improvement = previousBest - score.Score
+	}
+	if improvement > *c.MaxImprovement {
+		return fmt.Sprintf("Score improves on the previous best of %v by more than %v", previousBest, *c.MaxImprovement)
+	}
+	return ""
+}
+
+// func CheckSubmissionRate returns the reason a player's submission is one too many for the minute, or an empty string when it is allowed
+// submissions is the number the player has made in the minute including this one
+func (c GameConfig) CheckSubmissionRate(submissions int) string {
+	if c.MaxSubmissionsPerMinute > 0 && submissions > c.MaxSubmissionsPerMinute {
+		return fmt.Sprintf("More than %v scores submitted in a minute", c.MaxSubmissionsPerMinute)
+	}
+	return ""
+}
+
+// func SubmissionCounter is the key counting the player's submissions to the game in the minute of now, and when the count can be forgotten
+func SubmissionCounter(score Score, now time.Time) (string, int) {
+	minute := now.Truncate(time.Minute)
+	return fmt.Sprintf("submissions|%v|%v|%v", score.Game, score.PlayerId, minute.Unix()), int(minute.Add(2 * time.Minute).Unix())
+}
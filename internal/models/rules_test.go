@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckBoundsDefaultsToPositiveScores(t *testing.T) {
+	config := NewGameConfig("tetris")
+
+	if reason := config.CheckBounds(Score{Score: -5}); reason == "" {
+		t.Errorf("want a negative score out of bounds")
+	}
+	if reason := config.CheckBounds(Score{Score: 1}); reason != "" {
+		t.Errorf("want nil, got %v", reason)
+	}
+	config.MinScore = nil
+	if reason := config.CheckBounds(Score{Score: -5}); reason != "" {
+		t.Errorf("want an unbounded game to accept negative scores, got %v", reason)
+	}
+}
+
+func TestCheckImprovement(t *testing.T) {
+	maxImprovement := 100
+	type test struct {
+		order        Order
+		score        int
+		previousBest int
+		want         bool
+	}
+	testCases := []test{
+		{order: HighestFirst, score: 200, previousBest: 100, want: false},
+		{order: HighestFirst, score: 201, previousBest: 100, want: true},
+		{order: HighestFirst, score: 10, previousBest: 1000, want: false},
+		{order: LowestFirst, score: 900, previousBest: 1000, want: false},
+		{order: LowestFirst, score: 899, previousBest: 1000, want: true},
+		{order: LowestFirst, score: 5000, previousBest: 1000, want: false},
+	}
+
+	for _, tc := range testCases {
+		config := NewGameConfig("tetris")
+		config.Order = tc.order
+		config.MaxImprovement = &maxImprovement
+		got := config.CheckImprovement(Score{Score: tc.score}, tc.previousBest) != ""
+		if got != tc.want {
+			t.Errorf("want %v, got %v, order %v score %v previous best %v", tc.want, got, tc.order, tc.score, tc.previousBest)
+		}
+	}
+
+	if reason := NewGameConfig("tetris").CheckImprovement(Score{Score: 1000000}, 1); reason != "" {
+		t.Errorf("want no limit on improvement by default, got %v", reason)
+	}
+}
+
+func TestCheckSubmissionRate(t *testing.T) {
+	config := NewGameConfig("tetris")
+	if reason := config.CheckSubmissionRate(1000); reason != "" {
+		t.Errorf("want no limit by default, got %v", reason)
+	}
+	config.MaxSubmissionsPerMinute = 3
+	if reason := config.CheckSubmissionRate(3); reason != "" {
+		t.Errorf("want nil, got %v", reason)
+	}
+	if reason := config.CheckSubmissionRate(4); reason == "" {
+		t.Errorf("want the fourth submission refused")
+	}
+}
+
+func TestSubmissionCounter(t *testing.T) {
+	score := Score{Game: "tetris", PlayerId: "1"}
+	key, expiry := SubmissionCounter(score, time.Date(2026, time.October, 16, 12, 30, 45, 0, time.UTC))
+	sameMinute, _ := SubmissionCounter(score, time.Date(2026, time.October, 16, 12, 30, 0, 0, time.UTC))
+	nextMinute, _ := SubmissionCounter(score, time.Date(2026, time.October, 16, 12, 31, 0, 0, time.UTC))
+
+	if key != sameMinute {
+		t.Errorf("want %v, got %v", key, sameMinute)
+	}
+	if key == nextMinute {
+		t.Errorf("want a new counter each minute, got %v", nextMinute)
+	}
+	if want := int(time.Date(2026, time.October, 16, 12, 32, 0, 0, time.UTC).Unix()); expiry != want {
+		t.Errorf("want %v, got %v", want, expiry)
+	}
+}
+
+func TestQuarantinedScoreApproved(t *testing.T) {
+	score := Score{Game: "tetris", PlayerId: "1", PlayerName: "goose", Score: 10, Timestamp: 1739253593}
+	quarantined, err := NewQuarantinedScore(score, "Too good")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if quarantined.Id == "" || quarantined.Reason != "Too good" || quarantined.Expires != score.Expiry() {
+		t.Errorf("unexpected quarantined score %+v", quarantined)
+	}
+
+	config := NewGameConfig("tetris")
+	config.Order = LowestFirst
+	config.RetentionDays = 30
+	want := score
+	want.Periods = NewPeriods(score.Timestamp, time.UTC)
+	want.Order = LowestFirst
+	want.RetentionDays = 30
+	if diff := cmp.Diff(want, quarantined.Approved(config)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
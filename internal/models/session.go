@@ -0,0 +1,101 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionDuration is how long a session token may be used to submit a score after it is issued
+const SessionDuration = 24 * time.Hour
+
+// Session is a game played by a player, issued by the server before play so that a score can prove when play began
+type Session struct {
+	Id       string `json:"id"`
+	Game     string `json:"game"`
+	PlayerId string `json:"playerId"`
+	Start    int    `json:"start"`
+}
+
+func NewSession(game string, playerId string, now time.Time) (Session, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return Session{}, fmt.Errorf("Failed to generate session id: %w", err)
+	}
+	return Session{Id: id, Game: game, PlayerId: playerId, Start: int(now.Unix())}, nil
+}
+
+// func Token encodes the session for the client and signs it with the server's secret, so the client cannot change when the session began
+func (s Session) Token(secret string) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal session: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signSession(secret, encoded), nil
+}
+
+// func ParseSessionToken reads a session from a token made by Token with the same secret
+func ParseSessionToken(secret string, token string) (Session, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Session{}, errors.New("Malformed session token")
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return Session{}, fmt.Errorf("Failed to decode session token signature: %w", err)
+	}
+	want, _ := hex.DecodeString(signSession(secret, encoded))
+	if !hmac.Equal(got, want) {
+		return Session{}, errors.New("Session token signature does not match")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Session{}, fmt.Errorf("Failed to decode session token: %w", err)
+	}
+	var session Session
+	err = json.Unmarshal(payload, &session)
+	if err != nil {
+		return Session{}, fmt.Errorf("Failed to parse session token: %w", err)
+	}
+	return session, nil
+}
+
+// func Expiry is the unix time after which the session can no longer be used
+func (s Session) Expiry() int {
+	return int(time.Unix(int64(s.Start), 0).Add(SessionDuration).Unix())
+}
+
+// func Check verifies the session belongs to the score's game and player and has not expired
+func (s Session) Check(score Score, now time.Time) error {
+	// a session of a game is played on any of its boards
+	if s.Game != PartitionGame(score.Game) || s.PlayerId != score.PlayerId {
+		return errors.New("Session belongs to another game or player")
+	}
+	if int(now.Unix()) >= s.Expiry() {
+		return errors.New("Session has expired")
+	}
+	return nil
+}
+
+// func CheckRate verifies the score could have been reached in the time since the session began, a maxScoreRate of zero allows any score
+func (s Session) CheckRate(score Score, maxScoreRate float64, now time.Time) error {
+	elapsed := now.Sub(time.Unix(int64(s.Start), 0)).Seconds()
+	if maxScoreRate > 0 && float64(score.Score) > maxScoreRate*elapsed {
+		return fmt.Errorf("Score %v is implausible after %.0f seconds of play", score.Score, elapsed)
+	}
+	return nil
+}
+
+func signSession(secret string, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(encoded))
+	return hex.EncodeToString(mac.Sum(nil))
+}
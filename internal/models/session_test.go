@@ -0,0 +1,88 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSessionToken(t *testing.T) {
+	const secret = "server secret"
+	session, err := NewSession("tetris", "1", time.Unix(1739253593, 0))
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	token, err := session.Token(secret)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+
+	got, err := ParseSessionToken(secret, token)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if diff := cmp.Diff(session, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = ParseSessionToken("another secret", token)
+	if err == nil {
+		t.Errorf("want error for a token signed with another secret, got nil")
+	}
+
+	// a client moving the start of its session back invalidates the signature
+	earlier := session
+	earlier.Start -= 3600
+	forged, _ := earlier.Token("guessed secret")
+	_, signature, _ := strings.Cut(token, ".")
+	payload, _, _ := strings.Cut(forged, ".")
+	_, err = ParseSessionToken(secret, payload+"."+signature)
+	if err == nil {
+		t.Errorf("want error for a forged token, got nil")
+	}
+
+	for _, malformed := range []string{"", "nodot", "a.zz"} {
+		_, err = ParseSessionToken(secret, malformed)
+		if err == nil {
+			t.Errorf("want error, got nil, token %q", malformed)
+		}
+	}
+}
+
+func TestSessionCheck(t *testing.T) {
+	start := time.Unix(1739253593, 0)
+	session := Session{Id: "s1", Game: "tetris", PlayerId: "1", Start: int(start.Unix())}
+
+	type test struct {
+		name    string
+		score   Score
+		rate    float64
+		now     time.Time
+		wantErr bool
+	}
+	testCases := []test{
+		{name: "plausible", score: Score{Game: "tetris", PlayerId: "1", Score: 100}, rate: 2, now: start.Add(time.Minute)},
+		{name: "unlimited", score: Score{Game: "tetris", PlayerId: "1", Score: 1000000}, now: start.Add(time.Second)},
+		{name: "too fast", score: Score{Game: "tetris", PlayerId: "1", Score: 121}, rate: 2, now: start.Add(time.Minute), wantErr: true},
+		{name: "board of the game", score: Score{Game: "tetris/level1", PlayerId: "1", Score: 1}, now: start.Add(time.Minute)},
+		{name: "another game", score: Score{Game: "golf", PlayerId: "1", Score: 1}, now: start.Add(time.Minute), wantErr: true},
+		{name: "board of another game", score: Score{Game: "golf/hole1", PlayerId: "1", Score: 1}, now: start.Add(time.Minute), wantErr: true},
+		{name: "another player", score: Score{Game: "tetris", PlayerId: "2", Score: 1}, now: start.Add(time.Minute), wantErr: true},
+		{name: "expired", score: Score{Game: "tetris", PlayerId: "1", Score: 1}, now: start.Add(SessionDuration), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		err := session.Check(tc.score, tc.now)
+		if err == nil {
+			err = session.CheckRate(tc.score, tc.rate, tc.now)
+		}
+		if tc.wantErr && err == nil {
+			t.Errorf("%v: want error, got nil", tc.name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%v: want nil, got %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureWindow is how far a signature's timestamp may be from the server's clock, a nonce only needs to be remembered for this long after its timestamp
+const SignatureWindow = 5 * time.Minute
+
+const (
+	minSigningSecretLength = 16
+	maxNonceLength         = 64
+)
+
+// Signature is the proof a client sends with a score that it holds the game's signing secret
+type Signature struct {
+	// Value is the hex encoded HMAC-SHA256 of the signed request
+	Value     string
+	Timestamp int64
+	// Nonce is chosen by the client and may be used only once per game within the signature window
+	Nonce string
+}
+
+// func Sign computes the signature of a request, each part is joined by a newline so that no part can be shifted into another
+func Sign(secret string, method string, path string, timestamp int64, nonce string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, path, strconv.FormatInt(timestamp, 10), nonce, body}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// func NewSignature reads a signature from the values of its headers
+func NewSignature(value string, timestamp string, nonce string) (Signature, error) {
+	if value == "" || timestamp == "" || nonce == "" {
+		return Signature{}, errors.New("Missing signature")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Signature{}, fmt.Errorf("Failed to parse signature timestamp: %w", err)
+	}
+	if len(nonce) > maxNonceLength {
+		return Signature{}, fmt.Errorf("Signature nonce must be at most %v characters", maxNonceLength)
+	}
+	return Signature{Value: value, Timestamp: ts, Nonce: nonce}, nil
+}
+
+// func Verify checks that the signature was made with secret over the request and that its timestamp is within the signature window of now
+// it does not check the nonce has not been used before, which needs the database
+func (s Signature) Verify(secret string, method string, path string, body string, now time.Time) error {
+	signedAt := time.Unix(s.Timestamp, 0)
+	if signedAt.Before(now.Add(-SignatureWindow)) || signedAt.After(now.Add(SignatureWindow)) {
+		return errors.New("Signature timestamp is outside the signature window")
+	}
+	got, err := hex.DecodeString(s.Value)
+	if err != nil {
+		return fmt.Errorf("Failed to decode signature: %w", err)
+	}
+	want, _ := hex.DecodeString(Sign(secret, method, path, s.Timestamp, s.Nonce, body))
+	if !hmac.Equal(got, want) {
+		return errors.New("Signature does not match")
+	}
+	return nil
+}
+
+// func NonceExpiry is the unix time after which the signature can no longer be replayed, so its nonce may be forgotten
+func (s Signature) NonceExpiry() int {
+	return int(time.Unix(s.Timestamp, 0).Add(SignatureWindow).Unix())
+}
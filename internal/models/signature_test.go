@@ -0,0 +1,109 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignatureVerify(t *testing.T) {
+	const secret = "0123456789abcdef"
+	const path = "/tetris/1/scores"
+	const body = `{"score": 10, "playerName": "goose"}`
+	now := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+	signedAt := now.Add(-time.Minute).Unix()
+
+	type test struct {
+		name      string
+		signature Signature
+		secret    string
+		body      string
+		wantErr   bool
+	}
+	testCases := []test{
+		{
+			name:      "valid",
+			signature: Signature{Value: Sign(secret, "PUT", path, signedAt, "n1", body), Timestamp: signedAt, Nonce: "n1"},
+			secret:    secret,
+			body:      body,
+		},
+		{
+			name:      "wrong secret",
+			signature: Signature{Value: Sign("fedcba9876543210", "PUT", path, signedAt, "n1", body), Timestamp: signedAt, Nonce: "n1"},
+			secret:    secret,
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:      "tampered body",
+			signature: Signature{Value: Sign(secret, "PUT", path, signedAt, "n1", body), Timestamp: signedAt, Nonce: "n1"},
+			secret:    secret,
+			body:      `{"score": 10000, "playerName": "goose"}`,
+			wantErr:   true,
+		},
+		{
+			name:      "swapped nonce",
+			signature: Signature{Value: Sign(secret, "PUT", path, signedAt, "n1", body), Timestamp: signedAt, Nonce: "n2"},
+			secret:    secret,
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:      "expired",
+			signature: Signature{Value: Sign(secret, "PUT", path, now.Add(-SignatureWindow-time.Second).Unix(), "n1", body), Timestamp: now.Add(-SignatureWindow - time.Second).Unix(), Nonce: "n1"},
+			secret:    secret,
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:      "from the future",
+			signature: Signature{Value: Sign(secret, "PUT", path, now.Add(SignatureWindow+time.Second).Unix(), "n1", body), Timestamp: now.Add(SignatureWindow + time.Second).Unix(), Nonce: "n1"},
+			secret:    secret,
+			body:      body,
+			wantErr:   true,
+		},
+		{
+			name:      "not hex",
+			signature: Signature{Value: "zz", Timestamp: signedAt, Nonce: "n1"},
+			secret:    secret,
+			body:      body,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := tc.signature.Verify(tc.secret, "PUT", path, tc.body, now)
+		if tc.wantErr && err == nil {
+			t.Errorf("%v: want error, got nil", tc.name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%v: want nil, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestNewSignature(t *testing.T) {
+	signature, err := NewSignature("abc", "1739253593", "n1")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if signature.Timestamp != 1739253593 || signature.Value != "abc" || signature.Nonce != "n1" {
+		t.Errorf("unexpected signature %+v", signature)
+	}
+	if got := signature.NonceExpiry(); got != 1739253593+int(SignatureWindow.Seconds()) {
+		t.Errorf("want %v, got %v", 1739253593+int(SignatureWindow.Seconds()), got)
+	}
+
+	invalid := [][3]string{
+		{"", "1739253593", "n1"},
+		{"abc", "", "n1"},
+		{"abc", "1739253593", ""},
+		{"abc", "yesterday", "n1"},
+		{"abc", "1739253593", string(make([]byte, 65))},
+	}
+	for _, headers := range invalid {
+		_, err := NewSignature(headers[0], headers[1], headers[2])
+		if err == nil {
+			t.Errorf("want error, got nil, headers %q", headers)
+		}
+	}
+}
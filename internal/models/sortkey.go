@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// sortKeyDigits is the number of decimal places a sort key gives to the time its score was received
+// dynamodb numbers hold 38 significant digits, which leaves room for any int score alongside them
+const sortKeyDigits = 10
+
+// maxSortKeyTimestamp is the last second a sort key can tell apart, in the year 2286
+const maxSortKeyTimestamp = 9999999999
+
+// func SortKey is the number dynamodb sorts the score by, the score itself with a fraction made from the time it was received
+// the fraction keeps each submission of the same score as its own item, and orders them so that the earliest received ranks first in the game's order
+// only submissions of the same score by the same player received in the same second share a sort key
+func (s Score) SortKey() string {
+	ts := int64(min(max(s.tieBreak(), 0), maxSortKeyTimestamp))
+	fraction := ts
+	if s.Order == HighestFirst {
+		// the highest sort key ranks first, so earlier timestamps need the larger fraction
+		fraction = maxSortKeyTimestamp - ts
+	}
+	return formatSortKey(s.Score, fraction)
+}
+
+// func tieBreak is the time which orders equal scores, when the score was received rather than when the client says it was achieved
+// scores stored before the time they were received was kept have their timestamp instead, as their sort keys were written with it
+func (s Score) tieBreak() int {
+	if s.ReceivedAt == 0 {
+		return s.Timestamp
+	}
+	return s.ReceivedAt
+}
+
+// func SortKeyBounds returns the lowest and highest sort keys that any submission of score may have
+func SortKeyBounds(score int) (string, string) {
+	return formatSortKey(score, 0), formatSortKey(score, maxSortKeyTimestamp)
+}
+
+// func ParseSortKey returns the score of a sort key, which is the sort key rounded down
+// sort keys written before they held a timestamp are whole scores, and parse the same way
+func ParseSortKey(sortKey string) (int, error) {
+	r, ok := new(big.Rat).SetString(sortKey)
+	if !ok {
+		return 0, fmt.Errorf("Failed to parse sort key %q", sortKey)
+	}
+	// euclidean division by the always positive denominator rounds down, negative scores included
+	score := new(big.Int).Div(r.Num(), r.Denom())
+	if !score.IsInt64() {
+		return 0, fmt.Errorf("Sort key %q is out of range for a score", sortKey)
+	}
+	return int(score.Int64()), nil
+}
+
+// func formatSortKey writes score plus fraction ten billionths as a decimal, so no precision is lost to floating point
+func formatSortKey(score int, fraction int64) string {
+	if fraction == 0 {
+		return fmt.Sprint(score)
+	}
+	if score >= 0 {
+		return fmt.Sprintf("%d.%0*d", score, sortKeyDigits, fraction)
+	}
+	// a negative score plus the fraction is nearer zero, such as -5 plus 0.3 which is -4.7
+	whole := -(int64(score) + 1)
+	return fmt.Sprintf("-%d.%0*d", whole, sortKeyDigits, maxSortKeyTimestamp+1-fraction)
+}
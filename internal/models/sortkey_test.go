@@ -0,0 +1,114 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSortKey(t *testing.T) {
+	testCases := []struct {
+		score Score
+		want  string
+	}{
+		{Score{Score: 505, Timestamp: 1739253593}, "505.8260746406"},
+		{Score{Score: 505, Timestamp: 1739253593, Order: LowestFirst}, "505.1739253593"},
+		{Score{Score: 505, Timestamp: 0, Order: LowestFirst}, "505"},
+		{Score{Score: -5, Timestamp: 3000000000, Order: LowestFirst}, "-4.7000000000"},
+	}
+	for _, tc := range testCases {
+		got := tc.score.SortKey()
+		if got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+		score, err := ParseSortKey(got)
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if score != tc.score.Score {
+			t.Errorf("want %v, got %v", tc.score.Score, score)
+		}
+	}
+}
+
+func TestSortKeyRanksEarliestFirst(t *testing.T) {
+	for _, order := range []Order{HighestFirst, LowestFirst} {
+		earlier := Score{Score: -5, Timestamp: 100, Order: order}
+		later := Score{Score: -5, Timestamp: 200, Order: order}
+		worse := Score{Score: -6, Timestamp: 0, Order: order}
+		if order == LowestFirst {
+			worse.Score = -4
+		}
+
+		keys := []string{earlier.SortKey(), later.SortKey(), worse.SortKey()}
+		for i := 1; i < len(keys); i++ {
+			c := compareSortKeys(t, keys[i-1], keys[i])
+			if order == HighestFirst && c <= 0 || order == LowestFirst && c >= 0 {
+				t.Errorf("want %v to rank before %v for order %v", keys[i-1], keys[i], order)
+			}
+		}
+	}
+}
+
+func TestSortKeyRanksEarliestReceivedFirst(t *testing.T) {
+	for _, order := range []Order{HighestFirst, LowestFirst} {
+		// the later score says it was achieved first, but was received after
+		earlier := Score{Score: 505, Timestamp: 300, ReceivedAt: 400, Order: order}
+		later := Score{Score: 505, Timestamp: 100, ReceivedAt: 500, Order: order}
+
+		c := compareSortKeys(t, earlier.SortKey(), later.SortKey())
+		if order == HighestFirst && c <= 0 || order == LowestFirst && c >= 0 {
+			t.Errorf("want %v to rank before %v for order %v", earlier.SortKey(), later.SortKey(), order)
+		}
+	}
+}
+
+func TestSortKeyBounds(t *testing.T) {
+	for _, score := range []int{505, -5} {
+		first, last := SortKeyBounds(score)
+		for _, order := range []Order{HighestFirst, LowestFirst} {
+			key := Score{Score: score, Timestamp: 1739253593, Order: order}.SortKey()
+			if compareSortKeys(t, first, key) > 0 || compareSortKeys(t, key, last) > 0 {
+				t.Errorf("want %v between %v and %v", key, first, last)
+			}
+		}
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	testCases := map[string]int{
+		"505":          505,
+		"505.5":        505,
+		"-4.7":         -5,
+		"-5":           -5,
+		"1.0000000001": 1,
+	}
+	for sortKey, want := range testCases {
+		got, err := ParseSortKey(sortKey)
+		if err != nil {
+			t.Fatalf("want nil, got %v", err)
+		}
+		if got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+
+	for _, sortKey := range []string{"", "five", "1e30"} {
+		_, err := ParseSortKey(sortKey)
+		if err == nil {
+			t.Errorf("want error, got nil, sort key %q", sortKey)
+		}
+	}
+}
+
+func compareSortKeys(t *testing.T, a string, b string) int {
+	t.Helper()
+	ra, ok := new(big.Rat).SetString(a)
+	if !ok {
+		t.Fatalf("want a number, got %v", a)
+	}
+	rb, ok := new(big.Rat).SetString(b)
+	if !ok {
+		t.Fatalf("want a number, got %v", b)
+	}
+	return ra.Cmp(rb)
+}
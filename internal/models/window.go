@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Window is the span of time a leaderboard covers, each window restarts at the beginning of its period
+type Window string
+
+const (
+	WindowAllTime Window = "alltime"
+	WindowDaily   Window = "daily"
+	WindowWeekly  Window = "weekly"
+	WindowMonthly Window = "monthly"
+)
+
+// PeriodicWindows are every window other than all time, a score belongs to one period of each
+var PeriodicWindows = []Window{WindowDaily, WindowWeekly, WindowMonthly}
+
+// Period is a single occurrence of a window, such as the day 2026-10-16
+// the zero Period is all time
+type Period struct {
+	Window Window
+	// Key names the period uniquely across every window, 2026-10-16 for a day, 2026-W42 for an iso week and 2026-10 for a month
+	Key string
+	// Start is the first unix second within the period
+	Start int
+	// End is the first unix second after the period
+	End int
+}
+
+func ParseWindow(window string) (Window, error) {
+	switch w := Window(window); w {
+	case WindowAllTime, WindowDaily, WindowWeekly, WindowMonthly:
+		return w, nil
+	}
+	return "", fmt.Errorf("Unknown window %q, expected one of alltime, daily, weekly or monthly", window)
+}
+
+// func NewPeriod returns the period of window which contains t, with boundaries falling at midnight in loc
+// weeks begin on monday as in iso 8601
+func NewPeriod(window Window, t time.Time, loc *time.Location) Period {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	var start, end time.Time
+	var key string
+	switch window {
+	case WindowDaily:
+		start = day
+		end = start.AddDate(0, 0, 1)
+		key = start.Format("2006-01-02")
+	case WindowWeekly:
+		daysSinceMonday := (int(day.Weekday()) + 6) % 7
+		start = day.AddDate(0, 0, -daysSinceMonday)
+		end = start.AddDate(0, 0, 7)
+		year, week := start.ISOWeek()
+		key = fmt.Sprintf("%04d-W%02d", year, week)
+	case WindowMonthly:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 1, 0)
+		key = start.Format("2006-01")
+	default:
+		return Period{}
+	}
+
+	return Period{
+		Window: window,
+		Key:    key,
+		Start:  int(start.Unix()),
+		End:    int(end.Unix()),
+	}
+}
+
+// func NewPeriods returns the period of every periodic window which contains the unix timestamp
+func NewPeriods(timestamp int, loc *time.Location) []Period {
+	t := time.Unix(int64(timestamp), 0)
+	periods := make([]Period, 0, len(PeriodicWindows))
+	for _, window := range PeriodicWindows {
+		periods = append(periods, NewPeriod(window, t, loc))
+	}
+	return periods
+}
+
+func (p Period) IsAllTime() bool {
+	return p.Key == ""
+}
+
+// func Leaderboard names the leaderboard holding the game's scores for the period
+func (p Period) Leaderboard(game string) string {
+	if p.IsAllTime() {
+		return game
+	}
+	return fmt.Sprintf("%s|%s", game, p.Key)
+}
+
+// func Bounds returns the first unix second within the period and the first after it, all time is unbounded
+func (p Period) Bounds() (int, int) {
+	if p.IsAllTime() {
+		return math.MinInt, math.MaxInt
+	}
+	return p.Start, p.End
+}
+
+// func Expiry is the unix time after which storage may discard scores held only for the period
+// a day of grace lets requests in flight at the end of the period finish
+func (p Period) Expiry() int {
+	const grace = 24 * 60 * 60
+	return p.End + grace
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewPeriod(t *testing.T) {
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	date := func(year int, month time.Month, day int, hour int, loc *time.Location) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+	type test struct {
+		window Window
+		t      time.Time
+		loc    *time.Location
+		want   Period
+	}
+	testCases := []test{
+		{
+			window: WindowDaily,
+			t:      date(2026, time.October, 16, 23, time.UTC),
+			loc:    time.UTC,
+			want:   Period{WindowDaily, "2026-10-16", int(date(2026, time.October, 16, 0, time.UTC).Unix()), int(date(2026, time.October, 17, 0, time.UTC).Unix())},
+		},
+		{
+			// 23:00 utc is already the next morning in auckland
+			window: WindowDaily,
+			t:      date(2026, time.October, 16, 23, time.UTC),
+			loc:    auckland,
+			want:   Period{WindowDaily, "2026-10-17", int(date(2026, time.October, 17, 0, auckland).Unix()), int(date(2026, time.October, 18, 0, auckland).Unix())},
+		},
+		{
+			window: WindowWeekly,
+			t:      date(2026, time.October, 18, 12, time.UTC),
+			loc:    time.UTC,
+			want:   Period{WindowWeekly, "2026-W42", int(date(2026, time.October, 12, 0, time.UTC).Unix()), int(date(2026, time.October, 19, 0, time.UTC).Unix())},
+		},
+		{
+			// the iso week holding new year's day belongs to the year its monday falls in
+			window: WindowWeekly,
+			t:      date(2027, time.January, 1, 12, time.UTC),
+			loc:    time.UTC,
+			want:   Period{WindowWeekly, "2026-W53", int(date(2026, time.December, 28, 0, time.UTC).Unix()), int(date(2027, time.January, 4, 0, time.UTC).Unix())},
+		},
+		{
+			window: WindowMonthly,
+			t:      date(2026, time.December, 31, 23, time.UTC),
+			loc:    time.UTC,
+			want:   Period{WindowMonthly, "2026-12", int(date(2026, time.December, 1, 0, time.UTC).Unix()), int(date(2027, time.January, 1, 0, time.UTC).Unix())},
+		},
+		{
+			// auckland's daylight saving starts in september, so october is 23 hours short of 31 days
+			window: WindowMonthly,
+			t:      date(2026, time.October, 16, 12, auckland),
+			loc:    auckland,
+			want:   Period{WindowMonthly, "2026-10", int(date(2026, time.October, 1, 0, auckland).Unix()), int(date(2026, time.November, 1, 0, auckland).Unix())},
+		},
+		{
+			window: WindowAllTime,
+			t:      date(2026, time.October, 16, 12, time.UTC),
+			loc:    time.UTC,
+			want:   Period{},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := NewPeriod(tc.window, tc.t, tc.loc)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("mismatch for %v at %v (-want +got):\n%s", tc.window, tc.t, diff)
+		}
+	}
+}
+
+func TestNewPeriods(t *testing.T) {
+	timestamp := int(time.Date(2026, time.October, 16, 10, 0, 0, 0, time.UTC).Unix())
+	got := NewPeriods(timestamp, nil)
+	wantKeys := []string{"2026-10-16", "2026-W42", "2026-10"}
+	if len(got) != len(wantKeys) {
+		t.Fatalf("want %v periods, got %v", len(wantKeys), len(got))
+	}
+	for i, period := range got {
+		if period.Key != wantKeys[i] {
+			t.Errorf("want %v, got %v", wantKeys[i], period.Key)
+		}
+		if timestamp < period.Start || timestamp >= period.End {
+			t.Errorf("want %v within [%v, %v)", timestamp, period.Start, period.End)
+		}
+	}
+}
+
+func TestPeriodLeaderboard(t *testing.T) {
+	if got := (Period{}).Leaderboard("tag"); got != "tag" {
+		t.Errorf("want tag, got %v", got)
+	}
+	period := NewPeriod(WindowDaily, time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), time.UTC)
+	if got := period.Leaderboard("tag"); got != "tag|2026-10-16" {
+		t.Errorf("want tag|2026-10-16, got %v", got)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	for _, window := range []string{"alltime", "daily", "weekly", "monthly"} {
+		got, err := ParseWindow(window)
+		if err != nil {
+			t.Errorf("want nil, got %v", err)
+		}
+		if string(got) != window {
+			t.Errorf("want %v, got %v", window, got)
+		}
+	}
+	if _, err := ParseWindow("hourly"); err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
@@ -0,0 +1,852 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresScoreDatabase ranks scores with window functions, so positions are true positions across every score in a game rather than a count within one page
+type PostgresScoreDatabase struct {
+	pool *pgxpool.Pool
+}
+
+var pgPool *pgxpool.Pool
+var once sync.Once
+
+// migrationLockId is an arbitrary key for the advisory lock held while migrating, so that instances starting together do not race
+const migrationLockId = 6198241
+
+// migrations are applied in order and tracked in the schema_migrations table, so only append to this list
+var migrations = []string{
+	`CREATE TABLE scores (
+		player_id   TEXT   NOT NULL,
+		game        TEXT   NOT NULL,
+		score       BIGINT NOT NULL,
+		player_name TEXT   NOT NULL,
+		ts          BIGINT NOT NULL,
+		ttl         BIGINT NOT NULL,
+		PRIMARY KEY (player_id, game, score)
+	);
+	CREATE INDEX game_scores_index ON scores (game, score DESC);`,
+	// windowed leaderboards read a game's scores by submission time
+	`CREATE INDEX game_timestamps_index ON scores (game, ts);`,
+	// the game registry, each config is stored as the json the admin api accepts
+	`CREATE TABLE games (
+		game   TEXT  NOT NULL PRIMARY KEY,
+		config JSONB NOT NULL
+	);`,
+	// nonces of signed score submissions, kept until their signatures can no longer be replayed
+	`CREATE TABLE nonces (
+		game  TEXT   NOT NULL,
+		nonce TEXT   NOT NULL,
+		ttl   BIGINT NOT NULL,
+		PRIMARY KEY (game, nonce)
+	);`,
+	// api keys are looked up by id, only a hash of each key is stored
+	`CREATE TABLE api_keys (
+		id      TEXT    NOT NULL PRIMARY KEY,
+		game    TEXT    NOT NULL,
+		role    INTEGER NOT NULL,
+		name    TEXT    NOT NULL,
+		created BIGINT  NOT NULL,
+		hash    TEXT    NOT NULL
+	);`,
+	// counters are named by their caller, which starts a new counter for each period it counts over
+	`CREATE TABLE counters (
+		name  TEXT    NOT NULL PRIMARY KEY,
+		count INTEGER NOT NULL,
+		ttl   BIGINT  NOT NULL
+	);`,
+	// scores held back from the leaderboards until an admin approves or discards them
+	`CREATE TABLE quarantine (
+		game        TEXT    NOT NULL,
+		id          TEXT    NOT NULL,
+		player_id   TEXT    NOT NULL,
+		player_name TEXT    NOT NULL,
+		score       BIGINT  NOT NULL,
+		ts          BIGINT  NOT NULL,
+		reason      TEXT    NOT NULL,
+		ttl         BIGINT  NOT NULL,
+		PRIMARY KEY (game, id)
+	);`,
+	// banned players, a ban lasts until an admin lifts it
+	`CREATE TABLE bans (
+		game      TEXT   NOT NULL,
+		player_id TEXT   NOT NULL,
+		reason    TEXT   NOT NULL,
+		created   BIGINT NOT NULL,
+		PRIMARY KEY (game, player_id)
+	);`,
+	// player display names, shown in place of the name each score was submitted with
+	`CREATE TABLE profiles (
+		game        TEXT   NOT NULL,
+		player_id   TEXT   NOT NULL,
+		player_name TEXT   NOT NULL,
+		updated     BIGINT NOT NULL,
+		PRIMARY KEY (game, player_id)
+	);`,
+	// token buckets of the rate limits, a bucket past its ttl has refilled and can be removed
+	`CREATE TABLE buckets (
+		name    TEXT             NOT NULL PRIMARY KEY,
+		tokens  DOUBLE PRECISION NOT NULL,
+		updated BIGINT           NOT NULL,
+		ttl     BIGINT           NOT NULL
+	);`,
+	// responses to requests made with an Idempotency-Key, a status of zero is a request still in progress
+	`CREATE TABLE idempotency (
+		name         TEXT    NOT NULL PRIMARY KEY,
+		request_hash TEXT    NOT NULL,
+		status       INTEGER NOT NULL,
+		body         TEXT    NOT NULL,
+		ttl          BIGINT  NOT NULL
+	);`,
+	// each submission of a score is kept, equal scores of a player are told apart by when they were submitted
+	`ALTER TABLE scores DROP CONSTRAINT scores_pkey, ADD PRIMARY KEY (player_id, game, score, ts);`,
+	// a score may say it was achieved before it was received, the time it was received is kept for auditing and is ts for scores stored before
+	`ALTER TABLE scores ADD COLUMN received_ts BIGINT NOT NULL DEFAULT 0;
+	UPDATE scores SET received_ts = ts;
+	ALTER TABLE quarantine ADD COLUMN received_ts BIGINT NOT NULL DEFAULT 0;
+	UPDATE quarantine SET received_ts = ts;`,
+	// how each score was achieved, as a json object of strings which ranks can be filtered by with containment
+	`ALTER TABLE scores ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+	ALTER TABLE quarantine ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';`,
+	// groups of players ranked among themselves
+	`CREATE TABLE player_groups (
+		game       TEXT   NOT NULL,
+		id         TEXT   NOT NULL,
+		name       TEXT   NOT NULL,
+		player_ids TEXT[] NOT NULL,
+		updated    BIGINT NOT NULL,
+		PRIMARY KEY (game, id)
+	);`,
+}
+
+func New(ctx context.Context) (PostgresScoreDatabase, error) {
+	connString := os.Getenv("POSTGRES_URL")
+	if connString == "" {
+		return PostgresScoreDatabase{}, errors.New("No postgres url specified in env")
+	}
+
+	var onceErr error
+	once.Do(func() {
+		db, err := Open(ctx, connString)
+		if err != nil {
+			onceErr = err
+			return
+		}
+		pgPool = db.pool
+	})
+	if onceErr != nil {
+		return PostgresScoreDatabase{}, onceErr
+	}
+	if pgPool == nil {
+		return PostgresScoreDatabase{}, errors.New("Failed to connect to postgres")
+	}
+
+	return PostgresScoreDatabase{pool: pgPool}, nil
+}
+
+// func Open connects to the postgres database at connString and brings its schema up to date
+func Open(ctx context.Context, connString string) (PostgresScoreDatabase, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return PostgresScoreDatabase{}, fmt.Errorf("Failed to connect to postgres: %w", err)
+	}
+
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return PostgresScoreDatabase{}, err
+	}
+
+	_, err = pool.Exec(ctx, `DELETE FROM scores WHERE ttl <= $1`, time.Now().Unix())
+	if err != nil {
+		pool.Close()
+		return PostgresScoreDatabase{}, fmt.Errorf("Failed to remove expired scores: %w", err)
+	}
+	for _, table := range []string{"nonces", "counters", "quarantine", "buckets", "idempotency"} {
+		_, err = pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %v WHERE ttl <= $1`, table), time.Now().Unix())
+		if err != nil {
+			pool.Close()
+			return PostgresScoreDatabase{}, fmt.Errorf("Failed to remove expired %v: %w", table, err)
+		}
+	}
+
+	return PostgresScoreDatabase{pool: pool}, nil
+}
+
+func (p PostgresScoreDatabase) Close() {
+	p.pool.Close()
+}
+
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin migrations: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockId)
+	if err != nil {
+		return fmt.Errorf("Failed to lock for migrations: %w", err)
+	}
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("Failed to create schema_migrations: %w", err)
+	}
+
+	var version int
+	err = tx.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("Failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		_, err = tx.Exec(ctx, migrations[i])
+		if err != nil {
+			return fmt.Errorf("Failed to apply migration %v: %w", i+1, err)
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, i+1)
+		if err != nil {
+			return fmt.Errorf("Failed to record migration %v: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+const insertScore = `
+	INSERT INTO scores (player_id, game, score, player_name, ts, received_ts, metadata, ttl)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (player_id, game, score, ts) DO UPDATE SET
+		player_name = excluded.player_name,
+		metadata = excluded.metadata,
+		ttl = excluded.ttl`
+
+func (p PostgresScoreDatabase) PutScore(ctx context.Context, score models.Score) error {
+	_, err := p.pool.Exec(ctx, insertScore, score.PlayerId, score.Game, score.Score, score.PlayerName, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Expiry())
+	if err != nil {
+		return fmt.Errorf("Failed to insert score: %w", err)
+	}
+	return nil
+}
+
+// func PutScores sends the inserts as one batch in a transaction, so either all of the scores are stored or none are
+func (p PostgresScoreDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to begin inserting scores: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, score := range scores {
+		batch.Queue(insertScore, score.PlayerId, score.Game, score.Score, score.PlayerName, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Expiry())
+	}
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return fmt.Errorf("Failed to insert scores: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Failed to commit scores: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) GetTopPlayerScores(ctx context.Context, scoreRequest models.PlayerScoreRequest) ([]models.Score, error) {
+	start, end := scoreRequest.Period.Bounds()
+	rows, err := p.pool.Query(ctx, `
+		SELECT player_id, game, score, player_name, ts, received_ts, metadata::text FROM scores
+		WHERE player_id = $1 AND game = $2 AND ttl > $3 AND ts >= $4 AND ts < $5 AND metadata @> $6::jsonb
+		ORDER BY score `+direction(scoreRequest.Order)+`, received_ts ASC, ts ASC
+		LIMIT $7`,
+		scoreRequest.PlayerId, scoreRequest.Game, time.Now().Unix(), start, end, scoreRequest.Filter.Encode(), limitOrAll(scoreRequest.Limit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player scores: %w", err)
+	}
+
+	scores, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Score, error) {
+		var score models.Score
+		var metadata string
+		err := row.Scan(&score.PlayerId, &score.Game, &score.Score, &score.PlayerName, &score.Timestamp, &score.ReceivedAt, &metadata)
+		if err != nil {
+			return score, err
+		}
+		score.Metadata, err = models.DecodeMetadata(metadata)
+		return score, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read player scores: %w", err)
+	}
+	return scores, nil
+}
+
+// func rankedScores is a subquery selecting the scores ranked in a game, it takes the game, the current time, the period bounds and the filter as $1 to $5
+// the period bounds are a range over game_timestamps_index, so a window reads only the scores submitted within it
+func rankedScores(uniquePlayers bool, order models.Order) string {
+	if uniquePlayers {
+		return `
+			SELECT DISTINCT ON (player_id) player_id, score, player_name, ts, received_ts, metadata FROM scores
+			WHERE game = $1 AND ttl > $2 AND ts >= $3 AND ts < $4 AND metadata @> $5::jsonb
+			ORDER BY player_id, score ` + direction(order) + `, received_ts ASC, ts ASC`
+	}
+	return `
+			SELECT player_id, score, player_name, ts, received_ts, metadata FROM scores
+			WHERE game = $1 AND ttl > $2 AND ts >= $3 AND ts < $4 AND metadata @> $5::jsonb`
+}
+
+func (p PostgresScoreDatabase) GetGame(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	var config models.GameConfig
+	err := p.pool.QueryRow(ctx, `SELECT config FROM games WHERE game = $1`, game).Scan(&config)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.GameConfig{}, false, nil
+	}
+	if err != nil {
+		return models.GameConfig{}, false, fmt.Errorf("Failed to query game: %w", err)
+	}
+	return config, true, nil
+}
+
+func (p PostgresScoreDatabase) GetGames(ctx context.Context) ([]models.GameConfig, error) {
+	rows, err := p.pool.Query(ctx, `SELECT config FROM games ORDER BY game`)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query games: %w", err)
+	}
+	configs, err := pgx.CollectRows(rows, pgx.RowTo[models.GameConfig])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read games: %w", err)
+	}
+	return configs, nil
+}
+
+// func PutGame stores the config as jsonb, which pgx encodes with encoding/json
+func (p PostgresScoreDatabase) PutGame(ctx context.Context, config models.GameConfig) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO games (game, config) VALUES ($1, $2)
+		ON CONFLICT (game) DO UPDATE SET config = excluded.config`,
+		config.Game, config,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert game: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) DeleteGame(ctx context.Context, game string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM games WHERE game = $1`, game)
+	if err != nil {
+		return fmt.Errorf("Failed to delete game: %w", err)
+	}
+	return nil
+}
+
+// func ClaimNonce records the nonce as used for the game until expiry, reporting false if it is already in use
+// a nonce still within its ttl leaves the conflicting row untouched, so no row is affected
+func (p PostgresScoreDatabase) ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO nonces (game, nonce, ttl) VALUES ($1, $2, $3)
+		ON CONFLICT (game, nonce) DO UPDATE SET ttl = excluded.ttl
+		WHERE nonces.ttl <= $4`,
+		game, nonce, expiry, time.Now().Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("Failed to insert nonce: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (p PostgresScoreDatabase) ReleaseNonce(ctx context.Context, game string, nonce string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM nonces WHERE game = $1 AND nonce = $2`, game, nonce)
+	if err != nil {
+		return fmt.Errorf("Failed to delete nonce: %w", err)
+	}
+	return nil
+}
+
+// func ClaimIdempotencyKey stores the pending record unless its key is already in use, in which case the record using it is returned with false
+// a record still within its ttl leaves the conflicting row untouched, so no row is affected
+func (p PostgresScoreDatabase) ClaimIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO idempotency (name, request_hash, status, body, ttl) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			body = excluded.body,
+			ttl = excluded.ttl
+		WHERE idempotency.ttl <= $6`,
+		record.Key, record.RequestHash, record.StatusCode, record.Body, record.Expires, time.Now().Unix(),
+	)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to insert idempotency record: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return record, true, nil
+	}
+
+	existing := models.IdempotencyRecord{Key: record.Key}
+	err = p.pool.QueryRow(ctx, `SELECT request_hash, status, body, ttl FROM idempotency WHERE name = $1`, record.Key).
+		Scan(&existing.RequestHash, &existing.StatusCode, &existing.Body, &existing.Expires)
+	// the record was released by its request failing after the insert, which is still a request in progress to this one
+	if errors.Is(err, pgx.ErrNoRows) {
+		return existing, false, nil
+	}
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to query idempotency record: %w", err)
+	}
+	return existing, false, nil
+}
+
+func (p PostgresScoreDatabase) PutIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO idempotency (name, request_hash, status, body, ttl) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			body = excluded.body,
+			ttl = excluded.ttl`,
+		record.Key, record.RequestHash, record.StatusCode, record.Body, record.Expires,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to put idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM idempotency WHERE name = $1`, key)
+	if err != nil {
+		return fmt.Errorf("Failed to delete idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) GetApiKey(ctx context.Context, id string) (models.ApiKey, bool, error) {
+	rows, err := p.pool.Query(ctx, `SELECT id, game, role, name, created, hash FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to query api key: %w", err)
+	}
+	key, err := pgx.CollectExactlyOneRow(rows, scanApiKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ApiKey{}, false, nil
+	}
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to read api key: %w", err)
+	}
+	return key, true, nil
+}
+
+func (p PostgresScoreDatabase) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	rows, err := p.pool.Query(ctx, `SELECT id, game, role, name, created, hash FROM api_keys ORDER BY game, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query api keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, scanApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (p PostgresScoreDatabase) PutApiKey(ctx context.Context, key models.ApiKey) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO api_keys (id, game, role, name, created, hash) VALUES ($1, $2, $3, $4, $5, $6)`,
+		key.Id, key.Game, int(key.Role), key.Name, key.Created, key.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) DeleteApiKey(ctx context.Context, id string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete api key: %w", err)
+	}
+	return nil
+}
+
+// func Increment adds one to the counter and returns its new count, a counter past its expiry starts again from zero
+func (p PostgresScoreDatabase) Increment(ctx context.Context, key string, expiry int) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx, `
+		INSERT INTO counters (name, count, ttl) VALUES ($1, 1, $2)
+		ON CONFLICT (name) DO UPDATE SET
+			count = CASE WHEN counters.ttl <= $3 THEN 1 ELSE counters.count + 1 END,
+			ttl = excluded.ttl
+		RETURNING count`,
+		key, expiry, time.Now().Unix(),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to increment counter: %w", err)
+	}
+	return count, nil
+}
+
+// func TakeToken locks the bucket's row while it is refilled and taken from, so concurrent requests for a key wait for each other
+func (p PostgresScoreDatabase) TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to begin taking token: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// a new bucket is inserted before locking, as there would be no row to lock when two requests create it at once
+	_, err = tx.Exec(ctx, `
+		INSERT INTO buckets (name, tokens, updated, ttl) VALUES ($1, $2, 0, $3)
+		ON CONFLICT (name) DO NOTHING`,
+		key, float64(limit.Burst), now.Unix(),
+	)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to insert token bucket: %w", err)
+	}
+	var bucket models.TokenBucket
+	err = tx.QueryRow(ctx, `SELECT tokens, updated FROM buckets WHERE name = $1 FOR UPDATE`, key).Scan(&bucket.Tokens, &bucket.Updated)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to get token bucket: %w", err)
+	}
+	taken, ok := limit.Take(bucket, now)
+	if !ok {
+		return taken, false, nil
+	}
+	_, err = tx.Exec(ctx, `UPDATE buckets SET tokens = $2, updated = $3, ttl = $4 WHERE name = $1`,
+		key, taken.Tokens, taken.Updated, limit.Expiry(taken),
+	)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to update token bucket: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to commit token bucket: %w", err)
+	}
+	return taken, true, nil
+}
+
+func (p PostgresScoreDatabase) PutQuarantinedScore(ctx context.Context, score models.QuarantinedScore) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO quarantine (game, id, player_id, player_name, score, ts, received_ts, metadata, reason, ttl)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		score.Game, score.Id, score.PlayerId, score.PlayerName, score.Score, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Reason, score.Expires,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert quarantined score: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, game, player_id, player_name, score, ts, received_ts, metadata::text, reason, ttl FROM quarantine
+		WHERE game = $1 AND id = $2 AND ttl > $3`,
+		game, id, time.Now().Unix(),
+	)
+	if err != nil {
+		return models.QuarantinedScore{}, false, fmt.Errorf("Failed to query quarantined score: %w", err)
+	}
+	score, err := pgx.CollectExactlyOneRow(rows, scanQuarantinedScore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.QuarantinedScore{}, false, nil
+	}
+	if err != nil {
+		return models.QuarantinedScore{}, false, fmt.Errorf("Failed to read quarantined score: %w", err)
+	}
+	return score, true, nil
+}
+
+func (p PostgresScoreDatabase) GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, game, player_id, player_name, score, ts, received_ts, metadata::text, reason, ttl FROM quarantine
+		WHERE game = $1 AND ttl > $2
+		ORDER BY ts, id`,
+		game, time.Now().Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query quarantined scores: %w", err)
+	}
+	scores, err := pgx.CollectRows(rows, scanQuarantinedScore)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read quarantined scores: %w", err)
+	}
+	return scores, nil
+}
+
+// func scanQuarantinedScore reads the metadata as text, so that a score without any has nil metadata as it was submitted with
+func scanQuarantinedScore(row pgx.CollectableRow) (models.QuarantinedScore, error) {
+	var score models.QuarantinedScore
+	var metadata string
+	err := row.Scan(&score.Id, &score.Game, &score.PlayerId, &score.PlayerName, &score.Score, &score.Timestamp, &score.ReceivedAt, &metadata, &score.Reason, &score.Expires)
+	if err != nil {
+		return score, err
+	}
+	score.Metadata, err = models.DecodeMetadata(metadata)
+	return score, err
+}
+
+func (p PostgresScoreDatabase) DeleteQuarantinedScore(ctx context.Context, game string, id string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM quarantine WHERE game = $1 AND id = $2`, game, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete quarantined score: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) DeleteScore(ctx context.Context, score models.Score) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM scores WHERE player_id = $1 AND game = $2 AND score = $3`, score.PlayerId, score.Game, score.Score)
+	if err != nil {
+		return fmt.Errorf("Failed to delete score: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) DeletePlayerScores(ctx context.Context, game string, playerId string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM scores WHERE player_id = $1 AND game = $2`, playerId, game)
+	if err != nil {
+		return fmt.Errorf("Failed to delete player scores: %w", err)
+	}
+	return nil
+}
+
+// func GetPlayerPartitions finds the game's partitions holding scores of the player from the primary key, which begins with the player
+func (p PostgresScoreDatabase) GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT DISTINCT game FROM scores
+		WHERE player_id = $1 AND (game = $2 OR starts_with(game, $3))`,
+		playerId, game, models.BoardsPrefix(game),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player partitions: %w", err)
+	}
+	partitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read player partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// func RenamePlayer sets the name shown on every score of the player in the game
+func (p PostgresScoreDatabase) RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error {
+	_, err := p.pool.Exec(ctx, `UPDATE scores SET player_name = $1 WHERE player_id = $2 AND game = $3`, playerName, playerId, game)
+	if err != nil {
+		return fmt.Errorf("Failed to rename player: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) PutBan(ctx context.Context, ban models.Ban) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO bans (game, player_id, reason, created)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game, player_id) DO UPDATE SET
+			reason = excluded.reason,
+			created = excluded.created`,
+		ban.Game, ban.PlayerId, ban.Reason, ban.Created,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert ban: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error) {
+	rows, err := p.pool.Query(ctx, `SELECT game, player_id, reason, created FROM bans WHERE game = $1 AND player_id = $2`, game, playerId)
+	if err != nil {
+		return models.Ban{}, false, fmt.Errorf("Failed to query ban: %w", err)
+	}
+	ban, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[models.Ban])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Ban{}, false, nil
+	}
+	if err != nil {
+		return models.Ban{}, false, fmt.Errorf("Failed to read ban: %w", err)
+	}
+	return ban, true, nil
+}
+
+func (p PostgresScoreDatabase) GetBans(ctx context.Context, game string) ([]models.Ban, error) {
+	rows, err := p.pool.Query(ctx, `SELECT game, player_id, reason, created FROM bans WHERE game = $1 ORDER BY player_id`, game)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query bans: %w", err)
+	}
+	bans, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Ban])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read bans: %w", err)
+	}
+	return bans, nil
+}
+
+func (p PostgresScoreDatabase) DeleteBan(ctx context.Context, game string, playerId string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM bans WHERE game = $1 AND player_id = $2`, game, playerId)
+	if err != nil {
+		return fmt.Errorf("Failed to delete ban: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) PutProfile(ctx context.Context, profile models.Profile) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO profiles (game, player_id, player_name, updated)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (game, player_id) DO UPDATE SET
+			player_name = excluded.player_name,
+			updated = excluded.updated`,
+		profile.Game, profile.PlayerId, profile.PlayerName, profile.Updated,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert profile: %w", err)
+	}
+	return nil
+}
+
+// func GetProfiles returns the profiles of those players in the game who have one, in no particular order
+func (p PostgresScoreDatabase) GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT game, player_id, player_name, updated FROM profiles
+		WHERE game = $1 AND player_id = ANY($2)`,
+		game, playerIds,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query profiles: %w", err)
+	}
+	profiles, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Profile])
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+func (p PostgresScoreDatabase) PutGroup(ctx context.Context, group models.Group) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO player_groups (game, id, name, player_ids, updated)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (game, id) DO UPDATE SET
+			name = excluded.name,
+			player_ids = excluded.player_ids,
+			updated = excluded.updated`,
+		group.Game, group.Id, group.Name, group.PlayerIds, group.Updated,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert group: %w", err)
+	}
+	return nil
+}
+
+func (p PostgresScoreDatabase) GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error) {
+	rows, err := p.pool.Query(ctx, `SELECT id, game, name, player_ids, updated FROM player_groups WHERE game = $1 AND id = $2`, game, id)
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to query group: %w", err)
+	}
+	group, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[models.Group])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Group{}, false, nil
+	}
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to read group: %w", err)
+	}
+	return group, true, nil
+}
+
+func (p PostgresScoreDatabase) DeleteGroup(ctx context.Context, game string, id string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM player_groups WHERE game = $1 AND id = $2`, game, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete group: %w", err)
+	}
+	return nil
+}
+
+// func scanApiKey reads the role as a plain integer, as Role implements encoding.TextUnmarshaler for the api and not for the database
+func scanApiKey(row pgx.CollectableRow) (models.ApiKey, error) {
+	var key models.ApiKey
+	var role int
+	err := row.Scan(&key.Id, &key.Game, &role, &key.Name, &key.Created, &key.Hash)
+	key.Role = models.Role(role)
+	return key, err
+}
+
+// func rankedScoresArgs returns the parameters of rankedScores followed by args
+func rankedScoresArgs(ranksRequest models.RanksRequest, args ...any) []any {
+	start, end := ranksRequest.Period.Bounds()
+	return append([]any{ranksRequest.Game, time.Now().Unix(), start, end, ranksRequest.Filter.Encode()}, args...)
+}
+
+// func GetTopRanks returns every rank in the game when no limit is requested, there is no single page ceiling as with dynamodb
+// requests are bounded by the game's maxRanksLimit instead, and equal scores are positioned by the earliest submission first, as with every other backend
+func (p PostgresScoreDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRequest) (models.Ranks, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT score, ROW_NUMBER() OVER (ORDER BY score `+direction(ranksRequest.Order)+`, received_ts ASC, ts ASC, player_id ASC) AS position, player_name, ts, received_ts, metadata::text, player_id
+		FROM (`+rankedScores(ranksRequest.UniquePlayers, ranksRequest.Order)+`) AS ranked
+		ORDER BY position
+		LIMIT $6`,
+		rankedScoresArgs(ranksRequest, limitOrAll(ranksRequest.Limit))...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player ranks: %w", err)
+	}
+	return collectRanks(rows)
+}
+
+// func GetRanksAround numbers every score in the game and selects those either side of the player's, so positions are exact at any depth
+func (p PostgresScoreDatabase) GetRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	rows, err := p.pool.Query(ctx, `
+		WITH ranked AS (
+			SELECT player_id, score, player_name, ts, received_ts, metadata,
+				ROW_NUMBER() OVER (ORDER BY score `+direction(request.Order)+`, received_ts ASC, ts ASC, player_id ASC) AS position
+			FROM (`+rankedScores(request.UniquePlayers, request.Order)+`) AS scores
+		), pivot AS (
+			SELECT position FROM ranked
+			WHERE player_id = $6 AND score = $7 AND ts = $8
+			ORDER BY position
+			LIMIT 1
+		)
+		SELECT ranked.score, ranked.position, ranked.player_name, ranked.ts, ranked.received_ts, ranked.metadata::text, ranked.player_id FROM ranked, pivot
+		WHERE ranked.position BETWEEN pivot.position - $9 AND pivot.position + $9
+		ORDER BY ranked.position`,
+		rankedScoresArgs(request.RanksRequest, request.PlayerId, request.Score, request.Timestamp, request.Around)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query ranks around: %w", err)
+	}
+	return collectRanks(rows)
+}
+
+func collectRanks(rows pgx.Rows) (models.Ranks, error) {
+	ranks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Rank, error) {
+		var rank models.Rank
+		var metadata string
+		err := row.Scan(&rank.Score, &rank.Position, &rank.PlayerName, &rank.Timestamp, &rank.ReceivedAt, &metadata, &rank.PlayerId)
+		if err != nil {
+			return rank, err
+		}
+		rank.Metadata, err = models.DecodeMetadata(metadata)
+		return rank, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read player ranks: %w", err)
+	}
+	return ranks, nil
+}
+
+// func limitOrAll maps a missing limit to null, which postgres treats as LIMIT ALL
+func limitOrAll(limit int) *int {
+	if limit > 0 {
+		return &limit
+	}
+	return nil
+}
+
+// func direction is the sql sort direction which lists scores best first
+func direction(order models.Order) string {
+	if order == models.LowestFirst {
+		return "ASC"
+	}
+	return "DESC"
+}
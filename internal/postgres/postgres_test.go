@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/indimeco/cheerleader/internal/storagetest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var globalConnString string
+var globalAdminPool *pgxpool.Pool
+var testDatabaseCount atomic.Int32
+
+// func createTestPostgres starts a postgres container and returns its connection string, a closer and an error
+// the closer should always be called, regardless of if an error occurred during container creation
+func createTestPostgres(ctx context.Context) (string, func(), error) {
+	postgresContainer, err := tcpostgres.Run(ctx, "postgres:16-alpine",
+		tcpostgres.WithDatabase("cheerleader"),
+		tcpostgres.WithUsername("cheerleader"),
+		tcpostgres.WithPassword("cheerleader"),
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(time.Minute)),
+	)
+	close := func() {
+		if err := testcontainers.TerminateContainer(postgresContainer); err != nil {
+			panic(fmt.Sprintf("Failed to terminate container: %v", err))
+		}
+	}
+	if err != nil {
+		return "", close, fmt.Errorf("Failed to run postgres container: %w", err)
+	}
+
+	connString, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		return "", close, fmt.Errorf("Failed to get connection string: %w", err)
+	}
+	return connString, close, nil
+}
+
+// func createTestPostgresScoreDatabase opens a freshly created database, so that every test starts empty
+func createTestPostgresScoreDatabase(t *testing.T) PostgresScoreDatabase {
+	ctx := context.Background()
+	name := fmt.Sprintf("test_%d", testDatabaseCount.Add(1))
+	_, err := globalAdminPool.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s", name))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	connUrl, err := url.Parse(globalConnString)
+	if err != nil {
+		t.Fatalf("Failed to parse connection string: %v", err)
+	}
+	connUrl.Path = "/" + name
+	d, err := Open(ctx, connUrl.String())
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(d.Close)
+	return d
+}
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	connString, close, err := createTestPostgres(ctx)
+	defer close()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to get test database: %v", err))
+	}
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to connect to test database: %v", err))
+	}
+	defer pool.Close()
+	globalConnString = connString
+	globalAdminPool = pool
+	m.Run()
+	return
+}
+
+func TestOpenIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	d, err := Open(ctx, globalConnString)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	d.Close()
+	d, err = Open(ctx, globalConnString)
+	if err != nil {
+		t.Fatalf("Expected nil error reopening, got %v", err)
+	}
+	d.Close()
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Database {
+		return createTestPostgresScoreDatabase(t)
+	})
+}
+
+func TestGetTopRanksSharesPositionsForEqualScores(t *testing.T) {
+	scores := []models.Score{
+		{PlayerId: "6", PlayerName: "Albus", Game: "Chess", Score: 100, Timestamp: 111},
+		{PlayerId: "7", PlayerName: "Harry", Game: "Chess", Score: 50, Timestamp: 222},
+		{PlayerId: "8", PlayerName: "Potter", Game: "Chess", Score: 50, Timestamp: 333},
+		{PlayerId: "9", PlayerName: "Dobby", Game: "Chess", Score: 10, Timestamp: 444},
+	}
+
+	want := models.Ranks{
+		{Position: 1, PlayerName: "Albus", Score: 100, Timestamp: 111},
+		{Position: 2, PlayerName: "Harry", Score: 50, Timestamp: 222},
+		{Position: 2, PlayerName: "Potter", Score: 50, Timestamp: 333},
+		{Position: 4, PlayerName: "Dobby", Score: 10, Timestamp: 444},
+	}
+
+	d := createTestPostgresScoreDatabase(t)
+	ctx := context.Background()
+	for _, score := range scores {
+		err := d.PutScore(ctx, score)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Chess"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetTopRanksHasNoCeiling(t *testing.T) {
+	d := createTestPostgresScoreDatabase(t)
+	ctx := context.Background()
+	for i := 1; i <= 1200; i++ {
+		err := d.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Goose", Game: "Marathon", Score: i})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Marathon"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1200 {
+		t.Errorf("want %v, got %v", 1200, len(ranks))
+	}
+	last := ranks[len(ranks)-1]
+	if last.Position != 1200 || last.Score != 1 {
+		t.Errorf("want position %v with score %v, got position %v with score %v", 1200, 1, last.Position, last.Score)
+	}
+}
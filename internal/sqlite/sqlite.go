@@ -0,0 +1,966 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/indimeco/cheerleader/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+type SqliteScoreDatabase struct {
+	db        *sql.DB
+	rankLimit int
+}
+
+var sqliteDb *sql.DB
+var once sync.Once
+
+// migrations are applied in order and tracked with the user_version pragma, so only append to this list
+var migrations = []string{
+	`CREATE TABLE scores (
+		player_id   TEXT    NOT NULL,
+		game        TEXT    NOT NULL,
+		score       INTEGER NOT NULL,
+		player_name TEXT    NOT NULL,
+		ts          INTEGER NOT NULL,
+		ttl         INTEGER NOT NULL,
+		PRIMARY KEY (player_id, game, score)
+	);
+	CREATE INDEX game_scores_index ON scores (game, score);`,
+	// windowed leaderboards read a game's scores by submission time
+	`CREATE INDEX game_timestamps_index ON scores (game, ts);`,
+	// the game registry, each config is stored as the json the admin api accepts
+	`CREATE TABLE games (
+		game   TEXT NOT NULL PRIMARY KEY,
+		config TEXT NOT NULL
+	);`,
+	// nonces of signed score submissions, kept until their signatures can no longer be replayed
+	`CREATE TABLE nonces (
+		game  TEXT    NOT NULL,
+		nonce TEXT    NOT NULL,
+		ttl   INTEGER NOT NULL,
+		PRIMARY KEY (game, nonce)
+	);`,
+	// api keys are looked up by id, only a hash of each key is stored
+	`CREATE TABLE api_keys (
+		id      TEXT    NOT NULL PRIMARY KEY,
+		game    TEXT    NOT NULL,
+		role    INTEGER NOT NULL,
+		name    TEXT    NOT NULL,
+		created INTEGER NOT NULL,
+		hash    TEXT    NOT NULL
+	);`,
+	// counters are named by their caller, which starts a new counter for each period it counts over
+	`CREATE TABLE counters (
+		name  TEXT    NOT NULL PRIMARY KEY,
+		count INTEGER NOT NULL,
+		ttl   INTEGER NOT NULL
+	);`,
+	// scores held back from the leaderboards until an admin approves or discards them
+	`CREATE TABLE quarantine (
+		game        TEXT    NOT NULL,
+		id          TEXT    NOT NULL,
+		player_id   TEXT    NOT NULL,
+		player_name TEXT    NOT NULL,
+		score       INTEGER NOT NULL,
+		ts          INTEGER NOT NULL,
+		reason      TEXT    NOT NULL,
+		ttl         INTEGER NOT NULL,
+		PRIMARY KEY (game, id)
+	);`,
+	// banned players, a ban lasts until an admin lifts it
+	`CREATE TABLE bans (
+		game      TEXT    NOT NULL,
+		player_id TEXT    NOT NULL,
+		reason    TEXT    NOT NULL,
+		created   INTEGER NOT NULL,
+		PRIMARY KEY (game, player_id)
+	);`,
+	// player display names, shown in place of the name each score was submitted with
+	`CREATE TABLE profiles (
+		game        TEXT    NOT NULL,
+		player_id   TEXT    NOT NULL,
+		player_name TEXT    NOT NULL,
+		updated     INTEGER NOT NULL,
+		PRIMARY KEY (game, player_id)
+	);`,
+	// token buckets of the rate limits, a bucket past its ttl has refilled and can be removed
+	`CREATE TABLE buckets (
+		name    TEXT    NOT NULL PRIMARY KEY,
+		tokens  REAL    NOT NULL,
+		updated INTEGER NOT NULL,
+		ttl     INTEGER NOT NULL
+	);`,
+	// responses to requests made with an Idempotency-Key, a status of zero is a request still in progress
+	`CREATE TABLE idempotency (
+		name         TEXT    NOT NULL PRIMARY KEY,
+		request_hash TEXT    NOT NULL,
+		status       INTEGER NOT NULL,
+		body         TEXT    NOT NULL,
+		ttl          INTEGER NOT NULL
+	);`,
+	// each submission of a score is kept, sqlite cannot change a primary key so the table is rebuilt with the timestamp in it
+	`CREATE TABLE scores_by_submission (
+		player_id   TEXT    NOT NULL,
+		game        TEXT    NOT NULL,
+		score       INTEGER NOT NULL,
+		player_name TEXT    NOT NULL,
+		ts          INTEGER NOT NULL,
+		ttl         INTEGER NOT NULL,
+		PRIMARY KEY (player_id, game, score, ts)
+	);
+	INSERT INTO scores_by_submission SELECT player_id, game, score, player_name, ts, ttl FROM scores;
+	DROP TABLE scores;
+	ALTER TABLE scores_by_submission RENAME TO scores;
+	CREATE INDEX game_scores_index ON scores (game, score);
+	CREATE INDEX game_timestamps_index ON scores (game, ts);`,
+	// a score may say it was achieved before it was received, the time it was received is kept for auditing and is ts for scores stored before
+	`ALTER TABLE scores ADD COLUMN received_ts INTEGER NOT NULL DEFAULT 0;
+	UPDATE scores SET received_ts = ts;
+	ALTER TABLE quarantine ADD COLUMN received_ts INTEGER NOT NULL DEFAULT 0;
+	UPDATE quarantine SET received_ts = ts;`,
+	// how each score was achieved, as a json object of strings which ranks can be filtered by
+	`ALTER TABLE scores ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';
+	ALTER TABLE quarantine ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}';`,
+	// groups of players ranked among themselves, the players are a json array of their ids
+	`CREATE TABLE player_groups (
+		game       TEXT    NOT NULL,
+		id         TEXT    NOT NULL,
+		name       TEXT    NOT NULL,
+		player_ids TEXT    NOT NULL,
+		updated    INTEGER NOT NULL,
+		PRIMARY KEY (game, id)
+	);`,
+}
+
+func New(ctx context.Context) (SqliteScoreDatabase, error) {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "cheerleader.db"
+	}
+
+	var onceErr error
+	once.Do(func() {
+		db, err := Open(ctx, path)
+		if err != nil {
+			onceErr = err
+			return
+		}
+		sqliteDb = db.db
+	})
+	if onceErr != nil {
+		return SqliteScoreDatabase{}, onceErr
+	}
+	if sqliteDb == nil {
+		return SqliteScoreDatabase{}, fmt.Errorf("Failed to open sqlite database at %q", path)
+	}
+
+	return newSqliteScoreDatabase(sqliteDb), nil
+}
+
+// func Open opens the sqlite database at path and brings its schema up to date
+func Open(ctx context.Context, path string) (SqliteScoreDatabase, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path))
+	if err != nil {
+		return SqliteScoreDatabase{}, fmt.Errorf("Failed to open sqlite database: %w", err)
+	}
+	// sqlite allows a single writer, serialising access through one connection avoids busy errors under load
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return SqliteScoreDatabase{}, err
+	}
+
+	_, err = db.ExecContext(ctx, `DELETE FROM scores WHERE ttl <= ?`, time.Now().Unix())
+	if err != nil {
+		db.Close()
+		return SqliteScoreDatabase{}, fmt.Errorf("Failed to remove expired scores: %w", err)
+	}
+	for _, table := range []string{"nonces", "counters", "quarantine", "buckets", "idempotency"} {
+		_, err = db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %v WHERE ttl <= ?`, table), time.Now().Unix())
+		if err != nil {
+			db.Close()
+			return SqliteScoreDatabase{}, fmt.Errorf("Failed to remove expired %v: %w", table, err)
+		}
+	}
+
+	return newSqliteScoreDatabase(db), nil
+}
+
+func newSqliteScoreDatabase(db *sql.DB) SqliteScoreDatabase {
+	// the same single page limit as the dynamodb implementation, so the two behave the same when swapped
+	const sqliteMaxRanksLimit = 1000
+
+	return SqliteScoreDatabase{
+		db:        db,
+		rankLimit: sqliteMaxRanksLimit,
+	}
+}
+
+func (s SqliteScoreDatabase) Close() error {
+	return s.db.Close()
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("Failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to begin migration %v: %w", i+1, err)
+		}
+		_, err = tx.ExecContext(ctx, migrations[i])
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Failed to apply migration %v: %w", i+1, err)
+		}
+		// pragmas cannot take bound parameters
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, i+1))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Failed to record migration %v: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("Failed to commit migration %v: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+const insertScore = `
+	INSERT INTO scores (player_id, game, score, player_name, ts, received_ts, metadata, ttl)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (player_id, game, score, ts) DO UPDATE SET
+		player_name = excluded.player_name,
+		metadata = excluded.metadata,
+		ttl = excluded.ttl`
+
+// matchesFilter is a condition that the metadata of a row of scores has every key and value of the json object bound to it
+const matchesFilter = `NOT EXISTS (
+		SELECT 1 FROM json_each(?) AS filter
+		WHERE json_extract(scores.metadata, '$."' || filter.key || '"') IS NOT filter.value
+	)`
+
+func (s SqliteScoreDatabase) PutScore(ctx context.Context, score models.Score) error {
+	_, err := s.db.ExecContext(ctx, insertScore, score.PlayerId, score.Game, score.Score, score.PlayerName, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Expiry())
+	if err != nil {
+		return fmt.Errorf("Failed to insert score: %w", err)
+	}
+	return nil
+}
+
+// func PutScores inserts the scores in one transaction, so either all of them are stored or none are
+func (s SqliteScoreDatabase) PutScores(ctx context.Context, scores []models.Score) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to begin inserting scores: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertScore)
+	if err != nil {
+		return fmt.Errorf("Failed to prepare score insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, score := range scores {
+		_, err := stmt.ExecContext(ctx, score.PlayerId, score.Game, score.Score, score.PlayerName, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Expiry())
+		if err != nil {
+			return fmt.Errorf("Failed to insert score: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Failed to commit scores: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) GetTopPlayerScores(ctx context.Context, scoreRequest models.PlayerScoreRequest) ([]models.Score, error) {
+	limit := -1 // sqlite treats a negative limit as no limit
+	if scoreRequest.Limit > 0 {
+		limit = scoreRequest.Limit
+	}
+	start, end := scoreRequest.Period.Bounds()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT player_id, game, score, player_name, ts, received_ts, metadata FROM scores
+		WHERE player_id = ? AND game = ? AND ttl > ? AND ts >= ? AND ts < ? AND `+matchesFilter+`
+		ORDER BY score `+direction(scoreRequest.Order)+`, received_ts ASC, ts ASC
+		LIMIT ?`,
+		scoreRequest.PlayerId, scoreRequest.Game, time.Now().Unix(), start, end, scoreRequest.Filter.Encode(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player scores: %w", err)
+	}
+	defer rows.Close()
+
+	scores := make([]models.Score, 0)
+	for rows.Next() {
+		var score models.Score
+		var metadata string
+		err := rows.Scan(&score.PlayerId, &score.Game, &score.Score, &score.PlayerName, &score.Timestamp, &score.ReceivedAt, &metadata)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a score: %w", err)
+		}
+		score.Metadata, err = models.DecodeMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read player scores: %w", err)
+	}
+	return scores, nil
+}
+
+// func rankedScores is a subquery selecting the scores ranked in a game, it takes the game, the current time, the period bounds and the filter as parameters
+// the period bounds are a range over game_timestamps_index, so a window reads only the scores submitted within it
+func rankedScores(uniquePlayers bool, order models.Order) string {
+	if uniquePlayers {
+		return `
+			SELECT player_id, score, player_name, ts, received_ts, metadata FROM (
+				SELECT player_id, score, player_name, ts, received_ts, metadata,
+					ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY score ` + direction(order) + `, received_ts ASC, ts ASC) AS player_rank
+				FROM scores
+				WHERE game = ? AND ttl > ? AND ts >= ? AND ts < ? AND ` + matchesFilter + `
+			)
+			WHERE player_rank = 1`
+	}
+	return `
+			SELECT player_id, score, player_name, ts, received_ts, metadata FROM scores
+			WHERE game = ? AND ttl > ? AND ts >= ? AND ts < ? AND ` + matchesFilter
+}
+
+func (s SqliteScoreDatabase) GetTopRanks(ctx context.Context, ranksRequest models.RanksRequest) (models.Ranks, error) {
+	limit := s.rankLimit
+	if ranksRequest.Limit > 0 {
+		limit = ranksRequest.Limit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT score, ROW_NUMBER() OVER (ORDER BY score `+direction(ranksRequest.Order)+`, received_ts ASC, ts ASC, player_id ASC) AS position, player_name, ts, received_ts, metadata, player_id
+		FROM (`+rankedScores(ranksRequest.UniquePlayers, ranksRequest.Order)+`)
+		ORDER BY position
+		LIMIT ?`,
+		rankedScoresArgs(ranksRequest, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player ranks: %w", err)
+	}
+	return scanRanks(rows)
+}
+
+// func GetRanksAround numbers every score in the game and selects those either side of the player's, so positions are exact at any depth
+func (s SqliteScoreDatabase) GetRanksAround(ctx context.Context, request models.RanksAroundRequest) (models.Ranks, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH ranked AS (
+			SELECT player_id, score, player_name, ts, received_ts, metadata,
+				ROW_NUMBER() OVER (ORDER BY score `+direction(request.Order)+`, received_ts ASC, ts ASC, player_id ASC) AS position
+			FROM (`+rankedScores(request.UniquePlayers, request.Order)+`)
+		), pivot AS (
+			SELECT position FROM ranked
+			WHERE player_id = ? AND score = ? AND ts = ?
+			ORDER BY position
+			LIMIT 1
+		)
+		SELECT ranked.score, ranked.position, ranked.player_name, ranked.ts, ranked.received_ts, ranked.metadata, ranked.player_id FROM ranked, pivot
+		WHERE ranked.position BETWEEN pivot.position - ? AND pivot.position + ?
+		ORDER BY ranked.position`,
+		rankedScoresArgs(request.RanksRequest, request.PlayerId, request.Score, request.Timestamp, request.Around, request.Around)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query ranks around: %w", err)
+	}
+	return scanRanks(rows)
+}
+
+func (s SqliteScoreDatabase) GetGame(ctx context.Context, game string) (models.GameConfig, bool, error) {
+	var config string
+	err := s.db.QueryRowContext(ctx, `SELECT config FROM games WHERE game = ?`, game).Scan(&config)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.GameConfig{}, false, nil
+	}
+	if err != nil {
+		return models.GameConfig{}, false, fmt.Errorf("Failed to query game: %w", err)
+	}
+
+	var gameConfig models.GameConfig
+	err = json.Unmarshal([]byte(config), &gameConfig)
+	if err != nil {
+		return models.GameConfig{}, false, fmt.Errorf("Failed to unmarshal game config: %w", err)
+	}
+	return gameConfig, true, nil
+}
+
+func (s SqliteScoreDatabase) GetGames(ctx context.Context) ([]models.GameConfig, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT config FROM games ORDER BY game`)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query games: %w", err)
+	}
+	defer rows.Close()
+
+	configs := make([]models.GameConfig, 0)
+	for rows.Next() {
+		var config string
+		err := rows.Scan(&config)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a game: %w", err)
+		}
+		var gameConfig models.GameConfig
+		err = json.Unmarshal([]byte(config), &gameConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal game config: %w", err)
+		}
+		configs = append(configs, gameConfig)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read games: %w", err)
+	}
+	return configs, nil
+}
+
+func (s SqliteScoreDatabase) PutGame(ctx context.Context, config models.GameConfig) error {
+	out, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal game config: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO games (game, config) VALUES (?, ?)
+		ON CONFLICT (game) DO UPDATE SET config = excluded.config`,
+		config.Game, string(out),
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert game: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) DeleteGame(ctx context.Context, game string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM games WHERE game = ?`, game)
+	if err != nil {
+		return fmt.Errorf("Failed to delete game: %w", err)
+	}
+	return nil
+}
+
+// func ClaimNonce records the nonce as used for the game until expiry, reporting false if it is already in use
+// an expired nonce is taken over in place, as a conflicting insert only updates when the existing row has expired
+func (s SqliteScoreDatabase) ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO nonces (game, nonce, ttl) VALUES (?, ?, ?)
+		ON CONFLICT (game, nonce) DO UPDATE SET ttl = excluded.ttl
+		WHERE nonces.ttl <= ?`,
+		game, nonce, expiry, time.Now().Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("Failed to insert nonce: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("Failed to read inserted nonce: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+func (s SqliteScoreDatabase) ReleaseNonce(ctx context.Context, game string, nonce string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM nonces WHERE game = ? AND nonce = ?`, game, nonce)
+	if err != nil {
+		return fmt.Errorf("Failed to delete nonce: %w", err)
+	}
+	return nil
+}
+
+// func ClaimIdempotencyKey stores the pending record unless its key is already in use, in which case the record using it is returned with false
+// an expired record is taken over in place, in the same way as a nonce
+func (s SqliteScoreDatabase) ClaimIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (models.IdempotencyRecord, bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency (name, request_hash, status, body, ttl) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			body = excluded.body,
+			ttl = excluded.ttl
+		WHERE idempotency.ttl <= ?`,
+		record.Key, record.RequestHash, record.StatusCode, record.Body, record.Expires, time.Now().Unix(),
+	)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to insert idempotency record: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to read inserted idempotency record: %w", err)
+	}
+	if claimed == 1 {
+		return record, true, nil
+	}
+
+	existing := models.IdempotencyRecord{Key: record.Key}
+	err = s.db.QueryRowContext(ctx, `SELECT request_hash, status, body, ttl FROM idempotency WHERE name = ?`, record.Key).
+		Scan(&existing.RequestHash, &existing.StatusCode, &existing.Body, &existing.Expires)
+	// the record was released by its request failing after the insert, which is still a request in progress to this one
+	if errors.Is(err, sql.ErrNoRows) {
+		return existing, false, nil
+	}
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("Failed to query idempotency record: %w", err)
+	}
+	return existing, false, nil
+}
+
+func (s SqliteScoreDatabase) PutIdempotencyRecord(ctx context.Context, record models.IdempotencyRecord) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency (name, request_hash, status, body, ttl) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			body = excluded.body,
+			ttl = excluded.ttl`,
+		record.Key, record.RequestHash, record.StatusCode, record.Body, record.Expires,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to put idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency WHERE name = ?`, key)
+	if err != nil {
+		return fmt.Errorf("Failed to delete idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) GetApiKey(ctx context.Context, id string) (models.ApiKey, bool, error) {
+	var key models.ApiKey
+	err := s.db.QueryRowContext(ctx, `SELECT id, game, role, name, created, hash FROM api_keys WHERE id = ?`, id).
+		Scan(&key.Id, &key.Game, &key.Role, &key.Name, &key.Created, &key.Hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ApiKey{}, false, nil
+	}
+	if err != nil {
+		return models.ApiKey{}, false, fmt.Errorf("Failed to query api key: %w", err)
+	}
+	return key, true, nil
+}
+
+func (s SqliteScoreDatabase) GetApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, game, role, name, created, hash FROM api_keys ORDER BY game, id`)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.ApiKey, 0)
+	for rows.Next() {
+		var key models.ApiKey
+		err := rows.Scan(&key.Id, &key.Game, &key.Role, &key.Name, &key.Created, &key.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan an api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s SqliteScoreDatabase) PutApiKey(ctx context.Context, key models.ApiKey) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, game, role, name, created, hash) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.Id, key.Game, key.Role, key.Name, key.Created, key.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) DeleteApiKey(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete api key: %w", err)
+	}
+	return nil
+}
+
+// func Increment adds one to the counter and returns its new count, a counter past its expiry starts again from zero
+func (s SqliteScoreDatabase) Increment(ctx context.Context, key string, expiry int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO counters (name, count, ttl) VALUES (?, 1, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			count = CASE WHEN counters.ttl <= ? THEN 1 ELSE counters.count + 1 END,
+			ttl = excluded.ttl
+		RETURNING count`,
+		key, expiry, time.Now().Unix(),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to increment counter: %w", err)
+	}
+	return count, nil
+}
+
+// func TakeToken reads and replaces the bucket in a transaction, which the single connection serialises with every other request
+func (s SqliteScoreDatabase) TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to begin taking token: %w", err)
+	}
+	defer tx.Rollback()
+
+	var bucket models.TokenBucket
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated FROM buckets WHERE name = ?`, key).Scan(&bucket.Tokens, &bucket.Updated)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to get token bucket: %w", err)
+	}
+	taken, ok := limit.Take(bucket, now)
+	if !ok {
+		return taken, false, nil
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO buckets (name, tokens, updated, ttl) VALUES (?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			tokens = excluded.tokens,
+			updated = excluded.updated,
+			ttl = excluded.ttl`,
+		key, taken.Tokens, taken.Updated, limit.Expiry(taken),
+	)
+	if err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to put token bucket: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.TokenBucket{}, false, fmt.Errorf("Failed to commit token bucket: %w", err)
+	}
+	return taken, true, nil
+}
+
+func (s SqliteScoreDatabase) PutQuarantinedScore(ctx context.Context, score models.QuarantinedScore) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO quarantine (game, id, player_id, player_name, score, ts, received_ts, metadata, reason, ttl)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		score.Game, score.Id, score.PlayerId, score.PlayerName, score.Score, score.Timestamp, score.ReceivedAt, score.Metadata.Encode(), score.Reason, score.Expires,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert quarantined score: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT game, id, player_id, player_name, score, ts, received_ts, metadata, reason, ttl FROM quarantine
+		WHERE game = ? AND id = ? AND ttl > ?`,
+		game, id, time.Now().Unix(),
+	)
+	if err != nil {
+		return models.QuarantinedScore{}, false, fmt.Errorf("Failed to query quarantined score: %w", err)
+	}
+	scores, err := scanQuarantinedScores(rows)
+	if err != nil || len(scores) == 0 {
+		return models.QuarantinedScore{}, false, err
+	}
+	return scores[0], true, nil
+}
+
+func (s SqliteScoreDatabase) GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT game, id, player_id, player_name, score, ts, received_ts, metadata, reason, ttl FROM quarantine
+		WHERE game = ? AND ttl > ?
+		ORDER BY ts, id`,
+		game, time.Now().Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query quarantined scores: %w", err)
+	}
+	return scanQuarantinedScores(rows)
+}
+
+func (s SqliteScoreDatabase) DeleteQuarantinedScore(ctx context.Context, game string, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM quarantine WHERE game = ? AND id = ?`, game, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete quarantined score: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) DeleteScore(ctx context.Context, score models.Score) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM scores WHERE player_id = ? AND game = ? AND score = ?`, score.PlayerId, score.Game, score.Score)
+	if err != nil {
+		return fmt.Errorf("Failed to delete score: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) DeletePlayerScores(ctx context.Context, game string, playerId string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM scores WHERE player_id = ? AND game = ?`, playerId, game)
+	if err != nil {
+		return fmt.Errorf("Failed to delete player scores: %w", err)
+	}
+	return nil
+}
+
+// func GetPlayerPartitions finds the game's partitions holding scores of the player from the primary key, which begins with the player
+func (s SqliteScoreDatabase) GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error) {
+	prefix := models.BoardsPrefix(game)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT game FROM scores
+		WHERE player_id = ? AND (game = ? OR substr(game, 1, ?) = ?)`,
+		playerId, game, len(prefix), prefix,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query player partitions: %w", err)
+	}
+	defer rows.Close()
+
+	partitions := []string{}
+	for rows.Next() {
+		var partition string
+		err := rows.Scan(&partition)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan player partition: %w", err)
+		}
+		partitions = append(partitions, partition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read player partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// func RenamePlayer sets the name shown on every score of the player in the game
+func (s SqliteScoreDatabase) RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE scores SET player_name = ? WHERE player_id = ? AND game = ?`, playerName, playerId, game)
+	if err != nil {
+		return fmt.Errorf("Failed to rename player: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) PutBan(ctx context.Context, ban models.Ban) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO bans (game, player_id, reason, created)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (game, player_id) DO UPDATE SET
+			reason = excluded.reason,
+			created = excluded.created`,
+		ban.Game, ban.PlayerId, ban.Reason, ban.Created,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert ban: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT game, player_id, reason, created FROM bans WHERE game = ? AND player_id = ?`, game, playerId)
+	if err != nil {
+		return models.Ban{}, false, fmt.Errorf("Failed to query ban: %w", err)
+	}
+	bans, err := scanBans(rows)
+	if err != nil || len(bans) == 0 {
+		return models.Ban{}, false, err
+	}
+	return bans[0], true, nil
+}
+
+func (s SqliteScoreDatabase) GetBans(ctx context.Context, game string) ([]models.Ban, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT game, player_id, reason, created FROM bans WHERE game = ? ORDER BY player_id`, game)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query bans: %w", err)
+	}
+	return scanBans(rows)
+}
+
+func (s SqliteScoreDatabase) DeleteBan(ctx context.Context, game string, playerId string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM bans WHERE game = ? AND player_id = ?`, game, playerId)
+	if err != nil {
+		return fmt.Errorf("Failed to delete ban: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) PutProfile(ctx context.Context, profile models.Profile) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO profiles (game, player_id, player_name, updated)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (game, player_id) DO UPDATE SET
+			player_name = excluded.player_name,
+			updated = excluded.updated`,
+		profile.Game, profile.PlayerId, profile.PlayerName, profile.Updated,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert profile: %w", err)
+	}
+	return nil
+}
+
+// func GetProfiles returns the profiles of those players in the game who have one, in no particular order
+func (s SqliteScoreDatabase) GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error) {
+	profiles := make([]models.Profile, 0, len(playerIds))
+	if len(playerIds) == 0 {
+		return profiles, nil
+	}
+	args := []any{game}
+	for _, playerId := range playerIds {
+		args = append(args, playerId)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT game, player_id, player_name, updated FROM profiles
+		WHERE game = ? AND player_id IN (?`+strings.Repeat(", ?", len(playerIds)-1)+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query profiles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var profile models.Profile
+		err := rows.Scan(&profile.Game, &profile.PlayerId, &profile.PlayerName, &profile.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+func (s SqliteScoreDatabase) PutGroup(ctx context.Context, group models.Group) error {
+	playerIds, err := json.Marshal(group.PlayerIds)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal group players: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO player_groups (game, id, name, player_ids, updated)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (game, id) DO UPDATE SET
+			name = excluded.name,
+			player_ids = excluded.player_ids,
+			updated = excluded.updated`,
+		group.Game, group.Id, group.Name, string(playerIds), group.Updated,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to insert group: %w", err)
+	}
+	return nil
+}
+
+func (s SqliteScoreDatabase) GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error) {
+	var group models.Group
+	var playerIds string
+	err := s.db.QueryRowContext(ctx, `SELECT id, game, name, player_ids, updated FROM player_groups WHERE game = ? AND id = ?`, game, id).
+		Scan(&group.Id, &group.Game, &group.Name, &playerIds, &group.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Group{}, false, nil
+	}
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to query group: %w", err)
+	}
+	err = json.Unmarshal([]byte(playerIds), &group.PlayerIds)
+	if err != nil {
+		return models.Group{}, false, fmt.Errorf("Failed to unmarshal group players: %w", err)
+	}
+	return group, true, nil
+}
+
+func (s SqliteScoreDatabase) DeleteGroup(ctx context.Context, game string, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM player_groups WHERE game = ? AND id = ?`, game, id)
+	if err != nil {
+		return fmt.Errorf("Failed to delete group: %w", err)
+	}
+	return nil
+}
+
+func scanBans(rows *sql.Rows) ([]models.Ban, error) {
+	defer rows.Close()
+
+	bans := make([]models.Ban, 0)
+	for rows.Next() {
+		var ban models.Ban
+		err := rows.Scan(&ban.Game, &ban.PlayerId, &ban.Reason, &ban.Created)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a ban: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read bans: %w", err)
+	}
+	return bans, nil
+}
+
+func scanQuarantinedScores(rows *sql.Rows) ([]models.QuarantinedScore, error) {
+	defer rows.Close()
+
+	scores := make([]models.QuarantinedScore, 0)
+	for rows.Next() {
+		var score models.QuarantinedScore
+		var metadata string
+		err := rows.Scan(&score.Game, &score.Id, &score.PlayerId, &score.PlayerName, &score.Score, &score.Timestamp, &score.ReceivedAt, &metadata, &score.Reason, &score.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a quarantined score: %w", err)
+		}
+		score.Metadata, err = models.DecodeMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read quarantined scores: %w", err)
+	}
+	return scores, nil
+}
+
+// func rankedScoresArgs returns the parameters of rankedScores followed by args
+func rankedScoresArgs(ranksRequest models.RanksRequest, args ...any) []any {
+	start, end := ranksRequest.Period.Bounds()
+	return append([]any{ranksRequest.Game, time.Now().Unix(), start, end, ranksRequest.Filter.Encode()}, args...)
+}
+
+func scanRanks(rows *sql.Rows) (models.Ranks, error) {
+	defer rows.Close()
+
+	ranks := make(models.Ranks, 0)
+	for rows.Next() {
+		var rank models.Rank
+		var metadata string
+		err := rows.Scan(&rank.Score, &rank.Position, &rank.PlayerName, &rank.Timestamp, &rank.ReceivedAt, &metadata, &rank.PlayerId)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan a rank: %w", err)
+		}
+		rank.Metadata, err = models.DecodeMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		ranks = append(ranks, rank)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read player ranks: %w", err)
+	}
+	return ranks, nil
+}
+
+// func direction is the sql sort direction which lists scores best first
+func direction(order models.Order) string {
+	if order == models.LowestFirst {
+		return "ASC"
+	}
+	return "DESC"
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/indimeco/cheerleader/internal/models"
+	"github.com/indimeco/cheerleader/internal/storagetest"
+)
+
+func createTestSqliteScoreDatabase(t *testing.T) SqliteScoreDatabase {
+	d, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestOpenIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "test.db")
+	d, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	err = d.PutScore(ctx, models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Tetris", Score: 100})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	d.Close()
+
+	d, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Expected nil error reopening, got %v", err)
+	}
+	defer d.Close()
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Tetris"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1 {
+		t.Errorf("want %v, got %v", 1, len(ranks))
+	}
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Database {
+		return createTestSqliteScoreDatabase(t)
+	})
+}
+
+func TestGetTopRanksCeiling(t *testing.T) {
+	d := createTestSqliteScoreDatabase(t)
+	ctx := context.Background()
+	for i := 1; i <= 1200; i++ {
+		err := d.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Goose", Game: "Comedy", Score: i})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy", Limit: 5})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 5 {
+		t.Errorf("want %v, got %v", 5, len(ranks))
+	}
+
+	ranks, err = d.GetTopRanks(ctx, models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1000 {
+		t.Errorf("want %v, got %v", 1000, len(ranks))
+	}
+	if ranks[0].Score != 1200 || ranks[999].Score != 201 {
+		t.Errorf("want ranks from %v to %v, got %v to %v", 1200, 201, ranks[0].Score, ranks[999].Score)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	d := createTestSqliteScoreDatabase(t)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.PutScore(ctx, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Goose", Game: "Racing", Score: i})
+		}()
+		go func() {
+			defer wg.Done()
+			d.GetTopRanks(ctx, models.RanksRequest{Game: "Racing"})
+		}()
+	}
+	wg.Wait()
+
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Racing"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 50 {
+		t.Errorf("want %v, got %v", 50, len(ranks))
+	}
+}
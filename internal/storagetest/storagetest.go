@@ -0,0 +1,2235 @@
+// Package storagetest is a conformance suite for score storage backends
+// every backend runs the same suite, so a backend that passes can be swapped for any other without the handler noticing
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/indimeco/cheerleader/internal/models"
+)
+
+// Database is the storage contract verified by Run, any handler.HandlerDatabase satisfies it
+type Database interface {
+	PutScore(context.Context, models.Score) error
+	PutScores(context.Context, []models.Score) error
+	GetTopPlayerScores(context.Context, models.PlayerScoreRequest) ([]models.Score, error)
+	GetTopRanks(context.Context, models.RanksRequest) (models.Ranks, error)
+	GetRanksAround(context.Context, models.RanksAroundRequest) (models.Ranks, error)
+	GetGame(context.Context, string) (models.GameConfig, bool, error)
+	GetGames(context.Context) ([]models.GameConfig, error)
+	PutGame(context.Context, models.GameConfig) error
+	DeleteGame(context.Context, string) error
+	ClaimNonce(ctx context.Context, game string, nonce string, expiry int) (bool, error)
+	ReleaseNonce(ctx context.Context, game string, nonce string) error
+	GetApiKey(context.Context, string) (models.ApiKey, bool, error)
+	GetApiKeys(context.Context) ([]models.ApiKey, error)
+	PutApiKey(context.Context, models.ApiKey) error
+	DeleteApiKey(context.Context, string) error
+	Increment(ctx context.Context, key string, expiry int) (int, error)
+	TakeToken(ctx context.Context, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool, error)
+	ClaimIdempotencyKey(context.Context, models.IdempotencyRecord) (models.IdempotencyRecord, bool, error)
+	PutIdempotencyRecord(context.Context, models.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, key string) error
+	PutQuarantinedScore(context.Context, models.QuarantinedScore) error
+	GetQuarantinedScore(ctx context.Context, game string, id string) (models.QuarantinedScore, bool, error)
+	GetQuarantinedScores(ctx context.Context, game string) ([]models.QuarantinedScore, error)
+	DeleteQuarantinedScore(ctx context.Context, game string, id string) error
+	DeleteScore(context.Context, models.Score) error
+	DeletePlayerScores(ctx context.Context, game string, playerId string) error
+	GetPlayerPartitions(ctx context.Context, game string, playerId string) ([]string, error)
+	PutBan(context.Context, models.Ban) error
+	GetBan(ctx context.Context, game string, playerId string) (models.Ban, bool, error)
+	GetBans(ctx context.Context, game string) ([]models.Ban, error)
+	DeleteBan(ctx context.Context, game string, playerId string) error
+	PutProfile(context.Context, models.Profile) error
+	GetProfiles(ctx context.Context, game string, playerIds []string) ([]models.Profile, error)
+	RenamePlayer(ctx context.Context, game string, playerId string, playerName string) error
+	PutGroup(context.Context, models.Group) error
+	GetGroup(ctx context.Context, game string, id string) (models.Group, bool, error)
+	DeleteGroup(ctx context.Context, game string, id string) error
+}
+
+// Factory returns an empty database, it is called once for every test in the suite
+type Factory func(t *testing.T) Database
+
+// func Run verifies a storage backend against the contract the handler relies on
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, d Database)
+	}{
+		{name: "PutScore", test: testPutScore},
+		{name: "PutScoreKeepsSameScore", test: testPutScoreKeepsSameScore},
+		{name: "PutScores", test: testPutScores},
+		{name: "PutScoresWithPeriod", test: testPutScoresWithPeriod},
+		{name: "PutScoreOverwritesSameSubmission", test: testPutScoreOverwritesSameSubmission},
+		{name: "PutScoreKeepsReceivedAt", test: testPutScoreKeepsReceivedAt},
+		{name: "GetTopPlayerScores", test: testGetTopPlayerScores},
+		{name: "GetTopPlayerScoresWithLimit", test: testGetTopPlayerScoresWithLimit},
+		{name: "GetTopPlayerScoresWithUserGameIsolation", test: testGetTopPlayerScoresWithUserGameIsolation},
+		{name: "GetTopPlayerScoresForUnknownPlayer", test: testGetTopPlayerScoresForUnknownPlayer},
+		{name: "GetTopRanks", test: testGetTopRanks},
+		{name: "GetTopRanksWithLimit", test: testGetTopRanksWithLimit},
+		{name: "GetTopRanksBeyondOnePage", test: testGetTopRanksBeyondOnePage},
+		{name: "GetTopRanksBreaksTiesOnReceivedAt", test: testGetTopRanksBreaksTiesOnReceivedAt},
+		{name: "GetTopRanksWithGameIsolation", test: testGetTopRanksWithGameIsolation},
+		{name: "GetTopRanksWithBoardIsolation", test: testGetTopRanksWithBoardIsolation},
+		{name: "GetTopRanksForUnknownGame", test: testGetTopRanksForUnknownGame},
+		{name: "GetTopRanksWithUniquePlayers", test: testGetTopRanksWithUniquePlayers},
+		{name: "GetTopRanksWithUniquePlayersAndLimit", test: testGetTopRanksWithUniquePlayersAndLimit},
+		{name: "GetRanksAround", test: testGetRanksAround},
+		{name: "GetRanksAroundTopScore", test: testGetRanksAroundTopScore},
+		{name: "GetRanksAroundWithNoneAround", test: testGetRanksAroundWithNoneAround},
+		{name: "GetRanksAroundWithUniquePlayers", test: testGetRanksAroundWithUniquePlayers},
+		{name: "GetRanksAroundBeyondOnePage", test: testGetRanksAroundBeyondOnePage},
+		{name: "GetTopPlayerScoresWithPeriod", test: testGetTopPlayerScoresWithPeriod},
+		{name: "GetTopRanksWithPeriod", test: testGetTopRanksWithPeriod},
+		{name: "GetTopRanksWithPeriodAndUniquePlayers", test: testGetTopRanksWithPeriodAndUniquePlayers},
+		{name: "GetRanksAroundWithPeriod", test: testGetRanksAroundWithPeriod},
+		{name: "GetRanksAroundWithPeriodAndUniquePlayers", test: testGetRanksAroundWithPeriodAndUniquePlayers},
+		{name: "GetTopPlayerScoresLowestFirst", test: testGetTopPlayerScoresLowestFirst},
+		{name: "GetTopRanksLowestFirst", test: testGetTopRanksLowestFirst},
+		{name: "GetTopRanksLowestFirstWithUniquePlayers", test: testGetTopRanksLowestFirstWithUniquePlayers},
+		{name: "GetRanksAroundLowestFirst", test: testGetRanksAroundLowestFirst},
+		{name: "GetTopRanksWithEqualScores", test: testGetTopRanksWithEqualScores},
+		{name: "GetTopRanksWithEqualScoresAndUniquePlayers", test: testGetTopRanksWithEqualScoresAndUniquePlayers},
+		{name: "GetTopRanksLowestFirstWithEqualScores", test: testGetTopRanksLowestFirstWithEqualScores},
+		{name: "GetRanksAroundWithEqualScores", test: testGetRanksAroundWithEqualScores},
+		{name: "PutScoreKeepsMetadata", test: testPutScoreKeepsMetadata},
+		{name: "GetTopPlayerScoresWithFilter", test: testGetTopPlayerScoresWithFilter},
+		{name: "GetTopRanksWithFilter", test: testGetTopRanksWithFilter},
+		{name: "GetTopRanksWithFilterAndUniquePlayers", test: testGetTopRanksWithFilterAndUniquePlayers},
+		{name: "GetRanksAroundWithFilter", test: testGetRanksAroundWithFilter},
+		{name: "GetGameForUnknownGame", test: testGetGameForUnknownGame},
+		{name: "PutGame", test: testPutGame},
+		{name: "PutGameReplacesConfig", test: testPutGameReplacesConfig},
+		{name: "GetGames", test: testGetGames},
+		{name: "DeleteGame", test: testDeleteGame},
+		{name: "ClaimNonce", test: testClaimNonce},
+		{name: "ClaimNonceAfterExpiry", test: testClaimNonceAfterExpiry},
+		{name: "ReleaseNonce", test: testReleaseNonce},
+		{name: "PutApiKey", test: testPutApiKey},
+		{name: "GetApiKeyForUnknownId", test: testGetApiKeyForUnknownId},
+		{name: "GetApiKeys", test: testGetApiKeys},
+		{name: "DeleteApiKey", test: testDeleteApiKey},
+		{name: "Increment", test: testIncrement},
+		{name: "IncrementAfterExpiry", test: testIncrementAfterExpiry},
+		{name: "IncrementAfterExpiryConcurrently", test: testIncrementAfterExpiryConcurrently},
+		{name: "TakeToken", test: testTakeToken},
+		{name: "TakeTokenAfterRefill", test: testTakeTokenAfterRefill},
+		{name: "ClaimIdempotencyKey", test: testClaimIdempotencyKey},
+		{name: "ClaimIdempotencyKeyAfterExpiry", test: testClaimIdempotencyKeyAfterExpiry},
+		{name: "DeleteIdempotencyRecord", test: testDeleteIdempotencyRecord},
+		{name: "PutQuarantinedScore", test: testPutQuarantinedScore},
+		{name: "GetQuarantinedScoresWithGameIsolation", test: testGetQuarantinedScoresWithGameIsolation},
+		{name: "GetQuarantinedScoresExcludesExpired", test: testGetQuarantinedScoresExcludesExpired},
+		{name: "DeleteQuarantinedScore", test: testDeleteQuarantinedScore},
+		{name: "QuarantinedScoresAreNotRanked", test: testQuarantinedScoresAreNotRanked},
+		{name: "DeleteScore", test: testDeleteScore},
+		{name: "DeleteScoreWithPeriodAndUniquePlayers", test: testDeleteScoreWithPeriodAndUniquePlayers},
+		{name: "DeleteScoreWithEverySubmission", test: testDeleteScoreWithEverySubmission},
+		{name: "DeleteScoreKeepsMetadataOfNextBest", test: testDeleteScoreKeepsMetadataOfNextBest},
+		{name: "DeletePlayerScores", test: testDeletePlayerScores},
+		{name: "GetPlayerPartitions", test: testGetPlayerPartitions},
+		{name: "PutBan", test: testPutBan},
+		{name: "GetBans", test: testGetBans},
+		{name: "DeleteBan", test: testDeleteBan},
+		{name: "PutProfile", test: testPutProfile},
+		{name: "GetProfilesWithGameIsolation", test: testGetProfilesWithGameIsolation},
+		{name: "GetProfilesForNoPlayers", test: testGetProfilesForNoPlayers},
+		{name: "RenamePlayer", test: testRenamePlayer},
+		{name: "PutGroup", test: testPutGroup},
+		{name: "PutGroupReplacesGroup", test: testPutGroupReplacesGroup},
+		{name: "DeleteGroup", test: testDeleteGroup},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, factory(t))
+		})
+	}
+}
+
+func putScores(t *testing.T, d Database, scores ...models.Score) {
+	t.Helper()
+	ctx := context.Background()
+	for _, score := range scores {
+		err := d.PutScore(ctx, score)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func testPutScore(t *testing.T, d Database) {
+	score := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+	}
+	err := d.PutScore(context.Background(), score)
+	if err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}
+
+func testPutScoreKeepsSameScore(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  222,
+	}
+	score2 := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  111,
+	}
+	want := []models.Score{
+		score2,
+		score1,
+	}
+
+	putScores(t, d, score1, score2)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testPutScores(t *testing.T, d Database) {
+	err := d.PutScores(context.Background(), []models.Score{
+		{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 80, Timestamp: 111},
+		{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 90, Timestamp: 222},
+		{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: 333},
+		{PlayerId: "1", PlayerName: "Albus", Game: "Drama", Score: 200, Timestamp: 444},
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 333},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 222},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 80, Timestamp: 111},
+	}
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	want = models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 333},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 222},
+	}
+	ranks, err = d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("unique players mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testPutScoresWithPeriod(t *testing.T, d Database) {
+	err := d.PutScores(context.Background(), []models.Score{
+		{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 60, Timestamp: october16, Periods: []models.Period{october16Daily, october16Weekly}},
+		{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 50, Timestamp: october16, Periods: []models.Period{october16Daily, october16Weekly}},
+		{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 90, Timestamp: october15, Periods: []models.Period{models.NewPeriod(models.WindowDaily, time.Unix(int64(october15), 0), time.UTC), october16Weekly}},
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+	}
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true, Period: october16Daily})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testPutScoreOverwritesSameSubmission(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  111,
+	}
+	score2 := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananaking",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  111,
+	}
+	want := []models.Score{
+		score2,
+	}
+
+	putScores(t, d, score1, score2)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testPutScoreKeepsReceivedAt(t *testing.T, d Database) {
+	score := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  111,
+		ReceivedAt: 999,
+	}
+
+	putScores(t, d, score)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.Score{score}, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopPlayerScores(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+	}
+	score2 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      150,
+	}
+	score3 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      125,
+	}
+	want := []models.Score{
+		score2,
+		score3,
+		score1,
+	}
+
+	putScores(t, d, score1, score2, score3)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 3},
+		PlayerId:     "2",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopPlayerScoresWithLimit(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+	}
+	score2 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      150,
+	}
+	want := []models.Score{
+		score2,
+	}
+
+	putScores(t, d, score1, score2)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 1},
+		PlayerId:     "2",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopPlayerScoresWithUserGameIsolation(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "3",
+		PlayerName: "Joseph",
+		Game:       "Tetris",
+		Score:      1,
+	}
+	score2 := models.Score{
+		PlayerId:   "4",
+		PlayerName: "Apricot",
+		Game:       "Tetris",
+		Score:      2,
+	}
+	score3 := models.Score{
+		PlayerId:   "4",
+		PlayerName: "Apricot",
+		Game:       "Fetch",
+		Score:      3,
+	}
+	score4 := models.Score{
+		PlayerId:   "3",
+		PlayerName: "Joseph",
+		Game:       "Fetch",
+		Score:      4,
+	}
+	want := []models.Score{
+		score2,
+	}
+
+	putScores(t, d, score1, score2, score3, score4)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "4",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopPlayerScoresForUnknownPlayer(t *testing.T, d Database) {
+	putScores(t, d, models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Tetris", Score: 100})
+
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "2",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(scores) != 0 {
+		t.Errorf("want no scores, got %v", scores)
+	}
+}
+
+func testGetTopRanks(t *testing.T, d Database) {
+	score1 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Comedy",
+		Score:      100,
+		Timestamp:  333,
+	}
+	score2 := models.Score{
+		PlayerId:   "2",
+		PlayerName: "Bananalord",
+		Game:       "Comedy",
+		Score:      150,
+		Timestamp:  111,
+	}
+	score3 := models.Score{
+		PlayerId:   "5",
+		PlayerName: "Mongoose",
+		Game:       "Comedy",
+		Score:      124,
+		Timestamp:  222,
+	}
+	want := models.Ranks{
+		{
+			Position:   1,
+			PlayerId:   "2",
+			PlayerName: "Bananalord",
+			Score:      150,
+			Timestamp:  111,
+		},
+		{
+			Position:   2,
+			PlayerId:   "5",
+			PlayerName: "Mongoose",
+			Score:      124,
+			Timestamp:  222,
+		},
+		{
+			Position:   3,
+			PlayerId:   "2",
+			PlayerName: "Bananalord",
+			Score:      100,
+			Timestamp:  333,
+		},
+	}
+
+	putScores(t, d, score1, score2, score3)
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksWithLimit(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 10, Timestamp: 111},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 40, Timestamp: 222},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 30, Timestamp: 333},
+		models.Score{PlayerId: "4", PlayerName: "Dobby", Game: "Comedy", Score: 20, Timestamp: 444},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 40, Timestamp: 222},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 30, Timestamp: 333},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func testGetTopRanksBeyondOnePage checks that a request for more than 1000 ranks gets as many as it asks for rather than a single page
+func testGetTopRanksBeyondOnePage(t *testing.T, d Database) {
+	scores := make([]models.Score, 0, 1201)
+	for i := 1; i <= 1201; i++ {
+		scores = append(scores, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Albus", Game: "Comedy", Score: i, Timestamp: 111})
+	}
+	err := d.PutScores(context.Background(), scores)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Limit: 1200})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1200 {
+		t.Fatalf("want %v, got %v", 1200, len(ranks))
+	}
+	if ranks[0].Score != 1201 || ranks[1199].Score != 2 || ranks[1199].Position != 1200 {
+		t.Errorf("want ranks from %v to %v, got %v to %v at position %v", 1201, 2, ranks[0].Score, ranks[1199].Score, ranks[1199].Position)
+	}
+}
+
+// func testGetTopRanksBreaksTiesOnReceivedAt checks that of equal scores the one received first ranks first, whenever the client says they were achieved
+func testGetTopRanksBreaksTiesOnReceivedAt(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: 100, ReceivedAt: 300},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 100, Timestamp: 200, ReceivedAt: 250},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 90, Timestamp: 50, ReceivedAt: 260},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 100, Timestamp: 200, ReceivedAt: 250},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 100, ReceivedAt: 300},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 90, Timestamp: 50, ReceivedAt: 260},
+	}
+
+	for _, uniquePlayers := range []bool{false, true} {
+		ranksRequest := models.RanksRequest{Game: "Comedy", UniquePlayers: uniquePlayers}
+		ranks, err := d.GetTopRanks(context.Background(), ranksRequest)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		if diff := cmp.Diff(want, ranks); diff != "" {
+			t.Errorf("mismatch for unique players %v (-want +got):\n%s", uniquePlayers, diff)
+		}
+
+		ranks, err = d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+			RanksRequest: ranksRequest,
+			PlayerId:     "1",
+			Score:        100,
+			Timestamp:    100,
+			ReceivedAt:   300,
+			Around:       1,
+		})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		if diff := cmp.Diff(want, ranks); diff != "" {
+			t.Errorf("mismatch around for unique players %v (-want +got):\n%s", uniquePlayers, diff)
+		}
+	}
+}
+
+func testGetTopRanksWithGameIsolation(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 10, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Drama", Score: 40, Timestamp: 222},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Drama", Score: 30, Timestamp: 333},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 10, Timestamp: 111},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksWithBoardIsolation(t *testing.T, d Database) {
+	board := models.BoardPartition("Comedy", "level1")
+	putPeriodScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 10, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: board, Score: 40, Timestamp: 222},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: models.BoardPartition("Comedy", "level2"), Score: 30, Timestamp: 333},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 40, Timestamp: 222},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: board, UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: board, Limit: 10},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(scores) != 1 || scores[0].Game != board {
+		t.Errorf("want only the score on %v, got %v", board, scores)
+	}
+}
+
+func testGetTopRanksForUnknownGame(t *testing.T, d Database) {
+	putScores(t, d, models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Tetris", Score: 100})
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Solitaire"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 0 {
+		t.Errorf("want no ranks, got %v", ranks)
+	}
+}
+
+func putUniquePlayersScores(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 80, Timestamp: 222},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 90, Timestamp: 333},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 70, Timestamp: 444},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 95, Timestamp: 555},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Drama", Score: 200, Timestamp: 666},
+	)
+}
+
+func testGetTopRanksWithUniquePlayers(t *testing.T, d Database) {
+	putUniquePlayersScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 111},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 95, Timestamp: 555},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 333},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksWithUniquePlayersAndLimit(t *testing.T, d Database) {
+	putUniquePlayersScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 111},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 95, Timestamp: 555},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Limit: 2, UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func putTenPlayers gives players 1 to 10 a score ten times their id, so player 10 is ranked first and player 1 last
+func putTenPlayers(t *testing.T, d Database) {
+	for i := 1; i <= 10; i++ {
+		putScores(t, d, models.Score{PlayerId: fmt.Sprint(i), PlayerName: fmt.Sprint("player", i), Game: "Comedy", Score: i * 10, Timestamp: i})
+	}
+	putScores(t, d, models.Score{PlayerId: "1", PlayerName: "player1", Game: "Drama", Score: 1000, Timestamp: 1})
+}
+
+func testGetRanksAround(t *testing.T, d Database) {
+	putTenPlayers(t, d)
+	want := models.Ranks{
+		{Position: 5, PlayerId: "6", PlayerName: "player6", Score: 60, Timestamp: 6},
+		{Position: 6, PlayerId: "5", PlayerName: "player5", Score: 50, Timestamp: 5},
+		{Position: 7, PlayerId: "4", PlayerName: "player4", Score: 40, Timestamp: 4},
+		{Position: 8, PlayerId: "3", PlayerName: "player3", Score: 30, Timestamp: 3},
+		{Position: 9, PlayerId: "2", PlayerName: "player2", Score: 20, Timestamp: 2},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy"},
+		PlayerId:     "4",
+		Score:        40,
+		Timestamp:    4,
+		Around:       2,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func testGetRanksAroundBeyondOnePage checks that a score more than 1000 places down is positioned exactly, filtered or not and for unique players or not
+func testGetRanksAroundBeyondOnePage(t *testing.T, d Database) {
+	pc := models.Metadata{"platform": "pc"}
+	scores := make([]models.Score, 0, 1201)
+	for i := 1; i <= 1201; i++ {
+		scores = append(scores, models.Score{PlayerId: fmt.Sprint(i), PlayerName: "Albus", Game: "Comedy", Score: i, Timestamp: 111, Metadata: pc})
+	}
+	err := d.PutScores(context.Background(), scores)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	for _, filter := range []models.Metadata{nil, pc} {
+		for _, uniquePlayers := range []bool{false, true} {
+			ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+				RanksRequest: models.RanksRequest{Game: "Comedy", Filter: filter, UniquePlayers: uniquePlayers},
+				PlayerId:     "2",
+				Score:        2,
+				Timestamp:    111,
+				Around:       1,
+			})
+			if err != nil {
+				t.Fatalf("Expected nil error, got %v", err)
+			}
+			got := make([][2]int, 0, len(ranks))
+			for _, rank := range ranks {
+				got = append(got, [2]int{rank.Position, rank.Score})
+			}
+			want := [][2]int{{1199, 3}, {1200, 2}, {1201, 1}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("filter %v, unique players %v, positions and scores mismatch (-want +got):\n%s", filter, uniquePlayers, diff)
+			}
+		}
+	}
+}
+
+func testGetRanksAroundTopScore(t *testing.T, d Database) {
+	putTenPlayers(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "10", PlayerName: "player10", Score: 100, Timestamp: 10},
+		{Position: 2, PlayerId: "9", PlayerName: "player9", Score: 90, Timestamp: 9},
+		{Position: 3, PlayerId: "8", PlayerName: "player8", Score: 80, Timestamp: 8},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy"},
+		PlayerId:     "10",
+		Score:        100,
+		Timestamp:    10,
+		Around:       2,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundWithNoneAround(t *testing.T, d Database) {
+	putTenPlayers(t, d)
+	want := models.Ranks{
+		{Position: 10, PlayerId: "1", PlayerName: "player1", Score: 10, Timestamp: 1},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy"},
+		PlayerId:     "1",
+		Score:        10,
+		Timestamp:    1,
+		Around:       0,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundWithUniquePlayers(t *testing.T, d Database) {
+	putUniquePlayersScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 111},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 95, Timestamp: 555},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 333},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy", UniquePlayers: true},
+		PlayerId:     "3",
+		Score:        95,
+		Timestamp:    555,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// windowed tests are pinned to the week of monday 2026-10-12 in utc
+var (
+	october15       = int(time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC).Unix())
+	october16       = int(time.Date(2026, time.October, 16, 10, 0, 0, 0, time.UTC).Unix())
+	october16Pm     = int(time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC).Unix())
+	october16Daily  = models.NewPeriod(models.WindowDaily, time.Unix(int64(october16), 0), time.UTC)
+	october16Weekly = models.NewPeriod(models.WindowWeekly, time.Unix(int64(october16), 0), time.UTC)
+)
+
+// func putPeriodScores enters scores into their periods in utc, as the handler does on submission
+func putPeriodScores(t *testing.T, d Database, scores ...models.Score) {
+	t.Helper()
+	for i := range scores {
+		scores[i].Periods = models.NewPeriods(scores[i].Timestamp, time.UTC)
+	}
+	putScores(t, d, scores...)
+}
+
+func putWindowScores(t *testing.T, d Database) {
+	putPeriodScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: october15},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 60, Timestamp: october16},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 50, Timestamp: october16},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 70, Timestamp: october16Pm},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 40, Timestamp: october16Pm + 1},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Drama", Score: 200, Timestamp: october16},
+	)
+}
+
+func testGetTopPlayerScoresWithPeriod(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	want := []models.Score{
+		{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 60, Timestamp: october16},
+	}
+
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Comedy", Limit: 10, Period: october16Daily},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksWithPeriod(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	type test struct {
+		period models.Period
+		want   models.Ranks
+	}
+	testCases := []test{
+		{
+			period: october16Daily,
+			want: models.Ranks{
+				{Position: 1, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: october16Pm},
+				{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+				{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+				{Position: 4, PlayerId: "3", PlayerName: "Potter", Score: 40, Timestamp: october16Pm + 1},
+			},
+		},
+		{
+			period: october16Weekly,
+			want: models.Ranks{
+				{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: october15},
+				{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: october16Pm},
+				{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+				{Position: 4, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+				{Position: 5, PlayerId: "3", PlayerName: "Potter", Score: 40, Timestamp: october16Pm + 1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Period: tc.period})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		if diff := cmp.Diff(tc.want, ranks); diff != "" {
+			t.Errorf("mismatch for %v (-want +got):\n%s", tc.period.Key, diff)
+		}
+	}
+}
+
+func testGetTopRanksWithPeriodAndUniquePlayers(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: october16Pm},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true, Period: october16Daily})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundWithPeriod(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: october16Pm},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy", Period: october16Daily},
+		PlayerId:     "1",
+		Score:        60,
+		Timestamp:    october16,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundWithPeriodAndUniquePlayers(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: october15},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: october16Pm},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy", UniquePlayers: true, Period: october16Weekly},
+		PlayerId:     "3",
+		Score:        70,
+		Timestamp:    october16Pm,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func putSpeedrunScores submits times to a game where the lowest time wins
+func putSpeedrunScores(t *testing.T, d Database) {
+	scores := []models.Score{
+		{PlayerId: "1", PlayerName: "Albus", Game: "Speedrun", Score: 300, Timestamp: 111},
+		{PlayerId: "1", PlayerName: "Albus", Game: "Speedrun", Score: 250, Timestamp: 222},
+		{PlayerId: "2", PlayerName: "Harry", Game: "Speedrun", Score: 280, Timestamp: 333},
+		{PlayerId: "3", PlayerName: "Potter", Game: "Speedrun", Score: 320, Timestamp: 444},
+		{PlayerId: "3", PlayerName: "Potter", Game: "Speedrun", Score: 260, Timestamp: 555},
+	}
+	for i := range scores {
+		scores[i].Order = models.LowestFirst
+	}
+	putScores(t, d, scores...)
+}
+
+func testGetTopPlayerScoresLowestFirst(t *testing.T, d Database) {
+	putSpeedrunScores(t, d)
+	want := []models.Score{
+		{PlayerId: "1", PlayerName: "Albus", Game: "Speedrun", Score: 250, Timestamp: 222},
+		{PlayerId: "1", PlayerName: "Albus", Game: "Speedrun", Score: 300, Timestamp: 111},
+	}
+
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Speedrun", Limit: 10, Order: models.LowestFirst},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksLowestFirst(t *testing.T, d Database) {
+	putSpeedrunScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 250, Timestamp: 222},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 260, Timestamp: 555},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 280, Timestamp: 333},
+		{Position: 4, PlayerId: "1", PlayerName: "Albus", Score: 300, Timestamp: 111},
+		{Position: 5, PlayerId: "3", PlayerName: "Potter", Score: 320, Timestamp: 444},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Speedrun", Order: models.LowestFirst})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksLowestFirstWithUniquePlayers(t *testing.T, d Database) {
+	putSpeedrunScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 250, Timestamp: 222},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 260, Timestamp: 555},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 280, Timestamp: 333},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Speedrun", UniquePlayers: true, Order: models.LowestFirst})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundLowestFirst(t *testing.T, d Database) {
+	putSpeedrunScores(t, d)
+	want := models.Ranks{
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 260, Timestamp: 555},
+		{Position: 3, PlayerId: "2", PlayerName: "Harry", Score: 280, Timestamp: 333},
+		{Position: 4, PlayerId: "1", PlayerName: "Albus", Score: 300, Timestamp: 111},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Speedrun", Order: models.LowestFirst},
+		PlayerId:     "2",
+		Score:        280,
+		Timestamp:    333,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func putEqualScores submits equal scores at different times, including a player who repeats their score
+func putEqualScores(t *testing.T, d Database, game string) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: game, Score: 100, Timestamp: 333},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: game, Score: 100, Timestamp: 222},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: game, Score: 90, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: game, Score: 100, Timestamp: 444},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: game, Score: 100, Timestamp: 555},
+	)
+}
+
+func testGetTopRanksWithEqualScores(t *testing.T, d Database) {
+	putEqualScores(t, d, "Comedy")
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 100, Timestamp: 222},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 333},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 444},
+		{Position: 4, PlayerId: "3", PlayerName: "Potter", Score: 100, Timestamp: 555},
+		{Position: 5, PlayerId: "3", PlayerName: "Potter", Score: 90, Timestamp: 111},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksWithEqualScoresAndUniquePlayers(t *testing.T, d Database) {
+	putEqualScores(t, d, "Comedy")
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 100, Timestamp: 222},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 333},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 100, Timestamp: 555},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopRanksLowestFirstWithEqualScores(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Speedrun", Score: 300, Timestamp: 333, Order: models.LowestFirst},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Speedrun", Score: 300, Timestamp: 222, Order: models.LowestFirst},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Speedrun", Score: 310, Timestamp: 111, Order: models.LowestFirst},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 300, Timestamp: 222},
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 300, Timestamp: 333},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 310, Timestamp: 111},
+	}
+
+	for _, uniquePlayers := range []bool{false, true} {
+		ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Speedrun", UniquePlayers: uniquePlayers, Order: models.LowestFirst})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		if diff := cmp.Diff(want, ranks); diff != "" {
+			t.Errorf("unique players %v mismatch (-want +got):\n%s", uniquePlayers, diff)
+		}
+	}
+}
+
+func testGetRanksAroundWithEqualScores(t *testing.T, d Database) {
+	putEqualScores(t, d, "Comedy")
+	want := models.Ranks{
+		{Position: 2, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 333},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 100, Timestamp: 444},
+		{Position: 4, PlayerId: "3", PlayerName: "Potter", Score: 100, Timestamp: 555},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy"},
+		PlayerId:     "1",
+		Score:        100,
+		Timestamp:    444,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// func putPlatformScores submits scores from two platforms, player 1 is best on switch but only third on pc
+func putPlatformScores(t *testing.T, d Database) {
+	pc := models.Metadata{"platform": "pc"}
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: 1, Metadata: models.Metadata{"platform": "switch", "level": "3"}},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 50, Timestamp: 2, Metadata: pc},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 90, Timestamp: 3, Metadata: pc},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy", Score: 80, Timestamp: 4, Metadata: pc},
+		models.Score{PlayerId: "3", PlayerName: "Potter", Game: "Comedy", Score: 70, Timestamp: 5, Metadata: pc},
+		models.Score{PlayerId: "4", PlayerName: "Ron", Game: "Comedy", Score: 60, Timestamp: 6},
+		models.Score{PlayerId: "5", PlayerName: "Ginny", Game: "Drama", Score: 200, Timestamp: 7, Metadata: pc},
+	)
+}
+
+func testPutScoreKeepsMetadata(t *testing.T, d Database) {
+	score := models.Score{
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Game:       "Tetris",
+		Score:      100,
+		Timestamp:  111,
+		ReceivedAt: 111,
+		Metadata:   models.Metadata{"replay_id": "r-123", "level": "7"},
+	}
+
+	putScores(t, d, score)
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Tetris", Limit: 10},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.Score{score}, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Tetris"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1 {
+		t.Fatalf("want 1 rank, got %v", len(ranks))
+	}
+	if diff := cmp.Diff(score.Metadata, ranks[0].Metadata); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetTopPlayerScoresWithFilter(t *testing.T, d Database) {
+	putPlatformScores(t, d)
+
+	scores, err := d.GetTopPlayerScores(context.Background(), models.PlayerScoreRequest{
+		ScoreRequest: models.ScoreRequest{Game: "Comedy", Limit: 10, Filter: models.Metadata{"platform": "pc"}},
+		PlayerId:     "1",
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(scores) != 1 || scores[0].Score != 50 {
+		t.Errorf("want only the pc score of 50, got %v", scores)
+	}
+}
+
+func testGetTopRanksWithFilter(t *testing.T, d Database) {
+	putPlatformScores(t, d)
+	pc := models.Metadata{"platform": "pc"}
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 3, Metadata: pc},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 80, Timestamp: 4, Metadata: pc},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: 5, Metadata: pc},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Limit: 3, Filter: pc})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	ranks, err = d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", Filter: models.Metadata{"platform": "switch", "level": "3"}})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1 || ranks[0].Score != 100 {
+		t.Errorf("want only the switch score of 100, got %v", ranks)
+	}
+}
+
+func testGetTopRanksWithFilterAndUniquePlayers(t *testing.T, d Database) {
+	putPlatformScores(t, d)
+	pc := models.Metadata{"platform": "pc"}
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 3, Metadata: pc},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: 5, Metadata: pc},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 50, Timestamp: 2, Metadata: pc},
+	}
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true, Filter: pc})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetRanksAroundWithFilter(t *testing.T, d Database) {
+	putPlatformScores(t, d)
+	pc := models.Metadata{"platform": "pc"}
+	want := models.Ranks{
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: 5, Metadata: pc},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 50, Timestamp: 2, Metadata: pc},
+	}
+
+	ranks, err := d.GetRanksAround(context.Background(), models.RanksAroundRequest{
+		RanksRequest: models.RanksRequest{Game: "Comedy", UniquePlayers: true, Filter: pc},
+		PlayerId:     "1",
+		Score:        50,
+		Timestamp:    2,
+		Around:       1,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func putGames(t *testing.T, d Database, configs ...models.GameConfig) {
+	t.Helper()
+	ctx := context.Background()
+	for _, config := range configs {
+		err := d.PutGame(ctx, config)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func golfConfig() models.GameConfig {
+	minScore := 18
+	config := models.NewGameConfig("Golf")
+	config.Order = models.LowestFirst
+	config.UniquePlayers = true
+	config.MinScore = &minScore
+	config.RetentionDays = 30
+	config.Timezone = "Europe/London"
+	return config
+}
+
+func testGetGameForUnknownGame(t *testing.T, d Database) {
+	putGames(t, d, golfConfig())
+
+	_, ok, err := d.GetGame(context.Background(), "Tennis")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want an unknown game")
+	}
+}
+
+func testPutGame(t *testing.T, d Database) {
+	want := golfConfig()
+	putGames(t, d, want)
+
+	config, ok, err := d.GetGame(context.Background(), "Golf")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok {
+		t.Fatalf("want a known game")
+	}
+	if diff := cmp.Diff(want, config); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testPutGameReplacesConfig(t *testing.T, d Database) {
+	want := models.NewGameConfig("Golf")
+	want.MaxNameLength = 8
+	putGames(t, d, golfConfig(), want)
+
+	config, _, err := d.GetGame(context.Background(), "Golf")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, config); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetGames(t *testing.T, d Database) {
+	want := []models.GameConfig{
+		models.NewGameConfig("Comedy"),
+		golfConfig(),
+		models.NewGameConfig("Tetris"),
+	}
+	putGames(t, d, want[2], want[0], want[1])
+	putScores(t, d, models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Drama", Score: 100})
+
+	configs, err := d.GetGames(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, configs); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteGame(t *testing.T, d Database) {
+	putGames(t, d, golfConfig(), models.NewGameConfig("Tetris"))
+	ctx := context.Background()
+
+	err := d.DeleteGame(ctx, "Golf")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_, ok, err := d.GetGame(ctx, "Golf")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want the game to be deleted")
+	}
+	configs, err := d.GetGames(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.GameConfig{models.NewGameConfig("Tetris")}, configs); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func claimNonce(t *testing.T, d Database, game string, nonce string, expiry int) bool {
+	t.Helper()
+	claimed, err := d.ClaimNonce(context.Background(), game, nonce, expiry)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return claimed
+}
+
+func testClaimNonce(t *testing.T, d Database) {
+	expiry := int(time.Now().Add(time.Hour).Unix())
+
+	if !claimNonce(t, d, "Tetris", "n1", expiry) {
+		t.Errorf("want the first use of a nonce to be claimed")
+	}
+	if claimNonce(t, d, "Tetris", "n1", expiry) {
+		t.Errorf("want a used nonce to be refused")
+	}
+	if !claimNonce(t, d, "Golf", "n1", expiry) {
+		t.Errorf("want nonces to be isolated by game")
+	}
+	if !claimNonce(t, d, "Tetris", "n2", expiry) {
+		t.Errorf("want a different nonce to be claimed")
+	}
+}
+
+func testClaimNonceAfterExpiry(t *testing.T, d Database) {
+	expired := int(time.Now().Add(-time.Minute).Unix())
+	expiry := int(time.Now().Add(time.Hour).Unix())
+
+	if !claimNonce(t, d, "Tetris", "n1", expired) {
+		t.Fatalf("want the first use of a nonce to be claimed")
+	}
+	if !claimNonce(t, d, "Tetris", "n1", expiry) {
+		t.Errorf("want an expired nonce to be claimed again")
+	}
+	if claimNonce(t, d, "Tetris", "n1", expiry) {
+		t.Errorf("want a used nonce to be refused")
+	}
+}
+
+func testReleaseNonce(t *testing.T, d Database) {
+	expiry := int(time.Now().Add(time.Hour).Unix())
+	claimNonce(t, d, "Tetris", "n1", expiry)
+	claimNonce(t, d, "Golf", "n1", expiry)
+
+	err := d.ReleaseNonce(context.Background(), "Tetris", "n1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !claimNonce(t, d, "Tetris", "n1", expiry) {
+		t.Errorf("want a released nonce to be claimed again")
+	}
+	if claimNonce(t, d, "Golf", "n1", expiry) {
+		t.Errorf("want only the released game's nonce to be forgotten")
+	}
+}
+
+func putApiKeys(t *testing.T, d Database, keys ...models.ApiKey) {
+	t.Helper()
+	for _, key := range keys {
+		err := d.PutApiKey(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func testApiKey(id string, game string, role models.Role) models.ApiKey {
+	return models.ApiKey{Id: id, Game: game, Role: role, Name: "client " + id, Created: 1739253593, Hash: "hash" + id}
+}
+
+func testPutApiKey(t *testing.T, d Database) {
+	want := testApiKey("a1", "Tetris", models.RoleSubmitter)
+	putApiKeys(t, d, want)
+
+	key, ok, err := d.GetApiKey(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok {
+		t.Fatalf("want a known api key")
+	}
+	if diff := cmp.Diff(want, key); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetApiKeyForUnknownId(t *testing.T, d Database) {
+	putApiKeys(t, d, testApiKey("a1", "Tetris", models.RoleReader))
+
+	_, ok, err := d.GetApiKey(context.Background(), "b2")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want an unknown api key")
+	}
+}
+
+func testGetApiKeys(t *testing.T, d Database) {
+	want := []models.ApiKey{
+		testApiKey("c3", models.AllGames, models.RoleAdmin),
+		testApiKey("a1", "Golf", models.RoleReader),
+		testApiKey("b2", "Tetris", models.RoleReader),
+		testApiKey("d4", "Tetris", models.RoleSubmitter),
+	}
+	putApiKeys(t, d, want[3], want[1], want[0], want[2])
+	putGames(t, d, golfConfig())
+
+	keys, err := d.GetApiKeys(context.Background())
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, keys); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteApiKey(t *testing.T, d Database) {
+	putApiKeys(t, d, testApiKey("a1", "Tetris", models.RoleReader), testApiKey("b2", "Tetris", models.RoleReader))
+	ctx := context.Background()
+
+	err := d.DeleteApiKey(ctx, "a1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_, ok, err := d.GetApiKey(ctx, "a1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want the api key to be deleted")
+	}
+	keys, err := d.GetApiKeys(ctx)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.ApiKey{testApiKey("b2", "Tetris", models.RoleReader)}, keys); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func increment(t *testing.T, d Database, key string, expiry int) int {
+	t.Helper()
+	count, err := d.Increment(context.Background(), key, expiry)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return count
+}
+
+func testIncrement(t *testing.T, d Database) {
+	expiry := int(time.Now().Add(time.Hour).Unix())
+
+	for want := 1; want <= 3; want++ {
+		if got := increment(t, d, "a", expiry); got != want {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+	if got := increment(t, d, "b", expiry); got != 1 {
+		t.Errorf("want counters to be isolated by key, got %v", got)
+	}
+}
+
+func testIncrementAfterExpiry(t *testing.T, d Database) {
+	expired := int(time.Now().Add(-time.Minute).Unix())
+	expiry := int(time.Now().Add(time.Hour).Unix())
+
+	increment(t, d, "a", expired)
+	increment(t, d, "a", expired)
+	if got := increment(t, d, "a", expiry); got != 1 {
+		t.Errorf("want an expired counter to start again, got %v", got)
+	}
+	if got := increment(t, d, "a", expiry); got != 2 {
+		t.Errorf("want %v, got %v", 2, got)
+	}
+}
+
+// func testIncrementAfterExpiryConcurrently checks that concurrent increments of an expired counter restart it once, and each add to it
+func testIncrementAfterExpiryConcurrently(t *testing.T, d Database) {
+	expired := int(time.Now().Add(-time.Minute).Unix())
+	expiry := int(time.Now().Add(time.Hour).Unix())
+	increment(t, d, "a", expired)
+
+	counts := make([]int, 10)
+	errs := make([]error, 10)
+	var wg sync.WaitGroup
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts[i], errs[i] = d.Increment(context.Background(), "a", expiry)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+	slices.Sort(counts)
+	want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if diff := cmp.Diff(want, counts); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func takeToken(t *testing.T, d Database, key string, limit models.RateLimit, now time.Time) (models.TokenBucket, bool) {
+	t.Helper()
+	bucket, ok, err := d.TakeToken(context.Background(), key, limit, now)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return bucket, ok
+}
+
+func testTakeToken(t *testing.T, d Database) {
+	limit := models.RateLimit{Burst: 2, Rate: 1}
+	now := time.Now()
+
+	for i := 0; i < limit.Burst; i++ {
+		if _, ok := takeToken(t, d, "a", limit, now); !ok {
+			t.Fatalf("want token %v of the burst to be taken", i+1)
+		}
+	}
+	bucket, ok := takeToken(t, d, "a", limit, now)
+	if ok {
+		t.Errorf("want an empty bucket to refuse the token")
+	}
+	if got := limit.RetryAfter(bucket); got != time.Second {
+		t.Errorf("want %v, got %v", time.Second, got)
+	}
+	if _, ok := takeToken(t, d, "b", limit, now); !ok {
+		t.Errorf("want buckets to be isolated by key")
+	}
+}
+
+func testTakeTokenAfterRefill(t *testing.T, d Database) {
+	limit := models.RateLimit{Burst: 1, Rate: 1}
+	now := time.Now()
+
+	takeToken(t, d, "a", limit, now)
+	if _, ok := takeToken(t, d, "a", limit, now.Add(500*time.Millisecond)); ok {
+		t.Errorf("want half a token to be refused")
+	}
+	if _, ok := takeToken(t, d, "a", limit, now.Add(time.Second)); !ok {
+		t.Errorf("want the bucket to have refilled")
+	}
+}
+
+func claimIdempotencyKey(t *testing.T, d Database, record models.IdempotencyRecord) (models.IdempotencyRecord, bool) {
+	t.Helper()
+	got, ok, err := d.ClaimIdempotencyKey(context.Background(), record)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	return got, ok
+}
+
+func testIdempotencyRecord(key string, expires time.Time) models.IdempotencyRecord {
+	return models.IdempotencyRecord{Key: key, RequestHash: "abc123", Expires: int(expires.Unix())}
+}
+
+func testClaimIdempotencyKey(t *testing.T, d Database) {
+	pending := testIdempotencyRecord("Tetris|1|retry", time.Now().Add(time.Minute))
+
+	if _, ok := claimIdempotencyKey(t, d, pending); !ok {
+		t.Fatalf("want a new key to be claimed")
+	}
+	got, ok := claimIdempotencyKey(t, d, pending)
+	if ok {
+		t.Errorf("want a key in use to be refused")
+	}
+	if diff := cmp.Diff(pending, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	completed := pending.Completed(201, `{"score": 10}`, time.Now())
+	err := d.PutIdempotencyRecord(context.Background(), completed)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	got, _ = claimIdempotencyKey(t, d, pending)
+	if diff := cmp.Diff(completed, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := claimIdempotencyKey(t, d, testIdempotencyRecord("Tetris|2|retry", time.Now().Add(time.Minute))); !ok {
+		t.Errorf("want keys to be isolated")
+	}
+}
+
+func testClaimIdempotencyKeyAfterExpiry(t *testing.T, d Database) {
+	claimIdempotencyKey(t, d, testIdempotencyRecord("Tetris|1|retry", time.Now().Add(-time.Minute)))
+	if _, ok := claimIdempotencyKey(t, d, testIdempotencyRecord("Tetris|1|retry", time.Now().Add(time.Minute))); !ok {
+		t.Errorf("want an expired key to be claimed again")
+	}
+}
+
+func testDeleteIdempotencyRecord(t *testing.T, d Database) {
+	record := testIdempotencyRecord("Tetris|1|retry", time.Now().Add(time.Minute))
+	claimIdempotencyKey(t, d, record)
+	err := d.DeleteIdempotencyRecord(context.Background(), record.Key)
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if _, ok := claimIdempotencyKey(t, d, record); !ok {
+		t.Errorf("want a deleted key to be claimed again")
+	}
+}
+
+func quarantinedScore(id string, game string, timestamp int) models.QuarantinedScore {
+	return models.QuarantinedScore{
+		Id:         id,
+		Game:       game,
+		PlayerId:   "1",
+		PlayerName: "Bananalord",
+		Score:      999999,
+		Timestamp:  timestamp,
+		ReceivedAt: timestamp + 60,
+		Metadata:   models.Metadata{"platform": "pc"},
+		Reason:     "Score must be at most 1000",
+		Expires:    int(time.Now().Add(time.Hour).Unix()),
+	}
+}
+
+func putQuarantinedScores(t *testing.T, d Database, scores ...models.QuarantinedScore) {
+	t.Helper()
+	for _, score := range scores {
+		err := d.PutQuarantinedScore(context.Background(), score)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func testPutQuarantinedScore(t *testing.T, d Database) {
+	want := quarantinedScore("q1", "Tetris", 1739253593)
+	putQuarantinedScores(t, d, want)
+
+	score, ok, err := d.GetQuarantinedScore(context.Background(), "Tetris", "q1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok {
+		t.Fatalf("want a known quarantined score")
+	}
+	if diff := cmp.Diff(want, score); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	_, ok, err = d.GetQuarantinedScore(context.Background(), "Golf", "q1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want quarantined scores to be isolated by game")
+	}
+}
+
+func testGetQuarantinedScoresWithGameIsolation(t *testing.T, d Database) {
+	want := []models.QuarantinedScore{
+		quarantinedScore("q2", "Tetris", 1739253590),
+		quarantinedScore("q1", "Tetris", 1739253593),
+		quarantinedScore("q3", "Tetris", 1739253593),
+	}
+	putQuarantinedScores(t, d, want[1], quarantinedScore("q4", "Golf", 1739253593), want[2], want[0])
+
+	scores, err := d.GetQuarantinedScores(context.Background(), "Tetris")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetQuarantinedScoresExcludesExpired(t *testing.T, d Database) {
+	expired := quarantinedScore("q1", "Tetris", 1739253593)
+	expired.Expires = int(time.Now().Add(-time.Minute).Unix())
+	want := quarantinedScore("q2", "Tetris", 1739253593)
+	putQuarantinedScores(t, d, expired, want)
+
+	scores, err := d.GetQuarantinedScores(context.Background(), "Tetris")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.QuarantinedScore{want}, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	_, ok, err := d.GetQuarantinedScore(context.Background(), "Tetris", "q1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want an expired quarantined score to be unknown")
+	}
+}
+
+func testDeleteQuarantinedScore(t *testing.T, d Database) {
+	putQuarantinedScores(t, d, quarantinedScore("q1", "Tetris", 1739253593), quarantinedScore("q2", "Tetris", 1739253593))
+	ctx := context.Background()
+
+	err := d.DeleteQuarantinedScore(ctx, "Tetris", "q1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	scores, err := d.GetQuarantinedScores(ctx, "Tetris")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.QuarantinedScore{quarantinedScore("q2", "Tetris", 1739253593)}, scores); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testQuarantinedScoresAreNotRanked(t *testing.T, d Database) {
+	putScores(t, d, models.Score{PlayerId: "2", PlayerName: "Goose", Game: "Tetris", Score: 100})
+	putQuarantinedScores(t, d, quarantinedScore("q1", "Tetris", 1739253593))
+
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Tetris", Limit: 10})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1 || ranks[0].Score != 100 {
+		t.Errorf("want only the ranked score, got %v", ranks)
+	}
+}
+
+func testDeleteScore(t *testing.T, d Database) {
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Tetris", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Bananalord", Game: "Tetris", Score: 90, Timestamp: 222},
+		models.Score{PlayerId: "2", PlayerName: "Goose", Game: "Tetris", Score: 80, Timestamp: 333},
+	)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Bananalord", Score: 90, Timestamp: 222},
+		{Position: 2, PlayerId: "2", PlayerName: "Goose", Score: 80, Timestamp: 333},
+	}
+
+	err := d.DeleteScore(context.Background(), models.Score{PlayerId: "1", Game: "Tetris", Score: 100})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	for _, uniquePlayers := range []bool{false, true} {
+		ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Tetris", Limit: 10, UniquePlayers: uniquePlayers})
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		if diff := cmp.Diff(want, ranks); diff != "" {
+			t.Errorf("unique players %v mismatch (-want +got):\n%s", uniquePlayers, diff)
+		}
+	}
+}
+
+func testDeleteScoreWithPeriodAndUniquePlayers(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	want := models.Ranks{
+		{Position: 1, PlayerId: "1", PlayerName: "Albus", Score: 60, Timestamp: october16},
+		{Position: 2, PlayerId: "2", PlayerName: "Harry", Score: 50, Timestamp: october16},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 40, Timestamp: october16Pm + 1},
+	}
+
+	err := d.DeleteScore(context.Background(), models.Score{PlayerId: "3", Game: "Comedy", Score: 70})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true, Period: october16Daily})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteScoreWithEverySubmission(t *testing.T, d Database) {
+	putEqualScores(t, d, "Comedy")
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 100, Timestamp: 222},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 100, Timestamp: 555},
+		{Position: 3, PlayerId: "3", PlayerName: "Potter", Score: 90, Timestamp: 111},
+	}
+
+	err := d.DeleteScore(context.Background(), models.Score{PlayerId: "1", Game: "Comedy", Score: 100})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteScoreKeepsMetadataOfNextBest(t *testing.T, d Database) {
+	putPlatformScores(t, d)
+	pc := models.Metadata{"platform": "pc"}
+
+	err := d.DeleteScore(context.Background(), models.Score{PlayerId: "1", Game: "Comedy", Score: 100})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	ranks, err := d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	want := models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 3, Metadata: pc},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: 5, Metadata: pc},
+		{Position: 3, PlayerId: "4", PlayerName: "Ron", Score: 60, Timestamp: 6},
+		{Position: 4, PlayerId: "1", PlayerName: "Albus", Score: 50, Timestamp: 2, Metadata: pc},
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("unfiltered mismatch (-want +got):\n%s", diff)
+	}
+
+	ranks, err = d.GetTopRanks(context.Background(), models.RanksRequest{Game: "Comedy", UniquePlayers: true, Filter: pc})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	want = models.Ranks{
+		{Position: 1, PlayerId: "2", PlayerName: "Harry", Score: 90, Timestamp: 3, Metadata: pc},
+		{Position: 2, PlayerId: "3", PlayerName: "Potter", Score: 70, Timestamp: 5, Metadata: pc},
+		{Position: 3, PlayerId: "1", PlayerName: "Albus", Score: 50, Timestamp: 2, Metadata: pc},
+	}
+	if diff := cmp.Diff(want, ranks); diff != "" {
+		t.Errorf("filtered mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeletePlayerScores(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	ctx := context.Background()
+
+	err := d.DeletePlayerScores(ctx, "Comedy", "3")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	for _, request := range []models.RanksRequest{
+		{Game: "Comedy"},
+		{Game: "Comedy", UniquePlayers: true},
+		{Game: "Comedy", UniquePlayers: true, Period: october16Daily},
+	} {
+		ranks, err := d.GetTopRanks(ctx, request)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		for _, rank := range ranks {
+			if rank.PlayerName == "Potter" {
+				t.Errorf("want the player's scores deleted, got %v for %+v", ranks, request)
+			}
+		}
+	}
+
+	ranks, err := d.GetTopRanks(ctx, models.RanksRequest{Game: "Drama"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(ranks) != 1 {
+		t.Errorf("want the player's scores in other games kept, got %v", ranks)
+	}
+}
+
+// func testGetPlayerPartitions checks that the partitions with scores of the player are found among those of the game, and forgotten once their scores are deleted
+func testGetPlayerPartitions(t *testing.T, d Database) {
+	ctx := context.Background()
+	putScores(t, d,
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy/act1", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy/act2", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedy/act2", Score: 90, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Comedyx/act1", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "1", PlayerName: "Albus", Game: "Drama", Score: 100, Timestamp: 111},
+		models.Score{PlayerId: "2", PlayerName: "Harry", Game: "Comedy/act3", Score: 100, Timestamp: 111},
+	)
+	getPartitions := func(playerId string) []string {
+		t.Helper()
+		partitions, err := d.GetPlayerPartitions(ctx, "Comedy", playerId)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		slices.Sort(partitions)
+		return partitions
+	}
+
+	if diff := cmp.Diff([]string{"Comedy", "Comedy/act1", "Comedy/act2"}, getPartitions("1")); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Comedy/act3"}, getPartitions("2")); diff != "" {
+		t.Errorf("board only mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{}, getPartitions("3")); diff != "" {
+		t.Errorf("unknown player mismatch (-want +got):\n%s", diff)
+	}
+
+	err := d.DeletePlayerScores(ctx, "Comedy/act1", "1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"Comedy", "Comedy/act2"}, getPartitions("1")); diff != "" {
+		t.Errorf("after deleting mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func putBans(t *testing.T, d Database, bans ...models.Ban) {
+	t.Helper()
+	for _, ban := range bans {
+		err := d.PutBan(context.Background(), ban)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func testPutBan(t *testing.T, d Database) {
+	want := models.Ban{Game: "Tetris", PlayerId: "1", Reason: "impossible scores", Created: 1739253593}
+	putBans(t, d, want)
+
+	ban, ok, err := d.GetBan(context.Background(), "Tetris", "1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok {
+		t.Fatalf("want the player banned")
+	}
+	if diff := cmp.Diff(want, ban); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	_, ok, err = d.GetBan(context.Background(), "Golf", "1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want bans to be isolated by game")
+	}
+}
+
+func testGetBans(t *testing.T, d Database) {
+	want := []models.Ban{
+		{Game: "Tetris", PlayerId: "1", Created: 1739253593},
+		{Game: "Tetris", PlayerId: "2", Reason: "abuse", Created: 1739253590},
+	}
+	putBans(t, d, want[1], models.Ban{Game: "Golf", PlayerId: "3"}, want[0])
+
+	bans, err := d.GetBans(context.Background(), "Tetris")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, bans); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteBan(t *testing.T, d Database) {
+	putBans(t, d, models.Ban{Game: "Tetris", PlayerId: "1"})
+	ctx := context.Background()
+
+	err := d.DeleteBan(ctx, "Tetris", "1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_, ok, err := d.GetBan(ctx, "Tetris", "1")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want the ban lifted")
+	}
+}
+
+func putProfiles(t *testing.T, d Database, profiles ...models.Profile) {
+	t.Helper()
+	for _, profile := range profiles {
+		err := d.PutProfile(context.Background(), profile)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+// sortProfiles orders profiles by player, as GetProfiles returns them in no particular order
+var sortProfiles = cmpopts.SortSlices(func(a models.Profile, b models.Profile) bool {
+	return a.PlayerId < b.PlayerId
+})
+
+func testPutProfile(t *testing.T, d Database) {
+	want := models.Profile{Game: "Tetris", PlayerId: "1", PlayerName: "Dumbledore", Updated: 222}
+	putProfiles(t, d, models.Profile{Game: "Tetris", PlayerId: "1", PlayerName: "Albus", Updated: 111}, want)
+
+	profiles, err := d.GetProfiles(context.Background(), "Tetris", []string{"1"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff([]models.Profile{want}, profiles); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetProfilesWithGameIsolation(t *testing.T, d Database) {
+	want := []models.Profile{
+		{Game: "Tetris", PlayerId: "1", PlayerName: "Albus", Updated: 111},
+		{Game: "Tetris", PlayerId: "3", PlayerName: "Potter", Updated: 111},
+	}
+	putProfiles(t, d, want[1], models.Profile{Game: "Golf", PlayerId: "2", PlayerName: "Harry", Updated: 111}, want[0])
+
+	profiles, err := d.GetProfiles(context.Background(), "Tetris", []string{"3", "2", "1", "4"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, profiles, sortProfiles); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testGetProfilesForNoPlayers(t *testing.T, d Database) {
+	putProfiles(t, d, models.Profile{Game: "Tetris", PlayerId: "1", PlayerName: "Albus", Updated: 111})
+
+	profiles, err := d.GetProfiles(context.Background(), "Tetris", []string{})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(profiles) != 0 {
+		t.Errorf("want no profiles, got %v", profiles)
+	}
+}
+
+func testRenamePlayer(t *testing.T, d Database) {
+	putWindowScores(t, d)
+	ctx := context.Background()
+
+	err := d.RenamePlayer(ctx, "Comedy", "3", "Dumbledore")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	for _, request := range []models.RanksRequest{
+		{Game: "Comedy", Limit: 10},
+		{Game: "Comedy", UniquePlayers: true},
+		{Game: "Comedy", UniquePlayers: true, Period: october16Daily},
+	} {
+		ranks, err := d.GetTopRanks(ctx, request)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+		for _, rank := range ranks {
+			if (rank.PlayerName == "Dumbledore") != (rank.PlayerId == "3") {
+				t.Errorf("%+v: want only the player renamed, got %+v", request, rank)
+			}
+		}
+	}
+	scores, err := d.GetTopPlayerScores(ctx, models.PlayerScoreRequest{ScoreRequest: models.ScoreRequest{Game: "Drama"}, PlayerId: "3"})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if len(scores) != 1 || scores[0].PlayerName != "Potter" {
+		t.Errorf("want the player's scores in other games kept, got %v", scores)
+	}
+}
+
+func putGroups(t *testing.T, d Database, groups ...models.Group) {
+	t.Helper()
+	for _, group := range groups {
+		err := d.PutGroup(context.Background(), group)
+		if err != nil {
+			t.Fatalf("Expected nil error, got %v", err)
+		}
+	}
+}
+
+func testPutGroup(t *testing.T, d Database) {
+	want := models.Group{Id: "9c1e", Game: "Tetris", Name: "Hogwarts", PlayerIds: []string{"3", "1", "2"}, Updated: 111}
+	putGroups(t, d, want)
+
+	group, ok, err := d.GetGroup(context.Background(), "Tetris", "9c1e")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if !ok {
+		t.Fatalf("want the group stored")
+	}
+	if diff := cmp.Diff(want, group); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	_, ok, err = d.GetGroup(context.Background(), "Golf", "9c1e")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want groups to be isolated by game")
+	}
+}
+
+func testPutGroupReplacesGroup(t *testing.T, d Database) {
+	want := models.Group{Id: "9c1e", Game: "Tetris", Name: "Gryffindor", PlayerIds: []string{"1"}, Updated: 222}
+	putGroups(t, d, models.Group{Id: "9c1e", Game: "Tetris", Name: "Hogwarts", PlayerIds: []string{"1", "2"}, Updated: 111}, want)
+
+	group, _, err := d.GetGroup(context.Background(), "Tetris", "9c1e")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if diff := cmp.Diff(want, group); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func testDeleteGroup(t *testing.T, d Database) {
+	putGroups(t, d, models.Group{Id: "9c1e", Game: "Tetris", PlayerIds: []string{"1"}, Updated: 111})
+	ctx := context.Background()
+
+	err := d.DeleteGroup(ctx, "Tetris", "9c1e")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	_, ok, err := d.GetGroup(ctx, "Tetris", "9c1e")
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+	if ok {
+		t.Errorf("want the group deleted")
+	}
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	_ "time/tzdata" // the lambda runtime has no timezone database for LEADERBOARD_TIMEZONE
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -24,6 +29,15 @@ func handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (ev
 		return h.ResponseNotFound(), nil
 	}
 
+	caller, response, ok := h.Authorize(ctx, apiDefinition, event.HTTPMethod, event.Headers)
+	if !ok {
+		return response, nil
+	}
+	response, ok = h.RateLimit(ctx, apiDefinition, event.HTTPMethod, caller, event.RequestContext.Identity.SourceIP)
+	if !ok {
+		return response, nil
+	}
+
 	params := event.QueryStringParameters
 	body := event.Body
 
@@ -35,7 +49,7 @@ func handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (ev
 			case "GET":
 				return h.GetTopPlayerScores(ctx, apiDefinition, params), nil
 			case "PUT":
-				return h.PutScore(ctx, apiDefinition, body), nil
+				return h.PutScore(ctx, apiDefinition, event.Headers, body), nil
 			default:
 				return h.ResponseMethodNotAllowed(), nil
 			}
@@ -47,6 +61,27 @@ func handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (ev
 			}
 			return h.GetRanksAroundPlayer(ctx, apiDefinition, params), nil
 		}
+	case apiRoutes.SessionsByPlayer:
+		{
+			if event.HTTPMethod != "POST" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.PostSession(ctx, apiDefinition), nil
+		}
+	case apiRoutes.ProfileByPlayer:
+		{
+			if event.HTTPMethod != "PATCH" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.PatchProfile(ctx, apiDefinition, body), nil
+		}
+	case apiRoutes.ScoresBatch:
+		{
+			if event.HTTPMethod != "POST" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.PostScoreBatch(ctx, caller, apiDefinition, event.Headers, body), nil
+		}
 	case apiRoutes.Ranks:
 		{
 			if event.HTTPMethod != "GET" {
@@ -54,11 +89,147 @@ func handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (ev
 			}
 			return h.GetTopRanks(ctx, apiDefinition, params), nil
 		}
+	case apiRoutes.GroupRanks:
+		{
+			if event.HTTPMethod != "GET" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.GetGroupRanks(ctx, apiDefinition, params), nil
+		}
+	case apiRoutes.Groups:
+		{
+			if event.HTTPMethod != "POST" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.CreateGroup(ctx, apiDefinition, body), nil
+		}
+	case apiRoutes.Group:
+		{
+			switch event.HTTPMethod {
+			case "GET":
+				return h.GetGroup(ctx, apiDefinition), nil
+			case "PUT":
+				return h.PutGroup(ctx, apiDefinition, body), nil
+			case "DELETE":
+				return h.DeleteGroup(ctx, apiDefinition), nil
+			default:
+				return h.ResponseMethodNotAllowed(), nil
+			}
+		}
+	case apiRoutes.AdminGames:
+		{
+			if event.HTTPMethod != "GET" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.GetGames(ctx), nil
+		}
+	case apiRoutes.AdminGame:
+		{
+			switch event.HTTPMethod {
+			case "GET":
+				return h.GetGame(ctx, apiDefinition), nil
+			case "PUT":
+				return h.PutGame(ctx, apiDefinition, body), nil
+			case "DELETE":
+				return h.DeleteGame(ctx, apiDefinition), nil
+			default:
+				return h.ResponseMethodNotAllowed(), nil
+			}
+		}
+	case apiRoutes.AdminQuarantine:
+		{
+			if event.HTTPMethod != "GET" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.GetQuarantinedScores(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminQuarantinedScore:
+		{
+			if event.HTTPMethod != "DELETE" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.DiscardScore(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminApproveScore:
+		{
+			if event.HTTPMethod != "POST" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.ApproveScore(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminPlayer:
+		{
+			if event.HTTPMethod != "DELETE" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.DeletePlayerScores(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminPlayerScore:
+		{
+			if event.HTTPMethod != "DELETE" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.DeleteScore(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminBans:
+		{
+			if event.HTTPMethod != "GET" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.GetBans(ctx, apiDefinition), nil
+		}
+	case apiRoutes.AdminBan:
+		{
+			switch event.HTTPMethod {
+			case "PUT":
+				return h.BanPlayer(ctx, apiDefinition, body), nil
+			case "DELETE":
+				return h.UnbanPlayer(ctx, apiDefinition), nil
+			default:
+				return h.ResponseMethodNotAllowed(), nil
+			}
+		}
+	case apiRoutes.AdminKeys:
+		{
+			switch event.HTTPMethod {
+			case "GET":
+				return h.GetApiKeys(ctx, caller), nil
+			case "POST":
+				return h.CreateApiKey(ctx, caller, body), nil
+			default:
+				return h.ResponseMethodNotAllowed(), nil
+			}
+		}
+	case apiRoutes.AdminKey:
+		{
+			if event.HTTPMethod != "DELETE" {
+				return h.ResponseMethodNotAllowed(), nil
+			}
+			return h.RevokeApiKey(ctx, caller, apiDefinition), nil
+		}
 	}
 
 	return h.ResponseInternalServerError(fmt.Errorf("Unhandled API escaped with path %q method %q ", event.Path, event.HTTPMethod)), nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := serveFlags.String("addr", ":8080", "address for the http server to listen on")
+		trustedProxiesFlag := serveFlags.String("trusted-proxies", "", "comma separated ips and cidr ranges of proxies whose X-Forwarded-For is trusted")
+		serveFlags.Parse(os.Args[2:])
+
+		trustedProxies, err := parseTrustedProxies(*trustedProxiesFlag)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		if err := serve(*addr, trustedProxies); err != nil && err != http.ErrServerClosed {
+			slog.Error(fmt.Sprintf("Server stopped: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	lambda.Start(handleRequest)
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/indimeco/cheerleader/internal/handler"
+)
+
+// maxRequestBodyBytes mirrors the payload ceiling of API Gateway so that both entrypoints reject the same requests
+const maxRequestBodyBytes = 10 << 20
+
+// func serve runs cheerleader as a standalone http server
+// every request is adapted into an api gateway event and passed through the same flow as the lambda entrypoint
+// requests from a trusted proxy are attributed to the client it forwarded them for, as given by X-Forwarded-For
+func serve(addr string, trustedProxies []netip.Prefix) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// fail on startup rather than on the first request if the handler cannot be configured
+	if _, err := handler.New(ctx); err != nil {
+		return fmt.Errorf("Failed to get handler: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           httpHandler(trustedProxies),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info(fmt.Sprintf("Listening on %v", addr))
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+func httpHandler(trustedProxies []netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleHttpRequest(w, r, trustedProxies)
+	})
+}
+
+func handleHttpRequest(w http.ResponseWriter, r *http.Request, trustedProxies []netip.Prefix) {
+	event, err := httpRequestToEvent(w, r, trustedProxies)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := handleRequest(r.Context(), event)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Unexpected error: %v", err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeEventResponse(w, response)
+}
+
+// func httpRequestToEvent converts a plain http request into the shape api gateway uses for proxy integrations
+func httpRequestToEvent(w http.ResponseWriter, r *http.Request, trustedProxies []netip.Prefix) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, fmt.Errorf("Failed to read request body: %w", err)
+	}
+
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+
+	query := r.URL.Query()
+	var params map[string]string
+	if len(query) > 0 {
+		params = make(map[string]string, len(query))
+		for k, v := range query {
+			if len(v) > 0 {
+				params[k] = v[0]
+			}
+		}
+	}
+
+	sourceIp := clientIp(r, trustedProxies)
+
+	return events.APIGatewayProxyRequest{
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         headers,
+		MultiValueHeaders:               r.Header,
+		QueryStringParameters:           params,
+		MultiValueQueryStringParameters: query,
+		Body:                            string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Path:       r.URL.Path,
+			HTTPMethod: r.Method,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  sourceIp,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}, nil
+}
+
+// func clientIp returns the address a request came from
+// when it came through trusted proxies, the client is the last address in X-Forwarded-For which is not a trusted proxy, as earlier ones can be forged by the client
+func clientIp(r *http.Request, trustedProxies []netip.Prefix) string {
+	sourceIp, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIp = r.RemoteAddr
+	}
+	if !isTrustedProxy(sourceIp, trustedProxies) {
+		return sourceIp
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return sourceIp
+		}
+		sourceIp = ip
+		if !isTrustedProxy(ip, trustedProxies) {
+			break
+		}
+	}
+	return sourceIp
+}
+
+func isTrustedProxy(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// func parseTrustedProxies reads a comma separated list of ips and cidr ranges
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var trustedProxies []netip.Prefix
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(s); err == nil {
+			addr = addr.Unmap()
+			trustedProxies = append(trustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("Expected an ip or cidr range of trusted proxies, got %v", s)
+		}
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+	return trustedProxies, nil
+}
+
+func writeEventResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for k, v := range response.Headers {
+		w.Header().Set(k, v)
+	}
+	for k, values := range response.MultiValueHeaders {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	io.WriteString(w, response.Body)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHttpRequestToEvent(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/tetris/goose/scores?limit=10", strings.NewReader(`{"score": 10}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = "10.0.0.1:4321"
+
+	event, err := httpRequestToEvent(httptest.NewRecorder(), r, nil)
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if event.Path != "/tetris/goose/scores" {
+		t.Errorf("want %v, got %v", "/tetris/goose/scores", event.Path)
+	}
+	if event.HTTPMethod != "PUT" {
+		t.Errorf("want %v, got %v", "PUT", event.HTTPMethod)
+	}
+	if event.Body != `{"score": 10}` {
+		t.Errorf("want %v, got %v", `{"score": 10}`, event.Body)
+	}
+	if diff := cmp.Diff(map[string]string{"limit": "10"}, event.QueryStringParameters); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if event.Headers["Content-Type"] != "application/json" {
+		t.Errorf("want %v, got %v", "application/json", event.Headers["Content-Type"])
+	}
+	if event.RequestContext.Identity.SourceIP != "10.0.0.1" {
+		t.Errorf("want %v, got %v", "10.0.0.1", event.RequestContext.Identity.SourceIP)
+	}
+}
+
+func TestClientIp(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.7")
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	testCases := []struct {
+		remoteAddr     string
+		forwardedFor   string
+		trustedProxies []netip.Prefix
+		want           string
+	}{
+		{remoteAddr: "10.0.0.1:4321", forwardedFor: "203.0.113.5", want: "10.0.0.1"},
+		{remoteAddr: "10.0.0.1:4321", forwardedFor: "203.0.113.5", trustedProxies: trustedProxies, want: "203.0.113.5"},
+		{remoteAddr: "10.0.0.1:4321", forwardedFor: "198.51.100.9, 203.0.113.5, 192.0.2.7", trustedProxies: trustedProxies, want: "203.0.113.5"},
+		{remoteAddr: "203.0.113.5:4321", forwardedFor: "198.51.100.9", trustedProxies: trustedProxies, want: "203.0.113.5"},
+		{remoteAddr: "10.0.0.1:4321", trustedProxies: trustedProxies, want: "10.0.0.1"},
+		{remoteAddr: "10.0.0.1:4321", forwardedFor: "10.0.0.2, 10.0.0.3", trustedProxies: trustedProxies, want: "10.0.0.2"},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/tetris/scores", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+		if got := clientIp(r, tc.trustedProxies); got != tc.want {
+			t.Errorf("want %v, got %v, forwarded for %q", tc.want, got, tc.forwardedFor)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestHttpRequestToEventBodyTooLarge(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/tetris/goose/scores", strings.NewReader(strings.Repeat("a", maxRequestBodyBytes+1)))
+
+	_, err := httpRequestToEvent(httptest.NewRecorder(), r, nil)
+	if err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
+
+func TestWriteEventResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeEventResponse(w, events.APIGatewayProxyResponse{
+		StatusCode: http.StatusTeapot,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       "[]",
+	})
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("want %v, got %v", http.StatusTeapot, w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("want %v, got %v", "application/json", w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != "[]" {
+		t.Errorf("want %v, got %v", "[]", w.Body.String())
+	}
+}